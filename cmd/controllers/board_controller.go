@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BoardController struct {
+	db *sql.DB
+}
+
+func NewBoardController(db *sql.DB) *BoardController {
+	return &BoardController{
+		db,
+	}
+}
+
+func (b *BoardController) CreateColumn(c *fiber.Ctx) error {
+	column := models.Column{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := utilities.ReadJson(c.Body(), &column); err != nil || column.CreatedBy == 0 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_column",
+		})
+	}
+
+	if !column.ValidateColumn() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	id, err := column.InsertColumn(b.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":     id,
+			"column": column,
+		},
+	})
+}
+
+func (b *BoardController) GetAllColumns(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	column := models.Column{
+		CreatedBy: int64(id),
+	}
+
+	columns, err := column.GetAllColumnsFromUserId(b.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   columns,
+	})
+}
+
+func (b *BoardController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		column := models.Column{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_column_id",
+			})
+		}
+
+		if err := utilities.ReadJson(c.Body(), &column); err != nil || column.CreatedBy == 0 {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_column",
+			})
+		}
+
+		if !delete && !column.ValidateColumn() {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		err = column.UpdateColumnById(int64(id), delete, b.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   column,
+		})
+	}
+}
+
+// Moves the column in the board, reads {"created_by": id, "after": id | null} where a
+// null after puts it first
+func (b *BoardController) MoveColumn(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_column_id",
+		})
+	}
+
+	userId, after, err := readOwnerAndId(c.Body(), "after")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_order",
+		})
+	}
+
+	column := models.Column{
+		CreatedBy: userId,
+	}
+
+	position, err := column.MoveColumn(int64(id), after, b.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":       id,
+			"position": position,
+		},
+	})
+}
+
+// Moves the to do to a column of the board in one step, reads
+// {"created_by": id, "column": id | null, "after": id | null}. A null column takes it off
+// the board and a null after puts it at the top of the column
+func (b *BoardController) MoveToDo(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	holder := make(map[string]any)
+	err = utilities.ReadJson(c.Body(), &holder)
+	userId, ok := holder["created_by"].(float64)
+	column, columnOk := holder["column"].(float64)
+	after, afterOk := holder["after"].(float64)
+
+	if err != nil || !ok || (!columnOk && holder["column"] != nil) || (!afterOk && holder["after"] != nil) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_order",
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: int64(userId),
+	}
+
+	position, err := todo.MoveToColumn(int64(id), int64(column), int64(after), b.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":              id,
+			"column":          int64(column),
+			"column_position": position,
+		},
+	})
+}
+
+func (b *BoardController) GetBoard(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	board, err := models.GetBoard(int64(id), b.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   board,
+	})
+}
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+const DEFAULT_ACTIVITY_PER_PAGE = 30
+
+type CommentsController struct {
+	db *sql.DB
+}
+
+func NewCommentsController(db *sql.DB) *CommentsController {
+	return &CommentsController{
+		db,
+	}
+}
+
+// Reads {"created_by": id, "body": "...", "parent": id, "mentions": [ids]}, parent and mentions are optional
+func ReadCommentFromJson(comment *models.Comment, body []byte) error {
+	if err := utilities.ReadJson(body, comment); err != nil || comment.CreatedBy == 0 {
+		return locales.New("invalid_comment")
+	}
+
+	return nil
+}
+
+// Reads the before (unix millis) and limit queries of the activity feeds
+func ReadActivityFilter(c *fiber.Ctx) (models.ActivityFilter, error) {
+	filter := models.ActivityFilter{
+		Limit: c.QueryInt("limit", DEFAULT_ACTIVITY_PER_PAGE),
+	}
+
+	if c.Query("before") != "" {
+		unix := c.QueryInt("before", -1)
+
+		if unix < 0 {
+			return filter, locales.New("invalid_filter")
+		}
+
+		before := time.UnixMilli(int64(unix))
+		filter.Before = &before
+	}
+
+	if !filter.Validate() {
+		return filter, locales.New("invalid_filter")
+	}
+
+	return filter, nil
+}
+
+func (cc *CommentsController) CreateComment(c *fiber.Ctx) error {
+	comment := models.Comment{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	if err := ReadCommentFromJson(&comment, c.Body()); err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	if !comment.ValidateComment() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	comment.ToDo = int64(todoId)
+	id, err := comment.InsertComment(cc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":      id,
+			"comment": comment,
+		},
+	})
+}
+
+func (cc *CommentsController) GetComments(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	comment := models.Comment{
+		ToDo:      int64(todoId),
+		CreatedBy: int64(userId),
+	}
+
+	comments, err := comment.GetComments(cc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   comments,
+	})
+}
+
+// Only the author edits or deletes the comment
+func (cc *CommentsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		comment := models.Comment{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		todoId, commentId, err := listParams(c, "comment")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_comment_id",
+			})
+		}
+
+		if delete {
+			comment.CreatedBy, err = ReadOwnerFromJson(c.Body())
+		} else {
+			err = ReadCommentFromJson(&comment, c.Body())
+		}
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_comment",
+			})
+		}
+
+		// The thread of a comment can't change
+		comment.Parent = 0
+
+		if !delete && !comment.ValidateComment() {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		comment.ToDo = int64(todoId)
+		err = comment.UpdateCommentById(int64(commentId), delete, cc.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   commentId,
+		})
+	}
+}
+
+func (cc *CommentsController) GetToDoActivity(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	filter, err := ReadActivityFilter(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	activity, err := models.GetToDoActivity(int64(todoId), int64(userId), filter, cc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   activity,
+	})
+}
+
+func (cc *CommentsController) GetUserActivity(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	userId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	filter, err := ReadActivityFilter(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	activity, err := models.GetUserActivity(int64(userId), filter, cc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   activity,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"net/http"
 
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/gofiber/fiber/v2"
@@ -30,17 +31,28 @@ func (i *ImageController) PostImage(c *fiber.Ctx) error {
 	}()
 
 	if err != nil {
-		response.ErrorMsg = "invalid id"
+		response.ErrorCode = "invalid_id"
 		return c.JSON(response)
 	}
 
 	t := models.Tag{CreatedBy: int64(id)}
 	if active, err := t.CheckUserIsActive(i.db); !active || err != nil {
-		response.ErrorMsg = "invalid user"
+		response.ErrorCode = "invalid_user"
 		status = http.StatusBadRequest
 		return c.JSON(response)
 	}
 
+	// Images of a workspace count against its plan
+	workspace := workspaceParam(c)
+	if workspace != 0 {
+		if err := models.CheckWorkspaceQuota(workspace, int64(id), models.WORKSPACE_IMAGES, i.db); err != nil {
+			response.ErrorCode = locales.CodeOf(err)
+			response.ErrorMsg = err.Error()
+			status = http.StatusConflict
+			return c.JSON(response)
+		}
+	}
+
 	cld, ctx := creds()
 
 	form, err := c.MultipartForm()
@@ -48,7 +60,7 @@ func (i *ImageController) PostImage(c *fiber.Ctx) error {
 
 	if err != nil || len(files) == 0 {
 		status = http.StatusBadRequest
-		response.ErrorMsg = "file doesnt exist"
+		response.ErrorCode = "file_missing"
 		return c.JSON(response)
 	}
 
@@ -58,15 +70,15 @@ func (i *ImageController) PostImage(c *fiber.Ctx) error {
 
 	if err != nil {
 		status = http.StatusInternalServerError
-		response.ErrorMsg = "unexpected error"
+		response.ErrorCode = "unexpected_error"
 		return c.JSON(response)
 	}
 
-	imageId, err := i.uploadImage(res, id)
+	imageId, err := i.uploadImage(res, id, workspace)
 
 	if err != nil {
 		status = http.StatusInternalServerError
-		response.ErrorMsg = "unexpected error"
+		response.ErrorCode = "unexpected_error"
 		return c.JSON(response)
 	}
 	status = http.StatusCreated
@@ -80,14 +92,14 @@ func (i *ImageController) PostImage(c *fiber.Ctx) error {
 	})
 }
 
-func (i *ImageController) uploadImage(r *uploader.UploadResult, userId int) (int64, error) {
-	stm, err := i.db.Prepare("INSERT INTO images (image_url, public_id, id_user) VALUES (?, ?, ?) LIMIT 1;")
+func (i *ImageController) uploadImage(r *uploader.UploadResult, userId int, workspace int64) (int64, error) {
+	stm, err := i.db.Prepare("INSERT INTO images (image_url, public_id, id_user, id_workspace) VALUES (?, ?, ?, NULLIF(?, 0)) LIMIT 1;")
 	if err != nil {
 		return 0, err
 	}
 	defer stm.Close()
 
-	res, err := stm.Exec(r.SecureURL, r.PublicID, userId)
+	res, err := stm.Exec(r.SecureURL, r.PublicID, userId, workspace)
 	if err != nil {
 		return 0, err
 	}
@@ -106,21 +118,31 @@ func (i *ImageController) GetAllImages(c *fiber.Ctx) error {
 	}()
 
 	if err != nil {
-		response.ErrorMsg = "invalid id"
+		response.ErrorCode = "invalid_id"
 		return c.JSON(response)
 	}
 
 	t := models.Tag{CreatedBy: int64(id)}
 	if active, err := t.CheckUserIsActive(i.db); !active || err != nil {
-		response.ErrorMsg = "invalid user"
+		response.ErrorCode = "invalid_user"
 		status = http.StatusBadRequest
 		return c.JSON(response)
 	}
 
-	images, err := i.getImages(id)
+	workspace := workspaceParam(c)
+	if workspace != 0 {
+		if err := models.CheckWorkspaceRole(workspace, int64(id), models.ROLE_VIEWER, i.db); err != nil {
+			response.ErrorCode = locales.CodeOf(err)
+			response.ErrorMsg = err.Error()
+			status = http.StatusNotFound
+			return c.JSON(response)
+		}
+	}
+
+	images, err := i.getImages(id, workspace)
 
 	if err != nil {
-		response.ErrorMsg = "couldnt get images"
+		response.ErrorCode = "images_get_failed"
 		status = http.StatusInternalServerError
 		return c.JSON(response)
 	}
@@ -144,13 +166,13 @@ func (i *ImageController) DeleteImage(c *fiber.Ctx) error {
 	}()
 
 	if err != nil {
-		response.ErrorMsg = "invalid id"
+		response.ErrorCode = "invalid_id"
 		return c.JSON(response)
 	}
 
 	publicId, err := i.getPublicId(id)
 	if err != nil {
-		response.ErrorMsg = "image not found"
+		response.ErrorCode = "image_not_found"
 		return c.JSON(response)
 	}
 
@@ -160,13 +182,13 @@ func (i *ImageController) DeleteImage(c *fiber.Ctx) error {
 	})
 
 	if err != nil {
-		response.ErrorMsg = "image not found"
+		response.ErrorCode = "image_not_found"
 		return c.JSON(response)
 	}
 
 	err = i.deleteImage(id)
 	if err != nil {
-		response.ErrorMsg = "cannot delete image"
+		response.ErrorCode = "image_delete_failed"
 		return c.JSON(response)
 	}
 
@@ -177,14 +199,23 @@ func (i *ImageController) DeleteImage(c *fiber.Ctx) error {
 	})
 }
 
-func (i *ImageController) getImages(userId int) ([]Img, error) {
-	stm, err := i.db.Prepare("SELECT id_image, image_url FROM images WHERE id_user = ?;")
+// The personal images of the user, or every image of the workspace
+func (i *ImageController) getImages(userId int, workspace int64) ([]Img, error) {
+	query := "SELECT id_image, image_url FROM images WHERE id_user = ? AND id_workspace IS NULL;"
+	arg := any(userId)
+
+	if workspace != 0 {
+		query = "SELECT id_image, image_url FROM images WHERE id_workspace = ?;"
+		arg = workspace
+	}
+
+	stm, err := i.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stm.Close()
 
-	rows, err := stm.Query(userId)
+	rows, err := stm.Query(arg)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,921 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ListsController struct {
+	db *sql.DB
+}
+
+func NewListsController(db *sql.DB) *ListsController {
+	return &ListsController{
+		db,
+	}
+}
+
+// Reads the :id of the list and the :param next to it
+func listParams(c *fiber.Ctx, param string) (int64, int64, error) {
+	listId, err := c.ParamsInt("id")
+	id, paramErr := c.ParamsInt(param)
+
+	if err != nil || paramErr != nil {
+		return 0, 0, locales.New("invalid_id")
+	}
+
+	return int64(listId), int64(id), nil
+}
+
+// Reads {"created_by": id} plus the nullable id field, null and a missing field are 0
+func readOwnerAndId(body []byte, field string) (int64, int64, error) {
+	holder := make(map[string]any)
+	err := utilities.ReadJson(body, &holder)
+	errDefinition := locales.New("invalid_definition")
+
+	if err != nil {
+		return 0, 0, errDefinition
+	}
+
+	userId, ok := holder["created_by"].(float64)
+
+	if !ok {
+		return 0, 0, errDefinition
+	}
+
+	id, ok := holder[field].(float64)
+
+	if !ok && holder[field] != nil {
+		return 0, 0, errDefinition
+	}
+
+	return int64(userId), int64(id), nil
+}
+
+func (l *ListsController) CreateList(c *fiber.Ctx) error {
+	list := models.List{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := utilities.ReadJson(c.Body(), &list); err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_list",
+		})
+	}
+
+	if !list.ValidateList() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	id, err := list.InsertList(l.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   id,
+			"list": list,
+		},
+	})
+}
+
+func (l *ListsController) GetAllLists(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	list := models.List{
+		CreatedBy: int64(id),
+	}
+
+	lists, err := list.GetAllListsFromUserId(l.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   lists,
+	})
+}
+
+func (l *ListsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		list := models.List{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_list_id",
+			})
+		}
+
+		if err := utilities.ReadJson(c.Body(), &list); err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_list",
+			})
+		}
+
+		if !delete && !list.ValidateList() {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		err = list.UpdateListById(int64(id), delete, l.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   list,
+		})
+	}
+}
+
+func (l *ListsController) GetMembers(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	list := models.List{
+		CreatedBy: int64(userId),
+	}
+
+	members, err := list.GetMembers(int64(id), l.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   members,
+	})
+}
+
+// Changes the role of a member, reads {"created_by": id, "role": "viewer" | "editor" | "owner"}
+func (l *ListsController) SetMemberRole(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, memberId, err := listParams(c, "user")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	holder := struct {
+		CreatedBy int64  `json:"created_by"`
+		Role      string `json:"role"`
+	}{}
+
+	if err := utilities.ReadJson(c.Body(), &holder); err != nil || !models.IsValidRole(holder.Role) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_role",
+		})
+	}
+
+	list := models.List{
+		CreatedBy: holder.CreatedBy,
+	}
+
+	err = list.SetMemberRole(id, memberId, holder.Role, l.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   memberId,
+			"role": holder.Role,
+		},
+	})
+}
+
+func (l *ListsController) RemoveMember(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, memberId, err := listParams(c, "user")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	list := models.List{
+		CreatedBy: userId,
+	}
+
+	err = list.RemoveMember(id, memberId, l.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   memberId,
+	})
+}
+
+func (l *ListsController) GetInvites(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	list := models.List{
+		CreatedBy: int64(userId),
+	}
+
+	invites, err := list.GetInvites(int64(id), l.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   invites,
+	})
+}
+
+// Invites a mail to the list, reads {"created_by": id, "mail": "...", "role": "viewer" | "editor" | "owner"}
+func (l *ListsController) CreateInvite(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_list_id",
+		})
+	}
+
+	holder := struct {
+		CreatedBy int64  `json:"created_by"`
+		Mail      string `json:"mail"`
+		Role      string `json:"role"`
+	}{}
+
+	err = utilities.ReadJson(c.Body(), &holder)
+	invite := models.ListInvite{
+		Mail:      holder.Mail,
+		Role:      holder.Role,
+		List:      int64(id),
+		InvitedBy: holder.CreatedBy,
+	}
+
+	if err != nil || !invite.Validate() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_invite",
+		})
+	}
+
+	inviteId, err := invite.InsertInvite(l.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":     inviteId,
+			"invite": invite,
+		},
+	})
+}
+
+func (l *ListsController) CancelInvite(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, inviteId, err := listParams(c, "invite")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	list := models.List{
+		CreatedBy: userId,
+	}
+
+	if err := list.CancelInvite(id, inviteId, l.db); err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   inviteId,
+	})
+}
+
+// Pending invitations sent to the mail of the user of the :id param
+func (l *ListsController) GetUserInvites(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	invites, err := models.GetUserInvites(int64(id), l.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   invites,
+	})
+}
+
+func (l *ListsController) CreateAnswerInviteFuncs(accept bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		userId, inviteId, err := listParams(c, "invite")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		listId, err := models.AnswerInvite(inviteId, userId, accept, l.db)
+
+		if err != nil {
+			code = http.StatusNotFound
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body: fiber.Map{
+				"id":       inviteId,
+				"list":     listId,
+				"accepted": accept,
+			},
+		})
+	}
+}
+
+// Lists the to dos of the list for any of its members, with the filters of the to dos listing
+func (l *ListsController) GetListToDos(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	filter, err := ReadToDoFilter(c, false)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	// Always paginated as the /v2 listing, the :id here is the list
+	if c.Query("limit") == "" {
+		filter.Limit = DEFAULT_TODOS_PER_PAGE
+	}
+
+	filter.List = int64(id)
+
+	if err := models.CheckListRole(filter.List, int64(userId), models.ROLE_VIEWER, l.db); err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: int64(userId),
+	}
+
+	page, err := todo.GetAllToDosFromUserId(filter, l.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   page,
+	})
+}
+
+// Creates a to do in the list, it belongs to and counts against the quota of the member creating it
+func (l *ListsController) CreateListToDo(c *fiber.Ctx) error {
+	todo := models.ToDo{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_list_id",
+		})
+	}
+
+	if err := ReadToDoFromJson(&todo, c.Body()); err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	todo.List = int64(id)
+
+	if !ValidateToDo(&todo) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	todoId, err := todo.InsertToDo(l.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   todoId,
+			"todo": todo,
+		},
+	})
+}
+
+// Editors change and delete the to dos of the list, whoever created them
+func (l *ListsController) CreateUpdateOrDeleteToDoFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		todo := models.ToDo{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, todoId, err := listParams(c, "todo")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		userId, err := callerParam(c)
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_user_id",
+			})
+		}
+
+		if err := todo.ActAsListMember(id, todoId, userId, models.ROLE_EDITOR, l.db); err != nil {
+			code = http.StatusNotFound
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		// A PATCH changes only the fields it sends, the to do stays its creator's
+		owner := todo.CreatedBy
+
+		if !delete {
+			if err := todo.GetToDoById(todoId, l.db); err != nil {
+				code = http.StatusNotFound
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: locales.CodeOf(err),
+					ErrorMsg:  err.Error(),
+				})
+			}
+
+			if err := ReadToDoPatchFromJson(&todo, c.Body()); err != nil {
+				code = http.StatusBadRequest
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: "invalid_todo",
+				})
+			}
+		}
+
+		todo.CreatedBy = owner
+
+		if !delete && !ValidateToDo(&todo) {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		err = todo.UpdateToDoById(todoId, delete, l.db)
+
+		if err != nil {
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   todo,
+		})
+	}
+}
+
+func (l *ListsController) CreateCompleteToDoFuncs(completed bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, todoId, err := listParams(c, "todo")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		userId, err := ReadOwnerFromJson(c.Body())
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		todo := models.ToDo{}
+
+		if err := todo.ActAsListMember(id, todoId, userId, models.ROLE_EDITOR, l.db); err != nil {
+			code = http.StatusNotFound
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		nextId, err := todo.SetCompleted(todoId, completed, l.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		unblocked := make([]int64, 0)
+
+		// The to do is already completed, a failed lookup only leaves the list empty
+		if completed {
+			if ids, err := todo.UnblockedBy(todoId, l.db); err == nil {
+				unblocked = ids
+			}
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body: fiber.Map{
+				"id":        todoId,
+				"completed": completed,
+				"next":      nextId,
+				"unblocked": unblocked,
+			},
+		})
+	}
+}
+
+// Assigns a to do of the list to a member, reads {"created_by": id, "assigned_to": id | null}
+func (l *ListsController) AssignToDo(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, todoId, err := listParams(c, "todo")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	userId, assignee, err := readOwnerAndId(c.Body(), "assigned_to")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	todo := models.ToDo{}
+
+	if err := todo.ActAsListMember(id, todoId, userId, models.ROLE_EDITOR, l.db); err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	if err := todo.AssignToDo(todoId, assignee, l.db); err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":          todoId,
+			"assigned_to": assignee,
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Reads the user id from the :id param, or from the created_by query on the /v2 collection routes
+func userIdParam(c *fiber.Ctx) (int, error) {
+	if c.Params("id") != "" {
+		return c.ParamsInt("id")
+	}
+
+	return strconv.Atoi(c.Query("created_by"))
+}
+
+// Reads the caller of a /v2 route from the created_by query, or from a {"created_by": id} body
+func callerParam(c *fiber.Ctx) (int64, error) {
+	if c.Query("created_by") != "" {
+		return strconv.ParseInt(c.Query("created_by"), 10, 64)
+	}
+
+	return ReadOwnerFromJson(c.Body())
+}
+
+// Reads the workspace context of a listing from the workspace query, 0 is the personal space
+func workspaceParam(c *fiber.Ctx) int64 {
+	return int64(c.QueryInt("workspace", 0))
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PomodoroController struct {
+	db *sql.DB
+}
+
+func NewPomodoroController(db *sql.DB) *PomodoroController {
+	return &PomodoroController{
+		db,
+	}
+}
+
+// Reads {"created_by": id, "todo": id, "work_minutes": n, "break_minutes": n}, the lengths are optional
+func (pc *PomodoroController) StartPomodoro(c *fiber.Ctx) error {
+	pomodoro := models.Pomodoro{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := utilities.ReadJson(c.Body(), &pomodoro); err != nil || pomodoro.CreatedBy == 0 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_pomodoro",
+		})
+	}
+
+	if !pomodoro.ValidatePomodoro() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	id, err := pomodoro.StartPomodoro(pc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	session, err := pomodoro.GetPomodoroById(id, pc.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   session,
+	})
+}
+
+// Pauses, resumes or finishes the session, reads {"created_by": id}
+func (pc *PomodoroController) CreateStateFuncs(state string) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_pomodoro_id",
+			})
+		}
+
+		userId, err := ReadOwnerFromJson(c.Body())
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		pomodoro := models.Pomodoro{
+			CreatedBy: userId,
+		}
+
+		if err := pomodoro.SetPomodoroState(int64(id), state, pc.db); err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		session, err := pomodoro.GetPomodoroById(int64(id), pc.db)
+
+		if err != nil {
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   session,
+		})
+	}
+}
+
+// The active session of the user, so a client opened later picks it up where it is
+func (pc *PomodoroController) GetCurrentPomodoro(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	userId, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	pomodoro := models.Pomodoro{
+		CreatedBy: int64(userId),
+	}
+
+	session, active, err := pomodoro.GetCurrentPomodoro(pc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"active":  active,
+			"session": session,
+		},
+	})
+}
+
+// Completed pomodoros by day or week and by tag, with the range of the time reports
+func (pc *PomodoroController) GetPomodoroStats(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	userId, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	filter, err := ReadTimeReportFilter(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	stats, err := models.GetPomodoroStats(int64(userId), filter, pc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   stats,
+	})
+}
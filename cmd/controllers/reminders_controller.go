@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/reminders"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RemindersController struct {
+	db        *sql.DB
+	notifiers map[string]reminders.Notifier
+}
+
+func NewRemindersController(db *sql.DB, notifiers map[string]reminders.Notifier) *RemindersController {
+	return &RemindersController{
+		db:        db,
+		notifiers: notifiers,
+	}
+}
+
+// Reads {"remind_at": unix millis} or {"offset": minutes before the deadline}, with the channel and owner
+func ReadReminderFromJson(reminder *models.Reminder, body []byte) error {
+	holder := make(map[string]any)
+	err := utilities.ReadJson(body, &holder)
+	errDefinition := locales.New("invalid_reminder")
+
+	if err != nil {
+		return errDefinition
+	}
+
+	userId, ok := holder["created_by"].(float64)
+
+	if !ok {
+		return errDefinition
+	}
+
+	if unix, ok := holder["remind_at"].(float64); ok {
+		remindAt := time.UnixMilli(int64(unix))
+		reminder.RemindAt = &remindAt
+	} else if offset, ok := holder["offset"].(float64); ok {
+		reminder.Offset = int(offset)
+	} else {
+		return errDefinition
+	}
+
+	reminder.Channel, ok = holder["channel"].(string)
+
+	if !ok {
+		reminder.Channel = reminders.CHANNEL_LOG
+	}
+
+	reminder.CreatedBy = int64(userId)
+	return nil
+}
+
+func (r *RemindersController) CreateReminder(c *fiber.Ctx) error {
+	reminder := models.Reminder{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	err = ReadReminderFromJson(&reminder, c.Body())
+
+	if err != nil || !reminder.Validate() || !reminders.IsChannelAvailable(reminder.Channel, r.notifiers) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_reminder",
+		})
+	}
+
+	reminder.ToDo = int64(todoId)
+	id, err := reminder.InsertReminder(r.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":       id,
+			"reminder": reminder,
+		},
+	})
+}
+
+func (r *RemindersController) GetAllReminders(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	userId := c.QueryInt("created_by", -1)
+
+	if userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	reminder := models.Reminder{
+		ToDo:      int64(todoId),
+		CreatedBy: int64(userId),
+	}
+
+	list, err := reminder.GetAllRemindersFromToDo(r.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   list,
+	})
+}
+
+func (r *RemindersController) DeleteReminder(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+	reminderId, reminderErr := c.ParamsInt("reminder")
+
+	if err != nil || reminderErr != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	reminder := models.Reminder{
+		ToDo:      int64(todoId),
+		CreatedBy: userId,
+	}
+
+	err = reminder.DeleteReminderById(int64(reminderId), r.db)
+
+	if err != nil {
+		code = http.StatusInternalServerError
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   reminderId,
+	})
+}
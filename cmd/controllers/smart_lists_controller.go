@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SmartListsController struct {
+	db *sql.DB
+}
+
+func NewSmartListsController(db *sql.DB) *SmartListsController {
+	return &SmartListsController{
+		db,
+	}
+}
+
+func ReadSmartListFromJson(list *models.SmartList, body []byte) error {
+	if err := utilities.ReadJson(body, list); err != nil || list.CreatedBy == 0 {
+		return locales.New("invalid_smart_list")
+	}
+
+	return nil
+}
+
+func (sc *SmartListsController) CreateSmartList(c *fiber.Ctx) error {
+	list := models.SmartList{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := ReadSmartListFromJson(&list, c.Body()); err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	if !list.ValidateSmartList() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	id, err := list.InsertSmartList(sc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":         id,
+			"smart_list": list,
+		},
+	})
+}
+
+func (sc *SmartListsController) GetAllSmartLists(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	list := models.SmartList{
+		CreatedBy: int64(id),
+	}
+
+	lists, err := list.GetAllSmartListsFromUserId(sc.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   lists,
+	})
+}
+
+func (sc *SmartListsController) GetSmartList(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	list := models.SmartList{
+		CreatedBy: int64(userId),
+	}
+
+	if err := list.GetSmartListById(int64(id), sc.db); err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":         id,
+			"smart_list": list,
+		},
+	})
+}
+
+func (sc *SmartListsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		list := models.SmartList{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_smart_list_id",
+			})
+		}
+
+		if err := ReadSmartListFromJson(&list, c.Body()); err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		if !delete && !list.ValidateSmartList() {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		err = list.UpdateSmartListById(int64(id), delete, sc.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   list,
+		})
+	}
+}
+
+// The to dos matching the smart list today, paginated as the /v2 listing. Only sort,
+// order, limit and cursor are read from the query, the rest comes from the smart list
+func (sc *SmartListsController) GetSmartListToDos(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+	limit := c.QueryInt("limit", DEFAULT_TODOS_PER_PAGE)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	page := models.ToDoFilter{
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	}
+
+	if limit <= 0 || !page.Validate() || page.ValidateCursor() != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_filter",
+		})
+	}
+
+	list := models.SmartList{
+		CreatedBy: int64(userId),
+	}
+
+	todos, err := list.Evaluate(int64(id), page, sc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   todos,
+	})
+}
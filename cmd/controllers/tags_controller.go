@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"net/http"
 
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
 	"github.com/gofiber/fiber/v2"
@@ -32,8 +33,16 @@ func (t *TagsController) CreateTag(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: "invalid tag definition",
+			Status:    code,
+			ErrorCode: "invalid_tag",
+		})
+	}
+
+	if valid, _ := tag.ValidateTag(); !valid {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
 		})
 	}
 
@@ -42,8 +51,9 @@ func (t *TagsController) CreateTag(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusConflict
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
@@ -71,8 +81,8 @@ func (t *TagsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 		if err != nil {
 			code = http.StatusBadRequest
 			return c.JSON(models.Response{
-				Status: code,
-				ErrorMsg: "invalid tag id",
+				Status:    code,
+				ErrorCode: "invalid_tag_id",
 			})
 		}
 
@@ -81,8 +91,16 @@ func (t *TagsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 		if err != nil {
 			code = http.StatusBadRequest
 			return c.JSON(models.Response{
-				Status: code,
-				ErrorMsg: "invalid tag definition",
+				Status:    code,
+				ErrorCode: "invalid_tag",
+			})
+		}
+
+		if valid, _ := tag.ValidateTag(); !delete && !valid {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
 			})
 		}
 
@@ -91,15 +109,93 @@ func (t *TagsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 		if err != nil {
 			code = http.StatusInternalServerError
 			return c.JSON(models.Response{
-				Status: code,
-				ErrorMsg: err.Error(),
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   tag,
+		})
+	}
+}
+
+// The /v2 PATCH and DELETE. A DELETE only needs the caller, from the created_by query or
+// body, and a PATCH changes only the fields it sends
+func (t *TagsController) CreatePatchOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_tag_id",
+			})
+		}
+
+		userId, err := callerParam(c)
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_user_id",
+			})
+		}
+
+		tag := models.Tag{}
+
+		if !delete {
+			if err := tag.GetTagById(id, t.db); err != nil || tag.CreatedBy != userId {
+				code = http.StatusNotFound
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: "tag_not_found",
+				})
+			}
+
+			if err := utilities.ReadJson(c.Body(), &tag); err != nil || tag.CreatedBy != userId {
+				code = http.StatusBadRequest
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: "invalid_tag",
+				})
+			}
+
+			if valid, _ := tag.ValidateTag(); !valid {
+				code = http.StatusBadRequest
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: "invalid_fields",
+				})
+			}
+		}
+
+		tag.CreatedBy = userId
+		err = tag.UpdateTagById(int64(id), delete, t.db)
+
+		if err != nil {
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
 			})
 		}
 
 		code = http.StatusOK
 		return c.JSON(models.Response{
 			Status: code,
-			Body: tag,
+			Body:   tag,
 		})
 	}
 }
@@ -117,8 +213,8 @@ func (t *TagsController) GetTagById(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: "invalid tag id",
+			Status:    code,
+			ErrorCode: "invalid_tag_id",
 		})
 	}
 
@@ -127,15 +223,16 @@ func (t *TagsController) GetTagById(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusNotFound
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
 	code = http.StatusFound
 	return c.JSON(models.Response{
 		Status: code,
-		Body: tag,
+		Body:   tag,
 	})
 }
 
@@ -146,18 +243,19 @@ func (t *TagsController) GetAllTagsFromUserId(c *fiber.Ctx) error {
 		c.Status(code)
 	}()
 
-	id, err := c.ParamsInt("id") 
+	id, err := userIdParam(c) 
 
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: "invalid user id",
+			Status:    code,
+			ErrorCode: "invalid_user_id",
 		})
 	}
 
 	tag := models.Tag{
 		CreatedBy: int64(id),
+		Workspace: workspaceParam(c),
 	}
 
 	tags, err := tag.GetAllTagsFromUserId(t.db)
@@ -165,15 +263,16 @@ func (t *TagsController) GetAllTagsFromUserId(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusInternalServerError
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
 	code = http.StatusOK
 	return c.JSON(models.Response{
 		Status: code,
-		Body: tags,
+		Body:   tags,
 	})
 }
 
@@ -188,8 +287,8 @@ func (t *TagsController) DeleteAllTagsFromUserId(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: "invalid user id",
+			Status:    code,
+			ErrorCode: "invalid_user_id",
 		})
 	}
 
@@ -202,14 +301,15 @@ func (t *TagsController) DeleteAllTagsFromUserId(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusInternalServerError
 		return c.JSON(models.Response{
-			Status: code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
 	code = http.StatusOK
 	return c.JSON(models.Response{
 		Status: code,
-		Body: "deleted all tags",
+		Body:   "deleted all tags",
 	})
 }
\ No newline at end of file
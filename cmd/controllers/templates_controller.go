@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TemplatesController struct {
+	db *sql.DB
+}
+
+func NewTemplatesController(db *sql.DB) *TemplatesController {
+	return &TemplatesController{
+		db,
+	}
+}
+
+func ReadTemplateFromJson(template *models.Template, body []byte) error {
+	if err := utilities.ReadJson(body, template); err != nil || template.CreatedBy == 0 {
+		return locales.New("invalid_template")
+	}
+
+	return nil
+}
+
+func (tc *TemplatesController) CreateTemplate(c *fiber.Ctx) error {
+	template := models.Template{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := ReadTemplateFromJson(&template, c.Body()); err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	if !template.ValidateTemplate() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	id, err := template.InsertTemplate(tc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":       id,
+			"template": template,
+		},
+	})
+}
+
+// Saves existing to dos as a template, reads {"created_by": id, "title": "...", "todos": [ids]}
+func (tc *TemplatesController) CreateTemplateFromToDos(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	holder := struct {
+		Title     string  `json:"title"`
+		ToDos     []int64 `json:"todos"`
+		CreatedBy int64   `json:"created_by"`
+	}{}
+
+	if err := utilities.ReadJson(c.Body(), &holder); err != nil || holder.CreatedBy == 0 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_template",
+		})
+	}
+
+	template := models.Template{
+		Title:     holder.Title,
+		CreatedBy: holder.CreatedBy,
+	}
+
+	id, err := template.InsertTemplateFromToDos(holder.ToDos, tc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":       id,
+			"template": template,
+		},
+	})
+}
+
+func (tc *TemplatesController) GetAllTemplates(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	template := models.Template{
+		CreatedBy: int64(id),
+	}
+
+	templates, err := template.GetAllTemplatesFromUserId(tc.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   templates,
+	})
+}
+
+func (tc *TemplatesController) GetTemplate(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	template := models.Template{
+		CreatedBy: int64(userId),
+	}
+
+	if err := template.GetTemplateById(int64(id), tc.db); err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":       id,
+			"template": template,
+		},
+	})
+}
+
+func (tc *TemplatesController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		template := models.Template{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_template_id",
+			})
+		}
+
+		if err := ReadTemplateFromJson(&template, c.Body()); err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		if !delete && !template.ValidateTemplate() {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		err = template.UpdateTemplateById(int64(id), delete, tc.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   template,
+		})
+	}
+}
+
+// Creates the to dos of the template, reads {"created_by": id, "date": unix millis} where
+// the deadlines are taken from date
+func (tc *TemplatesController) Instantiate(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_template_id",
+		})
+	}
+
+	holder := struct {
+		Date      int64 `json:"date"`
+		CreatedBy int64 `json:"created_by"`
+	}{}
+
+	if err := utilities.ReadJson(c.Body(), &holder); err != nil || holder.CreatedBy == 0 || holder.Date <= 0 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_definition",
+		})
+	}
+
+	template := models.Template{
+		CreatedBy: holder.CreatedBy,
+	}
+
+	ids, err := template.Instantiate(int64(id), time.UnixMilli(holder.Date), tc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"template": id,
+			"todos":    ids,
+		},
+	})
+}
@@ -0,0 +1,469 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Range of the time reports when the query doesn't give one
+const DEFAULT_REPORT_DAYS = 7
+
+type TimeController struct {
+	db *sql.DB
+}
+
+func NewTimeController(db *sql.DB) *TimeController {
+	return &TimeController{
+		db,
+	}
+}
+
+// Reads the from and to queries (unix millis), the period and the timezone of the
+// reports. The last week of UTC by day is the default
+func ReadTimeReportFilter(c *fiber.Ctx) (models.TimeReportFilter, error) {
+	errFilter := locales.New("invalid_filter")
+	filter := models.TimeReportFilter{
+		To:     time.Now(),
+		Period: c.Query("period", models.REPORT_PERIOD_DAY),
+	}
+
+	location, err := time.LoadLocation(c.Query("timezone", "UTC"))
+
+	if err != nil {
+		return filter, errFilter
+	}
+
+	filter.Location = location
+	bounds := map[string]*time.Time{"from": &filter.From, "to": &filter.To}
+
+	for key, bound := range bounds {
+		if value := c.Query(key); value != "" {
+			unix, err := strconv.ParseInt(value, 10, 64)
+
+			if err != nil || unix < 0 {
+				return filter, errFilter
+			}
+
+			*bound = time.UnixMilli(unix)
+		}
+	}
+
+	if c.Query("from") == "" {
+		filter.From = filter.To.AddDate(0, 0, -DEFAULT_REPORT_DAYS)
+	}
+
+	if !filter.Validate() {
+		return filter, errFilter
+	}
+
+	return filter, nil
+}
+
+// Reads the todo and user of the timer routes, {"created_by": id} in the body
+func readTimeEntry(c *fiber.Ctx) (models.TimeEntry, error) {
+	entry := models.TimeEntry{}
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		return entry, locales.New("invalid_todo_id")
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		return entry, err
+	}
+
+	entry.ToDo = int64(id)
+	entry.CreatedBy = userId
+
+	return entry, nil
+}
+
+func (tc *TimeController) CreateTimerFuncs(start bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		entry, err := readTimeEntry(c)
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		var id int64
+
+		if start {
+			id, err = entry.StartTimer(tc.db)
+		} else {
+			id, err = entry.StopTimer(tc.db)
+		}
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		if start {
+			code = http.StatusCreated
+		}
+
+		return c.JSON(models.Response{
+			Status: code,
+			Body: fiber.Map{
+				"id":    id,
+				"entry": entry,
+			},
+		})
+	}
+}
+
+func (tc *TimeController) GetRunningTimer(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	userId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	entry := models.TimeEntry{
+		CreatedBy: int64(userId),
+	}
+
+	timer, running, err := entry.GetRunningTimer(tc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"running": running,
+			"timer":   timer,
+		},
+	})
+}
+
+// Reads {"created_by": id, "started_at": date, "ended_at": date}
+func (tc *TimeController) CreateTimeEntry(c *fiber.Ctx) error {
+	entry := models.TimeEntry{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	if err := utilities.ReadJson(c.Body(), &entry); err != nil || entry.CreatedBy == 0 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_time_entry",
+		})
+	}
+
+	entry.ToDo = int64(id)
+
+	if !entry.ValidateTimeEntry() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	entryId, err := entry.InsertTimeEntry(tc.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":    entryId,
+			"entry": entry,
+		},
+	})
+}
+
+func (tc *TimeController) DeleteTimeEntry(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, entryId, err := listParams(c, "entry")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	entry := models.TimeEntry{
+		ToDo:      todoId,
+		CreatedBy: userId,
+	}
+
+	if err := entry.DeleteTimeEntry(entryId, tc.db); err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   entryId,
+	})
+}
+
+func (tc *TimeController) GetTimeEntries(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	entry := models.TimeEntry{
+		ToDo:      int64(todoId),
+		CreatedBy: int64(userId),
+	}
+
+	entries, err := entry.GetTimeEntries(tc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   entries,
+	})
+}
+
+// Totals of the user by to do or, with by=tag, by tag
+func (tc *TimeController) GetTimeTotals(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	userId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	filter, err := ReadTimeReportFilter(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	totals, err := models.GetTimeTotals(int64(userId), c.Query("by", models.TIME_TOTALS_TODO), filter, tc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   totals,
+	})
+}
+
+// Time of the user by day or week, format=csv downloads one row per period and to do
+func (tc *TimeController) GetTimeReport(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	userId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	filter, err := ReadTimeReportFilter(c)
+	format := c.Query("format", "json")
+
+	if err != nil || (format != "json" && format != "csv") {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_filter",
+		})
+	}
+
+	report, err := models.GetTimeReport(int64(userId), filter, tc.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+
+	if format == "json" {
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   report,
+		})
+	}
+
+	buffer := bytes.Buffer{}
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{filter.Period, "todo", "title", "seconds"})
+
+	for _, period := range report {
+		for _, todo := range period["todos"].([]map[string]any) {
+			writer.Write([]string{
+				period["period"].(string),
+				strconv.FormatInt(todo["id"].(int64), 10),
+				csvCell(todo["title"].(string)),
+				strconv.FormatInt(todo["seconds"].(int64), 10),
+			})
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		code = http.StatusInternalServerError
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "internal_error",
+		})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="time-report.csv"`)
+	c.Type("csv", "utf-8")
+
+	return c.Send(buffer.Bytes())
+}
+
+// Spreadsheets run the cells starting with these as formulas, a leading quote keeps them text
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
@@ -2,15 +2,19 @@ package controllers
 
 import (
 	"database/sql"
-	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
 	"github.com/gofiber/fiber/v2"
 )
 
+const DEFAULT_TODOS_PER_PAGE = 50
+
 type ToDoController struct {
 	db *sql.DB
 }
@@ -22,9 +26,18 @@ func NewToDoController(db *sql.DB) *ToDoController {
 }
 
 func ReadToDoFromJson(todo *models.ToDo, body []byte) error {
+	return readToDoJson(todo, body, false)
+}
+
+// Reads only the fields the body has over the stored to do, for the /v2 PATCH
+func ReadToDoPatchFromJson(todo *models.ToDo, body []byte) error {
+	return readToDoJson(todo, body, true)
+}
+
+func readToDoJson(todo *models.ToDo, body []byte, partial bool) error {
 	holder := make(map[string]any)
 	err := utilities.ReadJson(body, &holder)
-	errDefinition := errors.New("invalid definition")
+	errDefinition := locales.New("invalid_definition")
 
 	if err != nil {
 		return errDefinition
@@ -35,21 +48,217 @@ func ReadToDoFromJson(todo *models.ToDo, body []byte) error {
 	userId, ok3 := holder["created_by"].(float64)
 	desc, ok4 := holder["description"].(string)
 	title, ok5 := holder["title"].(string)
-	tag, ok6 := holder["tag"].(float64)
 
-	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 {
+	// A partial body can leave fields out, but the ones it has must be right
+	for field, ok := range map[string]bool{"deadline": ok1, "color": ok2, "created_by": ok3, "description": ok4, "title": ok5} {
+		if _, sent := holder[field]; !ok && (sent || !partial) {
+			return errDefinition
+		}
+	}
+
+	// A list of tags, or a single one from the older clients
+	if list, ok := holder["tags"].([]any); ok {
+		todo.Tags = make([]int64, 0, len(list))
+
+		for _, value := range list {
+			tag, ok := value.(float64)
+
+			if !ok {
+				return errDefinition
+			}
+
+			todo.Tags = append(todo.Tags, int64(tag))
+		}
+	} else if tag, ok := holder["tag"].(float64); ok {
+		todo.Tags = nil
+		todo.Tag = int64(tag)
+	} else if holder["tag"] != nil {
 		return errDefinition
+	} else {
+		// Without tags the stored ones stay, see UpdateToDoById
+		todo.Tags = nil
+		todo.Tag = 0
+	}
+
+	if ok1 {
+		todo.Deadline = time.UnixMilli(int64(unix))
+	}
+
+	if ok2 {
+		todo.Color = uint(color)
+	}
+
+	if ok3 {
+		todo.CreatedBy = int64(userId)
+	}
+
+	if ok4 {
+		todo.Description = desc
+	}
+
+	if ok5 {
+		todo.Title = title
+	}
+
+	todo.Sent = make(map[string]bool)
+
+	for field := range holder {
+		todo.Sent[field] = true
+	}
+
+	// Optional, only recurring to dos send them
+	if recurrence, ok := holder["recurrence"].(string); ok {
+		todo.Recurrence = recurrence
+	}
+
+	if timezone, ok := holder["timezone"].(string); ok {
+		todo.Timezone = timezone
+	}
+
+	if priority, ok := holder["priority"].(float64); ok {
+		todo.Priority = int(priority)
 	}
 
-	todo.Deadline = time.UnixMilli(int64(unix))
-	todo.Color = uint(color)
-	todo.CreatedBy = int64(userId)
-	todo.Description = desc
-	todo.Title = title
-	todo.Tag = int64(tag)
+	if list, ok := holder["list"].(float64); ok {
+		todo.List = int64(list)
+	}
+
+	if workspace, ok := holder["workspace"].(float64); ok {
+		todo.Workspace = int64(workspace)
+	}
 	return nil
 }
 
+// Reads the listing filters from the query string, dates are unix millis as in the to dos
+func ReadToDoFilter(c *fiber.Ctx, archived bool) (models.ToDoFilter, error) {
+	errFilter := locales.New("invalid_filter")
+	filter := models.ToDoFilter{
+		State:    c.Query("state", models.TODO_STATE_ALL),
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+		Search:   c.Query("q"),
+		Cursor:   c.Query("cursor"),
+		TagMatch: c.Query("tag_match"),
+		Archived: archived,
+	}
+
+	if completed := c.Query("completed"); completed != "" {
+		done, err := strconv.ParseBool(completed)
+
+		if err != nil || filter.State != models.TODO_STATE_ALL {
+			return filter, errFilter
+		}
+
+		filter.State = models.TODO_STATE_OPEN
+		if done {
+			filter.State = models.TODO_STATE_COMPLETED
+		}
+	}
+
+	if overdue := c.Query("overdue"); overdue != "" {
+		var err error
+		if filter.Overdue, err = strconv.ParseBool(overdue); err != nil {
+			return filter, errFilter
+		}
+	}
+
+	numbers := map[string]int64{}
+
+	for _, key := range []string{"color", "deadline_from", "deadline_to", "limit", "assigned_to", "workspace"} {
+		if value := c.Query(key); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+
+			if err != nil || n < 0 {
+				return filter, errFilter
+			}
+
+			numbers[key] = n
+		}
+	}
+
+	// tags=1,2,3 and tag=1 can be mixed
+	tags := strings.Split(c.Query("tags"), ",")
+	tags = append(tags, c.Query("tag"))
+
+	for _, value := range tags {
+		if value == "" {
+			continue
+		}
+
+		tag, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil || tag <= 0 {
+			return filter, errFilter
+		}
+
+		filter.Tags = append(filter.Tags, tag)
+	}
+
+	if color, ok := numbers["color"]; ok {
+		value := uint(color)
+		filter.Color = &value
+	}
+
+	filter.AssignedTo = numbers["assigned_to"]
+	filter.Workspace = numbers["workspace"]
+
+	if unix, ok := numbers["deadline_from"]; ok {
+		from := time.UnixMilli(unix)
+		filter.DeadlineFrom = &from
+	}
+
+	if unix, ok := numbers["deadline_to"]; ok {
+		to := time.UnixMilli(unix)
+		filter.DeadlineTo = &to
+	}
+
+	// The /v2 listing is always paginated, the older ones only when a limit is given
+	if limit, ok := numbers["limit"]; ok {
+		if limit == 0 {
+			return filter, errFilter
+		}
+		filter.Limit = int(limit)
+	} else if c.Params("id") == "" {
+		filter.Limit = DEFAULT_TODOS_PER_PAGE
+	}
+
+	if filter.Limit > models.MAX_TODOS_PER_PAGE || !filter.Validate() {
+		return filter, errFilter
+	}
+
+	if err := filter.ValidateCursor(); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// Reads the owner of the to do from a {"created_by": id} body
+func ReadOwnerFromJson(body []byte) (int64, error) {
+	holder := make(map[string]any)
+	err := utilities.ReadJson(body, &holder)
+	errDefinition := locales.New("invalid_definition")
+
+	if err != nil {
+		return 0, errDefinition
+	}
+
+	userId, ok := holder["created_by"].(float64)
+
+	if !ok {
+		return 0, errDefinition
+	}
+
+	return int64(userId), nil
+}
+
+func ValidateToDo(todo *models.ToDo) bool {
+	titleOk, _ := todo.ValidateTitle()
+	descOk, _ := todo.ValidateDescription()
+
+	return titleOk && descOk && todo.ValidatePriority() && todo.ValidateTags() && todo.ValidateRecurrence()
+}
+
 func (t *ToDoController) CreateToDo(c *fiber.Ctx) error {
 	todo := models.ToDo{}
 	code := http.StatusInternalServerError
@@ -63,8 +272,17 @@ func (t *ToDoController) CreateToDo(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status:   code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	if !ValidateToDo(&todo) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
 		})
 	}
 
@@ -73,8 +291,9 @@ func (t *ToDoController) CreateToDo(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusConflict
 		return c.JSON(models.Response{
-			Status:   code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
@@ -102,8 +321,8 @@ func (t *ToDoController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 		if err != nil {
 			code = http.StatusBadRequest
 			return c.JSON(models.Response{
-				Status:   code,
-				ErrorMsg: "invalid to do id",
+				Status:    code,
+				ErrorCode: "invalid_todo_id",
 			})
 		}
 
@@ -112,8 +331,16 @@ func (t *ToDoController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 		if err != nil {
 			code = http.StatusBadRequest
 			return c.JSON(models.Response{
-				Status:   code,
-				ErrorMsg: "invalid to do definition",
+				Status:    code,
+				ErrorCode: "invalid_todo",
+			})
+		}
+
+		if !delete && !ValidateToDo(&todo) {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
 			})
 		}
 
@@ -122,8 +349,86 @@ func (t *ToDoController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 		if err != nil {
 			code = http.StatusInternalServerError
 			return c.JSON(models.Response{
-				Status:   code,
-				ErrorMsg: err.Error(),
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   todo,
+		})
+	}
+}
+
+// The /v2 PATCH and DELETE. A DELETE only needs the caller, from the created_by query or
+// body, and a PATCH changes only the fields it sends
+func (t *ToDoController) CreatePatchOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_todo_id",
+			})
+		}
+
+		userId, err := callerParam(c)
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_user_id",
+			})
+		}
+
+		todo := models.ToDo{CreatedBy: userId}
+
+		if !delete {
+			if err := todo.GetToDoById(int64(id), t.db); err != nil {
+				code = http.StatusNotFound
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: locales.CodeOf(err),
+					ErrorMsg:  err.Error(),
+				})
+			}
+
+			if err := ReadToDoPatchFromJson(&todo, c.Body()); err != nil || todo.CreatedBy != userId {
+				code = http.StatusBadRequest
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: "invalid_todo",
+				})
+			}
+
+			if !ValidateToDo(&todo) {
+				code = http.StatusBadRequest
+				return c.JSON(models.Response{
+					Status:    code,
+					ErrorCode: "invalid_fields",
+				})
+			}
+		}
+
+		err = todo.UpdateToDoById(int64(id), delete, t.db)
+
+		if err != nil {
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
 			})
 		}
 
@@ -136,19 +441,39 @@ func (t *ToDoController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx)
 }
 
 func (t *ToDoController) GetAllToDosFromUserId(c *fiber.Ctx) error {
+	return t.listToDos(c, false)
+}
+
+// Lists the archived to dos, with the same filters and pagination as the to dos in use
+func (t *ToDoController) GetArchivedToDos(c *fiber.Ctx) error {
+	return t.listToDos(c, true)
+}
+
+func (t *ToDoController) listToDos(c *fiber.Ctx, archived bool) error {
 	code := http.StatusInternalServerError
 
 	defer func() {
 		c.Status(code)
 	}()
 
-	id, err := c.ParamsInt("id")
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	filter, err := ReadToDoFilter(c, archived)
 
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status:   code,
-			ErrorMsg: "invalid user id",
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
@@ -156,19 +481,35 @@ func (t *ToDoController) GetAllToDosFromUserId(c *fiber.Ctx) error {
 		CreatedBy: int64(id),
 	}
 
-	todos, err := todo.GetAllToDosFromUserId(t.db)
+	page, err := todo.GetAllToDosFromUserId(filter, t.db)
 
 	if err != nil {
 		code = http.StatusInternalServerError
 		return c.JSON(models.Response{
-			Status:   code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
+	code = http.StatusOK
+
+	if c.Params("id") == "" {
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   page,
+		})
+	}
+
+	// The older listings keep the plain array, the page goes in the headers
+	c.Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		c.Set("X-Next-Cursor", page.NextCursor)
+	}
+
 	return c.JSON(models.Response{
 		Status: code,
-		Body:   todos,
+		Body:   page.ToDos,
 	})
 }
 
@@ -183,8 +524,8 @@ func (t *ToDoController) DeleteAllToDosFromUserId(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusBadRequest
 		return c.JSON(models.Response{
-			Status:   code,
-			ErrorMsg: "invalid user id",
+			Status:    code,
+			ErrorCode: "invalid_user_id",
 		})
 	}
 
@@ -197,8 +538,9 @@ func (t *ToDoController) DeleteAllToDosFromUserId(c *fiber.Ctx) error {
 	if err != nil {
 		code = http.StatusInternalServerError
 		return c.JSON(models.Response{
-			Status:   code,
-			ErrorMsg: err.Error(),
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
 		})
 	}
 
@@ -208,3 +550,503 @@ func (t *ToDoController) DeleteAllToDosFromUserId(c *fiber.Ctx) error {
 		Body:   "deleted all todos",
 	})
 }
+
+func (t *ToDoController) CreateCompleteFuncs(completed bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_todo_id",
+			})
+		}
+
+		userId, err := ReadOwnerFromJson(c.Body())
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		todo := models.ToDo{
+			CreatedBy: userId,
+		}
+
+		nextId, err := todo.SetCompleted(int64(id), completed, t.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		unblocked := make([]int64, 0)
+
+		// The to do is already completed, a failed lookup only leaves the list empty
+		if completed {
+			if ids, err := todo.UnblockedBy(int64(id), t.db); err == nil {
+				unblocked = ids
+			}
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body: fiber.Map{
+				"id":        id,
+				"completed": completed,
+				"next":      nextId,
+				"unblocked": unblocked,
+			},
+		})
+	}
+}
+
+func (t *ToDoController) CreateArchiveFuncs(archived bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_todo_id",
+			})
+		}
+
+		userId, err := ReadOwnerFromJson(c.Body())
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		todo := models.ToDo{
+			CreatedBy: userId,
+		}
+
+		err = todo.SetArchived(int64(id), archived, t.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body: fiber.Map{
+				"id":       id,
+				"archived": archived,
+			},
+		})
+	}
+}
+
+// Moves the to do in the manual order of its owner, reads {"created_by": id, "after": id | null}
+// where a null after puts it first
+func (t *ToDoController) MoveToDo(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	holder := make(map[string]any)
+	err = utilities.ReadJson(c.Body(), &holder)
+	userId, ok := holder["created_by"].(float64)
+	after, afterOk := holder["after"].(float64)
+
+	if err != nil || !ok || (!afterOk && holder["after"] != nil) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_order",
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: int64(userId),
+	}
+
+	position, err := todo.MoveToDo(int64(id), int64(after), t.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":       id,
+			"position": position,
+		},
+	})
+}
+
+func (t *ToDoController) GetHistory(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	userId := c.QueryInt("created_by", -1)
+
+	if userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: int64(userId),
+	}
+
+	history, err := todo.GetHistory(int64(id), t.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   history,
+	})
+}
+
+// Puts the to do back as a revision left it, reads {"created_by": id, "revision": id}
+func (t *ToDoController) Undo(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	holder := make(map[string]any)
+	err = utilities.ReadJson(c.Body(), &holder)
+	userId, ok1 := holder["created_by"].(float64)
+	revision, ok2 := holder["revision"].(float64)
+
+	if err != nil || !ok1 || !ok2 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_definition",
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: int64(userId),
+	}
+
+	undo, err := todo.RestoreRevision(int64(id), int64(revision), t.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   undo,
+	})
+}
+
+// Puts the to do in a shared list or takes it out, reads {"created_by": id, "list": id | null}
+func (t *ToDoController) SetList(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	userId, listId, err := readOwnerAndId(c.Body(), "list")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: userId,
+	}
+
+	if err := todo.SetList(int64(id), listId, t.db); err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   id,
+			"list": listId,
+		},
+	})
+}
+
+func (t *ToDoController) GetDependencies(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	userId := c.QueryInt("created_by", -1)
+
+	if userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	todo := models.ToDo{
+		CreatedBy: int64(userId),
+	}
+
+	dependencies, err := todo.GetDependencies(int64(id), t.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   dependencies,
+	})
+}
+
+// Makes the to do wait for the :blocker to do or stops it, reads {"created_by": id}
+func (t *ToDoController) CreateBlockerFuncs(add bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, blocker, err := listParams(c, "blocker")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		userId, err := ReadOwnerFromJson(c.Body())
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		todo := models.ToDo{
+			CreatedBy: userId,
+		}
+
+		if add {
+			err = todo.AddBlocker(id, blocker, t.db)
+		} else {
+			err = todo.RemoveBlocker(id, blocker, t.db)
+		}
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body: fiber.Map{
+				"id":      id,
+				"blocker": blocker,
+				"blocked": add,
+			},
+		})
+	}
+}
+
+// Reads a to do from a line of text, {"created_by": id, "text": "Pay rent tomorrow 9am #home !red", "timezone": "America/Mexico_City"}.
+// Nothing is created, the to do comes back in the body of POST /v2/todos to be confirmed
+func (t *ToDoController) QuickAdd(c *fiber.Ctx) error {
+	quickAdd := models.QuickAdd{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := utilities.ReadJson(c.Body(), &quickAdd); err != nil || quickAdd.CreatedBy == 0 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_quick_add",
+		})
+	}
+
+	// Only decides the order of day and month in 5/3
+	preferred, _ := models.GetUserLocale(int(quickAdd.CreatedBy), t.db)
+	locale := locales.Negotiate(c.Get(fiber.HeaderAcceptLanguage), preferred)
+
+	result, err := quickAdd.Parse(locale, t.db)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	todo := result.ToDo
+	valid := ValidateToDo(&todo)
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"todo": fiber.Map{
+				"title":       todo.Title,
+				"description": todo.Description,
+				"color":       todo.Color,
+				"deadline":    todo.Deadline.UnixMilli(),
+				"tag":         todo.Tag,
+				"tags":        todo.Tags,
+				"created_by":  todo.CreatedBy,
+				"timezone":    todo.Timezone,
+			},
+			"dated":        result.Dated,
+			"unknown_tags": result.UnknownTags,
+			"valid":        valid,
+		},
+	})
+}
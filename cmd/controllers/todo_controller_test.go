@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+)
+
+func storedToDo() models.ToDo {
+	return models.ToDo{
+		Title:       "Stored",
+		Description: "Kept",
+		Color:       3,
+		Deadline:    time.UnixMilli(1700000000000),
+		Tag:         4,
+		Tags:        []int64{4, 5},
+		CreatedBy:   7,
+		Recurrence:  "FREQ=DAILY",
+		Timezone:    "America/Mexico_City",
+		Priority:    2,
+	}
+}
+
+func TestReadToDoPatchFromJson(t *testing.T) {
+	todo := storedToDo()
+
+	if err := ReadToDoPatchFromJson(&todo, []byte(`{"title": "New title", "priority": 3}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := storedToDo()
+	want.Title = "New title"
+	want.Priority = 3
+
+	// The stored tags are taken back by UpdateToDoById when none are sent
+	if todo.Tags != nil || todo.Tag != 0 {
+		t.Errorf("read tags %v and tag %d, want none", todo.Tags, todo.Tag)
+	}
+
+	if todo.Title != want.Title || todo.Description != want.Description || todo.Color != want.Color ||
+		!todo.Deadline.Equal(want.Deadline) || todo.CreatedBy != want.CreatedBy || todo.Recurrence != want.Recurrence ||
+		todo.Timezone != want.Timezone || todo.Priority != want.Priority {
+		t.Errorf("read %+v, want %+v", todo, want)
+	}
+
+	if !todo.Sent["title"] || !todo.Sent["priority"] || todo.Sent["tags"] || todo.Sent["recurrence"] {
+		t.Errorf("sent fields = %v", todo.Sent)
+	}
+}
+
+func TestReadToDoPatchFromJsonTags(t *testing.T) {
+	todo := storedToDo()
+
+	if err := ReadToDoPatchFromJson(&todo, []byte(`{"tags": [6, 4]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(todo.Tags, []int64{6, 4}) {
+		t.Errorf("read tags %v, want [6 4]", todo.Tags)
+	}
+
+	todo = storedToDo()
+
+	if err := ReadToDoPatchFromJson(&todo, []byte(`{"tag": 6}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if todo.Tags != nil || todo.Tag != 6 {
+		t.Errorf("read tags %v and tag %d, want only tag 6", todo.Tags, todo.Tag)
+	}
+}
+
+func TestReadToDoFromJsonWrongTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		partial bool
+	}{
+		{"full body missing the title", `{"description": "", "color": 1, "deadline": 1, "created_by": 7}`, false},
+		{"full body with a string color", `{"title": "Title", "description": "", "color": "1", "deadline": 1, "created_by": 7}`, false},
+		{"partial body with a string color", `{"color": "1"}`, true},
+		{"partial body with a null title", `{"title": null}`, true},
+		{"partial body with a string tag", `{"tag": "1"}`, true},
+		{"partial body that is not json", `title=New`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			todo := storedToDo()
+			read := ReadToDoFromJson
+
+			if test.partial {
+				read = ReadToDoPatchFromJson
+			}
+
+			if err := read(&todo, []byte(test.body)); err == nil {
+				t.Errorf("read %s without error", test.body)
+			}
+		})
+	}
+}
+
+func TestReadToDoFromJsonFull(t *testing.T) {
+	todo := models.ToDo{}
+	body := `{"title": "Title", "description": "", "color": 1, "deadline": 1700000000000, "created_by": 7}`
+
+	if err := ReadToDoFromJson(&todo, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+
+	if todo.Title != "Title" || todo.Color != 1 || todo.CreatedBy != 7 || todo.Deadline.UnixMilli() != 1700000000000 {
+		t.Errorf("read %+v", todo)
+	}
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ToDoItemsController struct {
+	db *sql.DB
+}
+
+func NewToDoItemsController(db *sql.DB) *ToDoItemsController {
+	return &ToDoItemsController{
+		db,
+	}
+}
+
+func (t *ToDoItemsController) CreateItem(c *fiber.Ctx) error {
+	item := models.ToDoItem{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	err = utilities.ReadJson(c.Body(), &item)
+
+	if err != nil || !item.ValidateText() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_item",
+		})
+	}
+
+	item.ToDo = int64(todoId)
+	id, err := item.InsertItem(t.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   id,
+			"item": item,
+		},
+	})
+}
+
+func (t *ToDoItemsController) GetAllItems(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	userId := c.QueryInt("created_by", -1)
+
+	if userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	item := models.ToDoItem{
+		ToDo:      int64(todoId),
+		CreatedBy: int64(userId),
+	}
+
+	items, err := item.GetAllItemsFromToDo(t.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   items,
+	})
+}
+
+func (t *ToDoItemsController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		item := models.ToDoItem{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		todoId, err := c.ParamsInt("id")
+		itemId, itemErr := c.ParamsInt("item")
+
+		if err != nil || itemErr != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_id",
+			})
+		}
+
+		err = utilities.ReadJson(c.Body(), &item)
+
+		if err != nil || (!delete && !item.ValidateText()) {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_item",
+			})
+		}
+
+		item.ToDo = int64(todoId)
+		err = item.UpdateItemById(int64(itemId), delete, t.db)
+
+		if err != nil {
+			code = http.StatusInternalServerError
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   item,
+		})
+	}
+}
+
+func (t *ToDoItemsController) ReorderItems(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+	body := struct {
+		CreatedBy int64   `json:"created_by"`
+		Items     []int64 `json:"items"`
+	}{}
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	todoId, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_todo_id",
+		})
+	}
+
+	err = utilities.ReadJson(c.Body(), &body)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_order",
+		})
+	}
+
+	item := models.ToDoItem{
+		ToDo:      int64(todoId),
+		CreatedBy: body.CreatedBy,
+	}
+
+	err = item.ReorderItems(body.Items, t.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	items, err := item.GetAllItemsFromToDo(t.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   items,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
 	"github.com/gofiber/fiber/v2"
@@ -33,8 +34,8 @@ func (u *UserController) ValidateToken(c *fiber.Ctx) error {
 	c.Status(http.StatusUnauthorized)
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusUnauthorized,
-			ErrorMsg: "Invalid",
+			Status:    http.StatusUnauthorized,
+			ErrorCode: "invalid_credentials",
 		})
 	}
 
@@ -43,8 +44,8 @@ func (u *UserController) ValidateToken(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusUnauthorized,
-			ErrorMsg: "Invalid",
+			Status:    http.StatusUnauthorized,
+			ErrorCode: "invalid_credentials",
 		})
 	}
 
@@ -56,11 +57,13 @@ func (u *UserController) ValidateToken(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusUnauthorized,
-			ErrorMsg: "Invalid user credentials",
+			Status:    http.StatusUnauthorized,
+			ErrorCode: "invalid_credentials",
 		})
 	}
 
+	c.Locals("locale", user.Locale)
+
 	c.Status(http.StatusOK)
 	return c.JSON(models.Response{
 		Status: http.StatusAccepted,
@@ -75,11 +78,19 @@ func (u *UserController) Register(c *fiber.Ctx) error {
 	user := models.UserDTO{}
 	utilities.ReadJson(c.Body(), &user)
 
-	if ok, err := user.ValidateUser(); !ok && err != nil {
+	ok, err := user.ValidateUser()
+
+	if user.Locale == "" {
+		user.Locale = locales.Negotiate(c.Get(fiber.HeaderAcceptLanguage), "")
+	}
+
+	c.Locals("locale", user.Locale)
+
+	if !ok && err != nil {
 		c.Status(http.StatusBadRequest)
 		return c.JSON(models.Response{
-			Status:   http.StatusBadRequest,
-			ErrorMsg: "Invalid fields",
+			Status:    http.StatusBadRequest,
+			ErrorCode: "invalid_fields",
 		})
 	}
 
@@ -88,24 +99,24 @@ func (u *UserController) Register(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
 	if count != 0 {
 		c.Status(http.StatusConflict)
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Mail already in use",
+			Status:    http.StatusConflict,
+			ErrorCode: "mail_in_use",
 		})
 	}
 
 	hash, err := user.GeneratePasswordHash()
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -113,8 +124,8 @@ func (u *UserController) Register(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -122,8 +133,8 @@ func (u *UserController) Register(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -145,16 +156,18 @@ func (u *UserController) Edit(c *fiber.Ctx) error {
 	err := utilities.ReadJson(c.Body(), &userDto)
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
-	if ok, err := userDto.ValidateUser(); !ok && err != nil {
+	ok, err := userDto.ValidateUser()
+
+	if !ok && err != nil {
 		c.Status(http.StatusBadRequest)
 		return c.JSON(models.Response{
-			Status:   http.StatusBadRequest,
-			ErrorMsg: "Invalid fields",
+			Status:    http.StatusBadRequest,
+			ErrorCode: "invalid_fields",
 		})
 	}
 
@@ -163,8 +176,8 @@ func (u *UserController) Edit(c *fiber.Ctx) error {
 	if err != nil {
 		c.Status(http.StatusBadRequest)
 		return c.JSON(models.Response{
-			Status:   http.StatusBadRequest,
-			ErrorMsg: "Invalid id",
+			Status:    http.StatusBadRequest,
+			ErrorCode: "invalid_id",
 		})
 	}
 
@@ -172,8 +185,8 @@ func (u *UserController) Edit(c *fiber.Ctx) error {
 
 	if !valid || err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -181,16 +194,16 @@ func (u *UserController) Edit(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
 	if count != 0 {
 		c.Status(http.StatusConflict)
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "New mail already in use",
+			Status:    http.StatusConflict,
+			ErrorCode: "new_mail_in_use",
 		})
 	}
 
@@ -198,8 +211,8 @@ func (u *UserController) Edit(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -207,17 +220,22 @@ func (u *UserController) Edit(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
+	if userDto.Locale == "" {
+		userDto.Locale, _ = models.GetUserLocale(id, u.db)
+	}
+
+	c.Locals("locale", userDto.Locale)
 	tk, err := models.GeneratePasetoToken(&userDto, int64(id))
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -242,8 +260,8 @@ func (u *UserController) Delete(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusBadRequest,
-			ErrorMsg: "Invalid parameter",
+			Status:    http.StatusBadRequest,
+			ErrorCode: "invalid_id",
 		})
 	}
 
@@ -253,8 +271,8 @@ func (u *UserController) Delete(c *fiber.Ctx) error {
 	if !valid || err != nil {
 		statusCode = http.StatusConflict
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -263,8 +281,8 @@ func (u *UserController) Delete(c *fiber.Ctx) error {
 	if err != nil {
 		statusCode = http.StatusInternalServerError
 		return c.JSON(models.Response{
-			Status:   http.StatusInternalServerError,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusInternalServerError,
+			ErrorCode: "unexpected_error",
 		})
 	}
 	statusCode = http.StatusOK
@@ -279,8 +297,8 @@ func (u *UserController) Login(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Invalid input fields",
+			Status:    http.StatusConflict,
+			ErrorCode: "invalid_fields",
 		})
 	}
 
@@ -288,15 +306,15 @@ func (u *UserController) Login(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Error validating user data",
+			Status:    http.StatusConflict,
+			ErrorCode: "user_validation_failed",
 		})
 	}
 
 	if id == -1 || passwordHash == "" {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Incorrect mail or password",
+			Status:    http.StatusConflict,
+			ErrorCode: "wrong_mail_or_password",
 		})
 	}
 
@@ -304,17 +322,18 @@ func (u *UserController) Login(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusUnauthorized,
-			ErrorMsg: "Incorrect mail or password",
+			Status:    http.StatusUnauthorized,
+			ErrorCode: "wrong_mail_or_password",
 		})
 	}
 
+	c.Locals("locale", userDto.Locale)
 	tk, err := models.GeneratePasetoToken(&userDto, int64(id))
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 	return c.JSON(models.Response{
@@ -332,8 +351,8 @@ func (u *UserController) ConvertToPremium(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -342,8 +361,8 @@ func (u *UserController) ConvertToPremium(c *fiber.Ctx) error {
 
 	if !valid || err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusUnauthorized,
-			ErrorMsg: "Invalid user",
+			Status:    http.StatusUnauthorized,
+			ErrorCode: "invalid_user",
 		})
 	}
 
@@ -351,8 +370,8 @@ func (u *UserController) ConvertToPremium(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -373,8 +392,8 @@ func (u *UserController) UpdateProfileImage(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -391,9 +410,9 @@ func (u *UserController) UpdateProfileImage(c *fiber.Ctx) error {
 
 	if err != nil || len(files) == 0 {
 		return c.JSON(models.Response{
-			Status:   http.StatusBadRequest,
-			ErrorMsg: "File doesnt exist",
-			Body:     form.File,
+			Status:    http.StatusBadRequest,
+			ErrorCode: "file_missing",
+			Body:      form.File,
 		})
 	}
 
@@ -401,8 +420,8 @@ func (u *UserController) UpdateProfileImage(c *fiber.Ctx) error {
 	res, err := uploadImage(cld, ctx, file)
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusBadRequest,
-			ErrorMsg: "Cannot upload image",
+			Status:    http.StatusBadRequest,
+			ErrorCode: "image_upload_failed",
 		})
 	}
 
@@ -410,8 +429,8 @@ func (u *UserController) UpdateProfileImage(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -428,15 +447,15 @@ func (u *UserController) RemoveProfileImage(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
 	if publicId == "" {
 		return c.JSON(models.Response{
-			Status:   http.StatusNoContent,
-			ErrorMsg: "No such image",
+			Status:    http.StatusNoContent,
+			ErrorCode: "image_not_found",
 		})
 	}
 
@@ -448,8 +467,8 @@ func (u *UserController) RemoveProfileImage(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
@@ -457,8 +476,8 @@ func (u *UserController) RemoveProfileImage(c *fiber.Ctx) error {
 
 	if err != nil {
 		return c.JSON(models.Response{
-			Status:   http.StatusConflict,
-			ErrorMsg: "Unexpected error",
+			Status:    http.StatusConflict,
+			ErrorCode: "unexpected_error",
 		})
 	}
 
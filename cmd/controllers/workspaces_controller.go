@@ -0,0 +1,454 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/utilities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WorkspacesController struct {
+	db *sql.DB
+}
+
+func NewWorkspacesController(db *sql.DB) *WorkspacesController {
+	return &WorkspacesController{
+		db,
+	}
+}
+
+func (w *WorkspacesController) CreateWorkspace(c *fiber.Ctx) error {
+	workspace := models.Workspace{}
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	if err := utilities.ReadJson(c.Body(), &workspace); err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_workspace",
+		})
+	}
+
+	if !workspace.ValidateWorkspace() {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_fields",
+		})
+	}
+
+	id, err := workspace.InsertWorkspace(w.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":        id,
+			"workspace": workspace,
+		},
+	})
+}
+
+func (w *WorkspacesController) GetAllWorkspaces(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := userIdParam(c)
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_user_id",
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: int64(id),
+	}
+
+	workspaces, err := workspace.GetAllWorkspacesFromUserId(w.db)
+
+	if err != nil {
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   workspaces,
+	})
+}
+
+// The workspace with its plan and the usage of its quotas, for any of its members
+func (w *WorkspacesController) GetWorkspace(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: int64(userId),
+	}
+
+	body, err := workspace.GetWorkspaceById(int64(id), w.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   body,
+	})
+}
+
+func (w *WorkspacesController) CreateUpdateOrDeleteFuncs(delete bool) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		workspace := models.Workspace{}
+		code := http.StatusInternalServerError
+
+		defer func() {
+			c.Status(code)
+		}()
+
+		id, err := c.ParamsInt("id")
+
+		if err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_workspace_id",
+			})
+		}
+
+		if err := utilities.ReadJson(c.Body(), &workspace); err != nil {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_workspace",
+			})
+		}
+
+		if !delete && !workspace.ValidateWorkspace() {
+			code = http.StatusBadRequest
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "invalid_fields",
+			})
+		}
+
+		err = workspace.UpdateWorkspaceById(int64(id), delete, w.db)
+
+		if err != nil {
+			code = http.StatusConflict
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: locales.CodeOf(err),
+				ErrorMsg:  err.Error(),
+			})
+		}
+
+		code = http.StatusOK
+		return c.JSON(models.Response{
+			Status: code,
+			Body:   workspace,
+		})
+	}
+}
+
+func (w *WorkspacesController) UpgradeToPremium(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_workspace_id",
+		})
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: userId,
+	}
+
+	expiracy, err := workspace.UpgradeToPremium(int64(id), w.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":               id,
+			"plan":             models.WORKSPACE_PLAN_PREMIUM,
+			"premium_expiracy": expiracy,
+		},
+	})
+}
+
+func (w *WorkspacesController) GetMembers(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+	userId := c.QueryInt("created_by", -1)
+
+	if err != nil || userId == -1 {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_id",
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: int64(userId),
+	}
+
+	members, err := workspace.GetMembers(int64(id), w.db)
+
+	if err != nil {
+		code = http.StatusNotFound
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   members,
+	})
+}
+
+// Adds a user by mail, reads {"created_by": id, "mail": "...", "role": "viewer" | "editor" | "owner"}
+func (w *WorkspacesController) AddMember(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, err := c.ParamsInt("id")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_workspace_id",
+		})
+	}
+
+	holder := struct {
+		CreatedBy int64  `json:"created_by"`
+		Mail      string `json:"mail"`
+		Role      string `json:"role"`
+	}{}
+
+	if err := utilities.ReadJson(c.Body(), &holder); err != nil || holder.Mail == "" || !models.IsValidRole(holder.Role) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_member",
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: holder.CreatedBy,
+	}
+
+	memberId, err := workspace.AddMember(int64(id), holder.Mail, holder.Role, w.db)
+
+	if err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusCreated
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   memberId,
+			"role": holder.Role,
+		},
+	})
+}
+
+// Changes the role of a member, reads {"created_by": id, "role": "viewer" | "editor" | "owner"}
+func (w *WorkspacesController) SetMemberRole(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, memberId, err := listParams(c, "user")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	holder := struct {
+		CreatedBy int64  `json:"created_by"`
+		Role      string `json:"role"`
+	}{}
+
+	if err := utilities.ReadJson(c.Body(), &holder); err != nil || !models.IsValidRole(holder.Role) {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: "invalid_role",
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: holder.CreatedBy,
+	}
+
+	if err := workspace.SetMemberRole(id, memberId, holder.Role, w.db); err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body: fiber.Map{
+			"id":   memberId,
+			"role": holder.Role,
+		},
+	})
+}
+
+func (w *WorkspacesController) RemoveMember(c *fiber.Ctx) error {
+	code := http.StatusInternalServerError
+
+	defer func() {
+		c.Status(code)
+	}()
+
+	id, memberId, err := listParams(c, "user")
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	userId, err := ReadOwnerFromJson(c.Body())
+
+	if err != nil {
+		code = http.StatusBadRequest
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	workspace := models.Workspace{
+		CreatedBy: userId,
+	}
+
+	if err := workspace.RemoveMember(id, memberId, w.db); err != nil {
+		code = http.StatusConflict
+		return c.JSON(models.Response{
+			Status:    code,
+			ErrorCode: locales.CodeOf(err),
+			ErrorMsg:  err.Error(),
+		})
+	}
+
+	code = http.StatusOK
+	return c.JSON(models.Response{
+		Status: code,
+		Body:   memberId,
+	})
+}
@@ -0,0 +1,86 @@
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>Nailit API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+	<script>
+		SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+	</script>
+</body>
+</html>`
+
+var paramRegex = regexp.MustCompile(`:(\w+)\??`)
+
+func ServeSpec(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(spec)
+}
+
+func ServeDocs(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(docsPage)
+}
+
+// Returns the registered routes that are not documented in the spec, as "METHOD /path"
+func MissingRoutes(app *fiber.App) ([]string, error) {
+	doc := struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}{}
+
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+
+		path := ToOpenAPIPath(route.Path)
+		key := route.Method + " " + path
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// "/todos/update/:id" -> "/todos/update/{id}"
+func ToOpenAPIPath(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return paramRegex.ReplaceAllString(path, "{$1}")
+}
@@ -1,27 +1,74 @@
 package main
 
 import (
+	"os"
+	"time"
+
 	"github.com/ArnulfoVargas/nailit_api.git/cmd/controllers"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/docs"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/middlewares"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
 	"github.com/gofiber/fiber/v2"
 )
 
 func (server *Server) handleControllers() {
+	server.app.Use(middlewares.Localize(server.db))
+	server.app.Use(server.rateLimiters())
+
 	server.app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"hello": "world",
 		})
 	})
 
-	server.mapUserRoutes()
-	server.mapTagsRoutes()
-	server.mapToDosRoutes()
-	server.mapPinnedImages()
+	// Unversioned routes are kept for old clients until the sunset date
+	server.mapV1Routes(server.app, middlewares.Deprecated(os.Getenv("LEGACY_SUNSET")))
+	server.mapV1Routes(server.app.Group("/v1"))
+	server.mapV2Routes(server.app.Group("/v2"))
+	server.mapDocs()
+}
+
+// Builds the global limiter and keeps a stricter one for the Cloudinary uploads
+func (server *Server) rateLimiters() fiber.Handler {
+	var store middlewares.RateLimitStore = middlewares.NewMemoryStore()
+
+	if os.Getenv("RATE_LIMIT_STORE") == "db" {
+		dbStore := middlewares.NewDBStore(server.db)
+		store = dbStore
+
+		go func() {
+			for range time.Tick(time.Hour) {
+				dbStore.Purge()
+			}
+		}()
+	}
+
+	server.uploadLimiter = middlewares.RateLimiter(middlewares.RateLimiterConfig{
+		Scope:   "uploads",
+		Free:    middlewares.ParseRateLimit(os.Getenv("RATE_LIMIT_UPLOADS_FREE"), middlewares.RateLimit{Requests: 5, Per: time.Minute}),
+		Premium: middlewares.ParseRateLimit(os.Getenv("RATE_LIMIT_UPLOADS_PREMIUM"), middlewares.RateLimit{Requests: 20, Per: time.Minute}),
+		Store:   store,
+	}, server.db)
+
+	return middlewares.RateLimiter(middlewares.RateLimiterConfig{
+		Scope:   "api",
+		Free:    middlewares.ParseRateLimit(os.Getenv("RATE_LIMIT_FREE"), middlewares.RateLimit{Requests: 60, Per: time.Minute}),
+		Premium: middlewares.ParseRateLimit(os.Getenv("RATE_LIMIT_PREMIUM"), middlewares.RateLimit{Requests: 300, Per: time.Minute}),
+		Store:   store,
+	}, server.db)
+}
+
+func (server *Server) mapV1Routes(router fiber.Router, handlers ...fiber.Handler) {
+	server.mapUserRoutes(router, handlers...)
+	server.mapTagsRoutes(router, handlers...)
+	server.mapToDosRoutes(router, handlers...)
+	server.mapPinnedImages(router, handlers...)
 }
 
-func (server *Server) mapUserRoutes() {
+func (server *Server) mapUserRoutes(router fiber.Router, handlers ...fiber.Handler) {
 	userController := controllers.NewUserController(server.db)
 
-	userGroup := server.app.Group("/user")
+	userGroup := router.Group("/user", handlers...)
 
 	userGroup.Post("/login", userController.Login)
 	userGroup.Post("/register", userController.Register)
@@ -29,14 +76,14 @@ func (server *Server) mapUserRoutes() {
 	userGroup.Patch("/update/:id", userController.Edit)
 	userGroup.Patch("/premium/:id", userController.ConvertToPremium)
 	userGroup.Delete("/delete/:id", userController.Delete)
-	userGroup.Put("/profile/:id", userController.UpdateProfileImage)
+	userGroup.Put("/profile/:id", server.uploadLimiter, userController.UpdateProfileImage)
 	userGroup.Delete("/profile/:id", userController.RemoveProfileImage)
 }
 
-func (server *Server) mapTagsRoutes() {
+func (server *Server) mapTagsRoutes(router fiber.Router, handlers ...fiber.Handler) {
 	tagsController := controllers.NewTagsController(server.db)
 
-	tagsGroup := server.app.Group("/tags")
+	tagsGroup := router.Group("/tags", handlers...)
 
 	tagsGroup.Post("/create", tagsController.CreateTag)
 	tagsGroup.Get("/:id", tagsController.GetTagById)
@@ -46,10 +93,10 @@ func (server *Server) mapTagsRoutes() {
 	tagsGroup.Delete("/delete/user/:id", tagsController.DeleteAllTagsFromUserId)
 }
 
-func (server *Server) mapToDosRoutes() {
+func (server *Server) mapToDosRoutes(router fiber.Router, handlers ...fiber.Handler) {
 	toDosController := controllers.NewToDoController(server.db)
 
-	toDosGroup := server.app.Group("/todos")
+	toDosGroup := router.Group("/todos", handlers...)
 
 	toDosGroup.Post("/create", toDosController.CreateToDo)
 	toDosGroup.Get("/user/:id", toDosController.GetAllToDosFromUserId)
@@ -58,12 +105,190 @@ func (server *Server) mapToDosRoutes() {
 	toDosGroup.Delete("/delete/user/:id", toDosController.DeleteAllToDosFromUserId)
 }
 
-func (server *Server) mapPinnedImages() {
+func (server *Server) mapPinnedImages(router fiber.Router, handlers ...fiber.Handler) {
 	imagesController := controllers.NewImageControler(server.db)
 
-	imagesGroup := server.app.Group("/pinnedimages")
+	imagesGroup := router.Group("/pinnedimages", handlers...)
 
-	imagesGroup.Post("/user/:id", imagesController.PostImage)
+	imagesGroup.Post("/user/:id", server.uploadLimiter, imagesController.PostImage)
 	imagesGroup.Get("/user/:id", imagesController.GetAllImages)
 	imagesGroup.Delete("/:id", imagesController.DeleteImage)
 }
+
+func (server *Server) mapV2Routes(router fiber.Router) {
+	userController := controllers.NewUserController(server.db)
+	tagsController := controllers.NewTagsController(server.db)
+	toDosController := controllers.NewToDoController(server.db)
+	imagesController := controllers.NewImageControler(server.db)
+	itemsController := controllers.NewToDoItemsController(server.db)
+	remindersController := controllers.NewRemindersController(server.db, server.notifiers)
+	trashController := controllers.NewTrashController(server.db)
+	listsController := controllers.NewListsController(server.db)
+	workspacesController := controllers.NewWorkspacesController(server.db)
+	commentsController := controllers.NewCommentsController(server.db)
+	boardController := controllers.NewBoardController(server.db)
+	timeController := controllers.NewTimeController(server.db)
+	pomodoroController := controllers.NewPomodoroController(server.db)
+	templatesController := controllers.NewTemplatesController(server.db)
+	smartListsController := controllers.NewSmartListsController(server.db)
+
+	router.Post("/sessions", userController.Login)
+	router.Post("/sessions/validate", userController.ValidateToken)
+
+	usersGroup := router.Group("/users")
+
+	usersGroup.Post("/", userController.Register)
+	usersGroup.Patch("/:id", userController.Edit)
+	usersGroup.Delete("/:id", userController.Delete)
+	usersGroup.Post("/:id/premium", userController.ConvertToPremium)
+	usersGroup.Put("/:id/profile-image", server.uploadLimiter, userController.UpdateProfileImage)
+	usersGroup.Delete("/:id/profile-image", userController.RemoveProfileImage)
+	usersGroup.Delete("/:id/tags", tagsController.DeleteAllTagsFromUserId)
+	usersGroup.Delete("/:id/todos", toDosController.DeleteAllToDosFromUserId)
+	usersGroup.Get("/:id/images", imagesController.GetAllImages)
+	usersGroup.Post("/:id/images", server.uploadLimiter, imagesController.PostImage)
+	usersGroup.Get("/:id/activity", commentsController.GetUserActivity)
+	usersGroup.Get("/:id/timer", timeController.GetRunningTimer)
+	usersGroup.Get("/:id/time", timeController.GetTimeTotals)
+	usersGroup.Get("/:id/time/report", timeController.GetTimeReport)
+	usersGroup.Get("/:id/invites", listsController.GetUserInvites)
+	usersGroup.Post("/:id/invites/:invite", listsController.CreateAnswerInviteFuncs(true))
+	usersGroup.Delete("/:id/invites/:invite", listsController.CreateAnswerInviteFuncs(false))
+
+	tagsGroup := router.Group("/tags")
+
+	tagsGroup.Get("/", tagsController.GetAllTagsFromUserId)
+	tagsGroup.Post("/", tagsController.CreateTag)
+	tagsGroup.Get("/trash", trashController.GetTagsTrash)
+	tagsGroup.Get("/:id", tagsController.GetTagById)
+	tagsGroup.Patch("/:id", tagsController.CreatePatchOrDeleteFuncs(false))
+	tagsGroup.Delete("/:id", tagsController.CreatePatchOrDeleteFuncs(true))
+	tagsGroup.Post("/:id/restore", trashController.RestoreTag)
+
+	toDosGroup := router.Group("/todos")
+
+	toDosGroup.Get("/", toDosController.GetAllToDosFromUserId)
+	toDosGroup.Post("/", toDosController.CreateToDo)
+	toDosGroup.Post("/quick", toDosController.QuickAdd)
+	toDosGroup.Get("/trash", trashController.GetToDosTrash)
+	toDosGroup.Get("/archive", toDosController.GetArchivedToDos)
+	toDosGroup.Patch("/:id", toDosController.CreatePatchOrDeleteFuncs(false))
+	toDosGroup.Delete("/:id", toDosController.CreatePatchOrDeleteFuncs(true))
+	toDosGroup.Post("/:id/restore", trashController.RestoreToDo)
+	toDosGroup.Post("/:id/complete", toDosController.CreateCompleteFuncs(true))
+	toDosGroup.Delete("/:id/complete", toDosController.CreateCompleteFuncs(false))
+	toDosGroup.Put("/:id/position", toDosController.MoveToDo)
+	toDosGroup.Post("/:id/archive", toDosController.CreateArchiveFuncs(true))
+	toDosGroup.Delete("/:id/archive", toDosController.CreateArchiveFuncs(false))
+	toDosGroup.Get("/:id/history", toDosController.GetHistory)
+	toDosGroup.Post("/:id/undo", toDosController.Undo)
+	toDosGroup.Put("/:id/list", toDosController.SetList)
+	toDosGroup.Put("/:id/column", boardController.MoveToDo)
+	toDosGroup.Get("/:id/dependencies", toDosController.GetDependencies)
+	toDosGroup.Put("/:id/blockers/:blocker", toDosController.CreateBlockerFuncs(true))
+	toDosGroup.Delete("/:id/blockers/:blocker", toDosController.CreateBlockerFuncs(false))
+
+	toDosGroup.Get("/:id/items", itemsController.GetAllItems)
+	toDosGroup.Post("/:id/items", itemsController.CreateItem)
+	toDosGroup.Put("/:id/items/order", itemsController.ReorderItems)
+	toDosGroup.Patch("/:id/items/:item", itemsController.CreateUpdateOrDeleteFuncs(false))
+	toDosGroup.Delete("/:id/items/:item", itemsController.CreateUpdateOrDeleteFuncs(true))
+
+	toDosGroup.Get("/:id/comments", commentsController.GetComments)
+	toDosGroup.Post("/:id/comments", commentsController.CreateComment)
+	toDosGroup.Patch("/:id/comments/:comment", commentsController.CreateUpdateOrDeleteFuncs(false))
+	toDosGroup.Delete("/:id/comments/:comment", commentsController.CreateUpdateOrDeleteFuncs(true))
+	toDosGroup.Get("/:id/activity", commentsController.GetToDoActivity)
+
+	toDosGroup.Post("/:id/timer", timeController.CreateTimerFuncs(true))
+	toDosGroup.Delete("/:id/timer", timeController.CreateTimerFuncs(false))
+	toDosGroup.Get("/:id/time", timeController.GetTimeEntries)
+	toDosGroup.Post("/:id/time", timeController.CreateTimeEntry)
+	toDosGroup.Delete("/:id/time/:entry", timeController.DeleteTimeEntry)
+
+	toDosGroup.Get("/:id/reminders", remindersController.GetAllReminders)
+	toDosGroup.Post("/:id/reminders", remindersController.CreateReminder)
+	toDosGroup.Delete("/:id/reminders/:reminder", remindersController.DeleteReminder)
+
+	listsGroup := router.Group("/lists")
+
+	listsGroup.Get("/", listsController.GetAllLists)
+	listsGroup.Post("/", listsController.CreateList)
+	listsGroup.Patch("/:id", listsController.CreateUpdateOrDeleteFuncs(false))
+	listsGroup.Delete("/:id", listsController.CreateUpdateOrDeleteFuncs(true))
+
+	listsGroup.Get("/:id/members", listsController.GetMembers)
+	listsGroup.Put("/:id/members/:user", listsController.SetMemberRole)
+	listsGroup.Delete("/:id/members/:user", listsController.RemoveMember)
+
+	listsGroup.Get("/:id/invites", listsController.GetInvites)
+	listsGroup.Post("/:id/invites", listsController.CreateInvite)
+	listsGroup.Delete("/:id/invites/:invite", listsController.CancelInvite)
+
+	listsGroup.Get("/:id/todos", listsController.GetListToDos)
+	listsGroup.Post("/:id/todos", listsController.CreateListToDo)
+	listsGroup.Patch("/:id/todos/:todo", listsController.CreateUpdateOrDeleteToDoFuncs(false))
+	listsGroup.Delete("/:id/todos/:todo", listsController.CreateUpdateOrDeleteToDoFuncs(true))
+	listsGroup.Post("/:id/todos/:todo/complete", listsController.CreateCompleteToDoFuncs(true))
+	listsGroup.Delete("/:id/todos/:todo/complete", listsController.CreateCompleteToDoFuncs(false))
+	listsGroup.Put("/:id/todos/:todo/assignee", listsController.AssignToDo)
+
+	workspacesGroup := router.Group("/workspaces")
+
+	workspacesGroup.Get("/", workspacesController.GetAllWorkspaces)
+	workspacesGroup.Post("/", workspacesController.CreateWorkspace)
+	workspacesGroup.Get("/:id", workspacesController.GetWorkspace)
+	workspacesGroup.Patch("/:id", workspacesController.CreateUpdateOrDeleteFuncs(false))
+	workspacesGroup.Delete("/:id", workspacesController.CreateUpdateOrDeleteFuncs(true))
+	workspacesGroup.Post("/:id/premium", workspacesController.UpgradeToPremium)
+
+	workspacesGroup.Get("/:id/members", workspacesController.GetMembers)
+	workspacesGroup.Post("/:id/members", workspacesController.AddMember)
+	workspacesGroup.Put("/:id/members/:user", workspacesController.SetMemberRole)
+	workspacesGroup.Delete("/:id/members/:user", workspacesController.RemoveMember)
+
+	columnsGroup := router.Group("/columns")
+
+	columnsGroup.Get("/", boardController.GetAllColumns)
+	columnsGroup.Post("/", boardController.CreateColumn)
+	columnsGroup.Patch("/:id", boardController.CreateUpdateOrDeleteFuncs(false))
+	columnsGroup.Delete("/:id", boardController.CreateUpdateOrDeleteFuncs(true))
+	columnsGroup.Put("/:id/position", boardController.MoveColumn)
+
+	router.Get("/board", boardController.GetBoard)
+
+	templatesGroup := router.Group("/templates")
+
+	templatesGroup.Get("/", templatesController.GetAllTemplates)
+	templatesGroup.Post("/", templatesController.CreateTemplate)
+	templatesGroup.Post("/from-todos", templatesController.CreateTemplateFromToDos)
+	templatesGroup.Get("/:id", templatesController.GetTemplate)
+	templatesGroup.Patch("/:id", templatesController.CreateUpdateOrDeleteFuncs(false))
+	templatesGroup.Delete("/:id", templatesController.CreateUpdateOrDeleteFuncs(true))
+	templatesGroup.Post("/:id/instantiate", templatesController.Instantiate)
+
+	smartListsGroup := router.Group("/smart-lists")
+
+	smartListsGroup.Get("/", smartListsController.GetAllSmartLists)
+	smartListsGroup.Post("/", smartListsController.CreateSmartList)
+	smartListsGroup.Get("/:id", smartListsController.GetSmartList)
+	smartListsGroup.Patch("/:id", smartListsController.CreateUpdateOrDeleteFuncs(false))
+	smartListsGroup.Delete("/:id", smartListsController.CreateUpdateOrDeleteFuncs(true))
+	smartListsGroup.Get("/:id/todos", smartListsController.GetSmartListToDos)
+
+	pomodorosGroup := router.Group("/pomodoros")
+
+	pomodorosGroup.Post("/", pomodoroController.StartPomodoro)
+	pomodorosGroup.Get("/current", pomodoroController.GetCurrentPomodoro)
+	pomodorosGroup.Get("/stats", pomodoroController.GetPomodoroStats)
+	pomodorosGroup.Post("/:id/pause", pomodoroController.CreateStateFuncs(models.POMODORO_PAUSED))
+	pomodorosGroup.Post("/:id/resume", pomodoroController.CreateStateFuncs(models.POMODORO_RUNNING))
+	pomodorosGroup.Post("/:id/finish", pomodoroController.CreateStateFuncs(models.POMODORO_FINISHED))
+
+	router.Delete("/images/:id", imagesController.DeleteImage)
+}
+
+func (server *Server) mapDocs() {
+	server.app.Get("/openapi.json", docs.ServeSpec)
+	server.app.Get("/docs", docs.ServeDocs)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/docs"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Every registered route must be documented in openapi.json
+func TestRoutesAreDocumented(t *testing.T) {
+	db, err := sql.Open("mysql", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	server := Server{db: db, app: fiber.New()}
+	server.handleControllers()
+
+	missing, err := docs.MissingRoutes(server.app)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(missing) != 0 {
+		t.Errorf("routes missing from openapi.json: %v", missing)
+	}
+}
@@ -0,0 +1,243 @@
+package locales
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	EN = "en"
+	ES = "es"
+
+	DEFAULT_LOCALE = EN
+)
+
+var Supported = []string{EN, ES}
+
+// Messages by error code and locale
+var catalog = map[string]map[string]string{
+	"invalid_credentials":    {EN: "Invalid credentials", ES: "Credenciales inválidas"},
+	"invalid_user":           {EN: "Invalid user", ES: "Usuario inválido"},
+	"invalid_user_id":        {EN: "Invalid user id", ES: "Id de usuario inválido"},
+	"invalid_id":             {EN: "Invalid id", ES: "Id inválido"},
+	"invalid_fields":         {EN: "Invalid fields", ES: "Campos inválidos"},
+	"invalid_definition":     {EN: "Invalid definition", ES: "Definición inválida"},
+	"invalid_tag_id":         {EN: "Invalid tag id", ES: "Id de etiqueta inválido"},
+	"invalid_tag":            {EN: "Invalid tag definition", ES: "Definición de etiqueta inválida"},
+	"invalid_todo_id":        {EN: "Invalid to do id", ES: "Id de tarea inválido"},
+	"invalid_todo":           {EN: "Invalid to do definition", ES: "Definición de tarea inválida"},
+	"wrong_mail_or_password": {EN: "Incorrect mail or password", ES: "Correo o contraseña incorrectos"},
+	"mail_in_use":            {EN: "Mail already in use", ES: "El correo ya está en uso"},
+	"new_mail_in_use":        {EN: "New mail already in use", ES: "El nuevo correo ya está en uso"},
+	"user_validation_failed": {EN: "Error validating user data", ES: "Error al validar los datos del usuario"},
+	"tag_not_found":          {EN: "Tag not found", ES: "Etiqueta no encontrada"},
+	"tags_limit":             {EN: "Tags limit exceeded", ES: "Límite de etiquetas excedido"},
+	"tag_update_failed":      {EN: "Couldn't update tag", ES: "No se pudo actualizar la etiqueta"},
+	"tag_delete_failed":      {EN: "Couldn't delete tag", ES: "No se pudo eliminar la etiqueta"},
+	"todo_not_found":         {EN: "To do not found", ES: "Tarea no encontrada"},
+	"todos_limit":            {EN: "To dos limit exceeded", ES: "Límite de tareas excedido"},
+	"todo_update_failed":     {EN: "Couldn't update to do", ES: "No se pudo actualizar la tarea"},
+	"todo_delete_failed":     {EN: "Couldn't delete to do", ES: "No se pudo eliminar la tarea"},
+	"delete_failed":          {EN: "Error deleting", ES: "Error al eliminar"},
+	"get_failed":             {EN: "Couldn't get", ES: "No se pudo obtener"},
+	"file_missing":           {EN: "File doesn't exist", ES: "El archivo no existe"},
+	"image_upload_failed":    {EN: "Cannot upload image", ES: "No se pudo subir la imagen"},
+	"image_not_found":        {EN: "Image not found", ES: "Imagen no encontrada"},
+	"image_delete_failed":    {EN: "Cannot delete image", ES: "No se pudo eliminar la imagen"},
+	"images_get_failed":      {EN: "Couldn't get images", ES: "No se pudieron obtener las imágenes"},
+	"internal_error":         {EN: "Internal server error", ES: "Error interno del servidor"},
+	"unexpected_error":       {EN: "Unexpected error", ES: "Error inesperado"},
+	"invalid_filter":         {EN: "Invalid filter", ES: "Filtro inválido"},
+	"todo_archived":          {EN: "The to do is already archived", ES: "La tarea ya está archivada"},
+	"todo_not_archived":      {EN: "The to do is not archived", ES: "La tarea no está archivada"},
+	"revision_not_found":     {EN: "Revision not found", ES: "Revisión no encontrada"},
+	"restore_failed":         {EN: "Couldn't restore", ES: "No se pudo restaurar"},
+	"invalid_cursor":         {EN: "Invalid cursor, start again from the first page", ES: "Cursor inválido, vuelve a empezar desde la primera página"},
+	"invalid_item":           {EN: "Invalid item definition", ES: "Definición de elemento inválida"},
+	"item_not_found":         {EN: "Item not found", ES: "Elemento no encontrado"},
+	"items_limit":            {EN: "Items limit exceeded", ES: "Límite de elementos excedido"},
+	"item_update_failed":     {EN: "Couldn't update item", ES: "No se pudo actualizar el elemento"},
+	"item_delete_failed":     {EN: "Couldn't delete item", ES: "No se pudo eliminar el elemento"},
+	"invalid_order":          {EN: "Invalid order", ES: "Orden inválido"},
+	"invalid_recurrence":     {EN: "Invalid recurrence", ES: "Recurrencia inválida"},
+	"invalid_reminder":       {EN: "Invalid reminder definition", ES: "Definición de recordatorio inválida"},
+	"reminder_not_found":     {EN: "Reminder not found", ES: "Recordatorio no encontrado"},
+	"reminders_limit":        {EN: "Reminders limit exceeded", ES: "Límite de recordatorios excedido"},
+	"reminder_delete_failed": {EN: "Couldn't delete reminder", ES: "No se pudo eliminar el recordatorio"},
+	"invalid_list_id":        {EN: "Invalid list id", ES: "Id de lista inválido"},
+	"invalid_list":           {EN: "Invalid list definition", ES: "Definición de lista inválida"},
+	"list_not_found":         {EN: "List not found", ES: "Lista no encontrada"},
+	"lists_limit":            {EN: "Lists limit exceeded", ES: "Límite de listas excedido"},
+	"list_update_failed":     {EN: "Couldn't update list", ES: "No se pudo actualizar la lista"},
+	"list_delete_failed":     {EN: "Couldn't delete list", ES: "No se pudo eliminar la lista"},
+	"not_allowed":            {EN: "Not allowed", ES: "No permitido"},
+	"invalid_role":           {EN: "Invalid role", ES: "Rol inválido"},
+	"member_not_found":       {EN: "Member not found", ES: "Miembro no encontrado"},
+	"list_needs_owner":       {EN: "The list needs an owner", ES: "La lista necesita un propietario"},
+	"members_limit":          {EN: "Members limit exceeded", ES: "Límite de miembros excedido"},
+	"already_member":         {EN: "Already a member", ES: "Ya es miembro"},
+	"invalid_invite":         {EN: "Invalid invite definition", ES: "Definición de invitación inválida"},
+	"invite_sent":            {EN: "Invite already sent", ES: "La invitación ya fue enviada"},
+	"invite_not_found":       {EN: "Invite not found", ES: "Invitación no encontrada"},
+	"todo_not_in_list":       {EN: "To do not in a list", ES: "La tarea no está en una lista"},
+	"invalid_workspace_id":   {EN: "Invalid workspace id", ES: "Id de espacio de trabajo inválido"},
+	"invalid_workspace":      {EN: "Invalid workspace definition", ES: "Definición de espacio de trabajo inválida"},
+	"invalid_member":         {EN: "Invalid member definition", ES: "Definición de miembro inválida"},
+	"workspace_not_found":    {EN: "Workspace not found", ES: "Espacio de trabajo no encontrado"},
+	"workspaces_limit":       {EN: "Workspaces limit exceeded", ES: "Límite de espacios de trabajo excedido"},
+	"workspace_not_empty":    {EN: "The workspace still has to dos, tags or images", ES: "El espacio de trabajo aún tiene tareas, etiquetas o imágenes"},
+	"workspace_not_updated":  {EN: "Couldn't update workspace", ES: "No se pudo actualizar el espacio de trabajo"},
+	"workspace_not_deleted":  {EN: "Couldn't delete workspace", ES: "No se pudo eliminar el espacio de trabajo"},
+	"workspace_needs_owner":  {EN: "The workspace needs an owner", ES: "El espacio de trabajo necesita un propietario"},
+	"todo_in_workspace":      {EN: "To dos of a workspace can't be in a list", ES: "Las tareas de un espacio de trabajo no pueden estar en una lista"},
+	"images_limit":           {EN: "Images limit exceeded", ES: "Límite de imágenes excedido"},
+	"user_not_found":         {EN: "User not found", ES: "Usuario no encontrado"},
+	"invalid_comment_id":     {EN: "Invalid comment id", ES: "Id de comentario inválido"},
+	"invalid_comment":        {EN: "Invalid comment definition", ES: "Definición de comentario inválida"},
+	"comment_not_found":      {EN: "Comment not found", ES: "Comentario no encontrado"},
+	"comment_update_failed":  {EN: "Couldn't update comment", ES: "No se pudo actualizar el comentario"},
+	"comment_delete_failed":  {EN: "Couldn't delete comment", ES: "No se pudo eliminar el comentario"},
+	"invalid_column_id":      {EN: "Invalid column id", ES: "Id de columna inválido"},
+	"invalid_column":         {EN: "Invalid column definition", ES: "Definición de columna inválida"},
+	"column_not_found":       {EN: "Column not found", ES: "Columna no encontrada"},
+	"columns_limit":          {EN: "Columns limit exceeded", ES: "Límite de columnas excedido"},
+	"column_update_failed":   {EN: "Couldn't update column", ES: "No se pudo actualizar la columna"},
+	"column_delete_failed":   {EN: "Couldn't delete column", ES: "No se pudo eliminar la columna"},
+	"dependency_cycle":       {EN: "The to dos would wait for each other", ES: "Las tareas se esperarían entre sí"},
+	"dependency_exists":      {EN: "The to do already waits for it", ES: "La tarea ya la espera"},
+	"dependency_not_found":   {EN: "Dependency not found", ES: "Dependencia no encontrada"},
+	"blockers_limit":         {EN: "Blockers limit exceeded", ES: "Límite de bloqueos excedido"},
+	"timer_running":          {EN: "Another timer is already running", ES: "Ya hay otro temporizador en marcha"},
+	"timer_not_running":      {EN: "The timer is not running", ES: "El temporizador no está en marcha"},
+	"invalid_time_entry":     {EN: "Invalid time entry definition", ES: "Definición de registro de tiempo inválida"},
+	"time_entry_not_found":   {EN: "Time entry not found", ES: "Registro de tiempo no encontrado"},
+	"invalid_pomodoro_id":    {EN: "Invalid pomodoro id", ES: "Id de pomodoro inválido"},
+	"invalid_pomodoro":       {EN: "Invalid pomodoro definition", ES: "Definición de pomodoro inválida"},
+	"pomodoro_running":       {EN: "Another pomodoro is already active", ES: "Ya hay otro pomodoro activo"},
+	"pomodoro_not_found":     {EN: "Pomodoro not found", ES: "Pomodoro no encontrado"},
+	"invalid_template_id":    {EN: "Invalid template id", ES: "Id de plantilla inválido"},
+	"invalid_template":       {EN: "Invalid template definition", ES: "Definición de plantilla inválida"},
+	"template_not_found":     {EN: "Template not found", ES: "Plantilla no encontrada"},
+	"templates_limit":        {EN: "Templates limit exceeded", ES: "Límite de plantillas excedido"},
+	"template_update_failed": {EN: "Couldn't update template", ES: "No se pudo actualizar la plantilla"},
+	"template_delete_failed": {EN: "Couldn't delete template", ES: "No se pudo eliminar la plantilla"},
+	"invalid_smart_list_id":  {EN: "Invalid smart list id", ES: "Id de lista inteligente inválido"},
+	"invalid_smart_list":     {EN: "Invalid smart list definition", ES: "Definición de lista inteligente inválida"},
+	"smart_list_not_found":   {EN: "Smart list not found", ES: "Lista inteligente no encontrada"},
+	"smart_lists_limit":      {EN: "Smart lists limit exceeded", ES: "Límite de listas inteligentes excedido"},
+	"smart_list_not_updated": {EN: "Couldn't update smart list", ES: "No se pudo actualizar la lista inteligente"},
+	"smart_list_not_deleted": {EN: "Couldn't delete smart list", ES: "No se pudo eliminar la lista inteligente"},
+	"invalid_quick_add":      {EN: "Invalid quick add definition", ES: "Definición de alta rápida inválida"},
+	"invalid_timezone":       {EN: "Invalid timezone", ES: "Zona horaria inválida"},
+	"mention_not_member":     {EN: "Only the users that can see the to do can be mentioned", ES: "Solo se puede mencionar a quienes pueden ver la tarea"},
+	"rate_limited":           {EN: "Too many requests, try again later", ES: "Demasiadas solicitudes, intenta más tarde"},
+	"invalid_state":          {EN: "Invalid state", ES: "Estado inválido"},
+}
+
+// An error the handlers answer with, translated by its catalog code
+type Error struct {
+	Code string
+}
+
+// The English message of the code
+func (e *Error) Error() string {
+	msg, _ := Message(DEFAULT_LOCALE, e.Code)
+	return msg
+}
+
+func New(code string) error {
+	return &Error{Code: code}
+}
+
+// Returns the catalog code of err, or an empty string when it has none
+func CodeOf(err error) string {
+	var coded *Error
+
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	return ""
+}
+
+// Returns the message of the code in the given locale, falling back to the default locale
+func Message(locale, code string) (string, bool) {
+	msgs, ok := catalog[code]
+
+	if !ok {
+		return "", false
+	}
+
+	if msg, ok := msgs[locale]; ok {
+		return msg, true
+	}
+
+	return msgs[DEFAULT_LOCALE], true
+}
+
+func IsSupported(locale string) bool {
+	for _, l := range Supported {
+		if l == locale {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Picks the locale to answer with, the user's stored preference wins over the Accept-Language header
+func Negotiate(acceptLanguage, preferred string) string {
+	if preferred = baseLanguage(preferred); IsSupported(preferred) {
+		return preferred
+	}
+
+	type option struct {
+		locale string
+		q      float64
+	}
+
+	options := make([]option, 0)
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := baseLanguage(fields[0])
+		q := 1.0
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+
+			if value, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if q > 0 && IsSupported(locale) {
+			options = append(options, option{locale, q})
+		}
+	}
+
+	if len(options) == 0 {
+		return DEFAULT_LOCALE
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].q > options[j].q
+	})
+
+	return options[0].locale
+}
+
+// "es-MX" -> "es"
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		tag = tag[:i]
+	}
+
+	return tag
+}
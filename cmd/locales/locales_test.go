@@ -0,0 +1,37 @@
+package locales
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		preferred      string
+		want           string
+	}{
+		{"nothing falls back to the default", "", "", EN},
+		{"stored preference wins over the header", "en-US,en;q=0.9", "es", ES},
+		{"stored preference with a region", "", "es-MX", ES},
+		{"unsupported preference uses the header", "es", "fr", ES},
+		{"header with a region", "es-MX", "", ES},
+		{"header with an underscore", "es_AR", "", ES},
+		{"header is case insensitive", "ES-mx", "", ES},
+		{"highest quality wins", "en;q=0.5, es;q=0.8", "", ES},
+		{"order breaks quality ties", "es;q=0.7, en;q=0.7", "", ES},
+		{"missing quality is 1", "en;q=0.9, es", "", ES},
+		{"unsupported languages are skipped", "fr-FR, de;q=0.9, es;q=0.1", "", ES},
+		{"zero quality is refused", "es;q=0, en;q=0.1", "", EN},
+		{"only unsupported languages", "fr, de", "", EN},
+		{"wildcard", "*", "", EN},
+		{"malformed quality keeps 1", "es;q=abc, en;q=0.9", "", ES},
+		{"extra spaces", "  fr ,  es ; q=0.4 ", "", ES},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Negotiate(test.acceptLanguage, test.preferred); got != test.want {
+				t.Errorf("Negotiate(%q, %q) = %q, want %q", test.acceptLanguage, test.preferred, got, test.want)
+			}
+		})
+	}
+}
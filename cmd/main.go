@@ -7,6 +7,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/reminders"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/trash"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
@@ -16,16 +19,24 @@ type Server struct {
 	db   *sql.DB
 	port string
 	app  *fiber.App
+
+	uploadLimiter fiber.Handler
+	notifiers     map[string]reminders.Notifier
 }
 
 func main() {
 	godotenv.Load()
+	models.LoadFieldLimits()
 
 	server := Server{}
 	server.openDB()
 
 	server.port = ":" + os.Getenv("PORT")
 
+	server.notifiers = reminders.NotifiersFromEnv()
+	reminders.NewScheduler(server.db, server.notifiers, durationFromEnv("REMINDER_POLL_INTERVAL", time.Minute)).Start()
+	trash.NewPurger(server.db, durationFromEnv("TRASH_RETENTION", 30*24*time.Hour), durationFromEnv("TRASH_PURGE_INTERVAL", time.Hour)).Start()
+
 	server.app = fiber.New()
 	server.handleControllers()
 
@@ -62,3 +73,14 @@ func (server *Server) openDB() {
 
 	server.db = db
 }
+
+// Reads a duration like 90s or 720h, fallback is used when it is missing or invalid
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+
+	return duration
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Flags the unversioned routes as deprecated, pointing to their /v1 successor.
+// sunset is a YYYY-MM-DD date, the Sunset header is omitted when it is empty or invalid
+func Deprecated(sunset string) fiber.Handler {
+	sunsetHeader := ""
+
+	if date, err := time.Parse(time.DateOnly, sunset); err == nil {
+		sunsetHeader = date.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, "</v1"+c.Path()+`>; rel="successor-version"`)
+
+		if sunsetHeader != "" {
+			c.Set("Sunset", sunsetHeader)
+		}
+
+		return c.Next()
+	}
+}
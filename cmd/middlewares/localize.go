@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Answers the error_code of the responses with its message in the locale negotiated for
+// the request. Responses without a code keep their error_msg as is
+func Localize(db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		body := c.Response().Body()
+
+		if !bytes.Contains(body, []byte(`"error_code"`)) {
+			return nil
+		}
+
+		response := models.Response{}
+
+		if err := json.Unmarshal(body, &response); err != nil || response.ErrorCode == "" {
+			return nil
+		}
+
+		locale := locales.Negotiate(c.Get(fiber.HeaderAcceptLanguage), preferredLocale(c, db))
+
+		if msg, ok := locales.Message(locale, response.ErrorCode); ok {
+			response.ErrorMsg = msg
+		}
+
+		c.Set(fiber.HeaderContentLanguage, locale)
+		return c.JSON(response)
+	}
+}
+
+// Routes whose :id param is the id of a user, any other :id belongs to another resource
+var userIdRoutes = []string{
+	"/v1/user/",
+	"/v1/tags/user/:id",
+	"/v1/tags/delete/user/:id",
+	"/v1/todos/user/:id",
+	"/v1/todos/delete/user/:id",
+	"/v1/pinnedimages/user/:id",
+	"/v2/users/:id",
+}
+
+// The handlers that load a user leave its locale in the context, otherwise
+// the user named by the request is looked up
+func preferredLocale(c *fiber.Ctx, db *sql.DB) string {
+	if locale, ok := c.Locals("locale").(string); ok && locale != "" {
+		return locale
+	}
+
+	id, ok := localeUserId(c)
+
+	if !ok {
+		return ""
+	}
+
+	locale, _ := models.GetUserLocale(id, db)
+	return locale
+}
+
+// Reads the user from an explicit param only: the created_by of the query or the body,
+// the :id of the user routes or a :user param
+func localeUserId(c *fiber.Ctx) (int, bool) {
+	if id := c.QueryInt("created_by", -1); id > 0 {
+		return id, true
+	}
+
+	holder := struct {
+		CreatedBy int `json:"created_by"`
+	}{}
+
+	if json.Unmarshal(c.Body(), &holder) == nil && holder.CreatedBy > 0 {
+		return holder.CreatedBy, true
+	}
+
+	path := c.Route().Path
+
+	for _, route := range userIdRoutes {
+		if !strings.HasPrefix(path, route) {
+			continue
+		}
+
+		if id, err := c.ParamsInt("id"); err == nil && id > 0 {
+			return id, true
+		}
+	}
+
+	if id, err := c.ParamsInt("user"); err == nil && id > 0 {
+		return id, true
+	}
+
+	return 0, false
+}
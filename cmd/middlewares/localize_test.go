@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLocaleUserId(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		id, ok := localeUserId(c)
+
+		if !ok {
+			return c.SendString("none")
+		}
+
+		return c.SendString(strconv.Itoa(id))
+	})
+
+	ok := func(c *fiber.Ctx) error { return nil }
+
+	app.Patch("/v1/user/update/:id", ok)
+	app.Get("/v1/todos/user/:id", ok)
+	app.Put("/v1/todos/update/:id", ok)
+	app.Get("/v2/users/:id/invites", ok)
+	app.Delete("/v2/todos/:id", ok)
+	app.Patch("/v2/todos/:id", ok)
+	app.Put("/v2/lists/:id/members/:user", ok)
+	app.Delete("/v2/workspaces/:id/members/:user", ok)
+	app.Get("/v2/columns", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   string
+	}{
+		{"v1 user route", "PATCH", "/v1/user/update/7", "", "7"},
+		{"v1 user collection route", "GET", "/v1/todos/user/7", "", "7"},
+		{"v1 to do route", "PUT", "/v1/todos/update/9", "", "none"},
+		{"v1 to do route with its creator", "PUT", "/v1/todos/update/9", `{"created_by": 7}`, "7"},
+		{"v2 user route", "GET", "/v2/users/7/invites", "", "7"},
+		{"v2 to do route", "DELETE", "/v2/todos/9", "", "none"},
+		{"v2 to do route with the caller", "DELETE", "/v2/todos/9?created_by=7", "", "7"},
+		{"v2 to do route with the caller in the body", "PATCH", "/v2/todos/9", `{"created_by": 7}`, "7"},
+		{"list member route", "PUT", "/v2/lists/9/members/7", "", "7"},
+		{"workspace member route", "DELETE", "/v2/workspaces/9/members/7", "", "7"},
+		{"caller wins over the member", "DELETE", "/v2/workspaces/9/members/5?created_by=7", "", "7"},
+		{"collection route with the caller", "GET", "/v2/columns?created_by=7", "", "7"},
+		{"collection route without the caller", "GET", "/v2/columns", "", "none"},
+		{"body that is not json", "PATCH", "/v2/todos/9", "created_by=7", "none"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			res, err := app.Test(req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, _ := io.ReadAll(res.Body)
+
+			if string(body) != test.want {
+				t.Errorf("%s %s read %q, want %q", test.method, test.target, body, test.want)
+			}
+		})
+	}
+}
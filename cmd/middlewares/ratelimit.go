@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RateLimiterConfig struct {
+	// Keeps the buckets of different limiters apart
+	Scope   string
+	Free    RateLimit
+	Premium RateLimit
+	Store   RateLimitStore
+}
+
+// How long the plan of a user is trusted before it is looked up again
+const PREMIUM_CACHE_TTL = time.Minute
+
+type rateLimitClient struct {
+	key     string
+	premium bool
+}
+
+// Limits the requests with a token bucket per authenticated user, or per IP for
+// anonymous requests. The user is taken from the "Authorization: Bearer <token>" header
+func RateLimiter(config RateLimiterConfig, db *sql.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		client := identifyClient(c, db)
+
+		limit := config.Free
+		if client.premium {
+			limit = config.Premium
+		}
+
+		res, err := config.Store.Take(config.Scope+":"+client.key, limit)
+
+		if err != nil {
+			// A broken store should not take the api down with it
+			log.Println("rate limiter:", err)
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		c.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Per)))
+
+		if !res.Allowed {
+			code := http.StatusTooManyRequests
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.Status(code)
+			return c.JSON(models.Response{
+				Status:    code,
+				ErrorCode: "rate_limited",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// Resolves the client once per request, so stacked limiters don't look up the user again
+func identifyClient(c *fiber.Ctx, db *sql.DB) rateLimitClient {
+	if client, ok := c.Locals("rate_limit_client").(rateLimitClient); ok {
+		return client
+	}
+
+	client := rateLimitClient{
+		key: "ip:" + c.IP(),
+	}
+
+	token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+
+	if found {
+		if data, err := models.ValidateToken(strings.TrimSpace(token)); err == nil {
+			client.key = "user:" + strconv.FormatInt(data.Id, 10)
+			client.premium = premiumUsers.isPremium(data.Id, db)
+		}
+	}
+
+	c.Locals("rate_limit_client", client)
+	return client
+}
+
+type premiumEntry struct {
+	premium bool
+	expires time.Time
+}
+
+// Remembers which users are premium for a short while, so every request doesn't
+// look the plan up again
+type premiumCache struct {
+	mu        sync.Mutex
+	users     map[int64]premiumEntry
+	lastSweep time.Time
+}
+
+var premiumUsers = &premiumCache{
+	users:     make(map[int64]premiumEntry),
+	lastSweep: time.Now(),
+}
+
+func (p *premiumCache) isPremium(id int64, db *sql.DB) bool {
+	now := time.Now()
+
+	p.mu.Lock()
+	entry, ok := p.users[id]
+	p.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return entry.premium
+	}
+
+	premium, err := models.IsUserPremium(int(id), db)
+
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sweep(now)
+	p.users[id] = premiumEntry{premium: premium, expires: now.Add(PREMIUM_CACHE_TTL)}
+
+	return premium
+}
+
+func (p *premiumCache) sweep(now time.Time) {
+	if now.Sub(p.lastSweep) < PREMIUM_CACHE_TTL {
+		return
+	}
+
+	for id, entry := range p.users {
+		if !now.Before(entry.expires) {
+			delete(p.users, id)
+		}
+	}
+
+	p.lastSweep = now
+}
+
+// Parses limits like "60/1m", returning def when the value is empty or invalid
+func ParseRateLimit(value string, def RateLimit) RateLimit {
+	requests, per, found := strings.Cut(value, "/")
+
+	if !found {
+		return def
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return def
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return def
+	}
+
+	return RateLimit{Requests: n, Per: d}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
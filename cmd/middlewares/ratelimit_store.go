@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	// Size of the bucket, how many requests can be made in a burst
+	Requests int
+	// Time it takes to refill the whole bucket
+	Per time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Time until the bucket is full again
+	Reset time.Duration
+	// Time until the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+type RateLimitStore interface {
+	// Takes a token from the bucket of key, creating it full if it does not exist
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// Refills the bucket for the elapsed time and tries to take a token from it
+func takeToken(tokens float64, updated, now time.Time, limit RateLimit) (float64, RateLimitResult) {
+	capacity := float64(limit.Requests)
+	perToken := limit.Per / time.Duration(limit.Requests)
+
+	elapsed := now.Sub(updated)
+	if elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed.Seconds()/perToken.Seconds())
+	}
+
+	res := RateLimitResult{}
+
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration((capacity - tokens) * float64(perToken))
+
+	return tokens, res
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	per     time.Duration
+}
+
+// Keeps the buckets in the process, every instance of the api has its own limits
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{
+			tokens:  float64(limit.Requests),
+			updated: now,
+		}
+		s.buckets[key] = bucket
+	}
+
+	tokens, res := takeToken(bucket.tokens, bucket.updated, now, limit)
+
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.per = limit.Per
+
+	return res, nil
+}
+
+// Drops the buckets that are already full, they would be created full anyway
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) >= bucket.per {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+// Keeps the buckets in the rate_limits table so they are shared between instances
+type DBStore struct {
+	db *sql.DB
+}
+
+func NewDBStore(db *sql.DB) *DBStore {
+	return &DBStore{
+		db: db,
+	}
+}
+
+func (s *DBStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now()
+
+	// Creates the bucket full when it is missing, so the lock below always finds a row
+	_, err := s.db.Exec("INSERT INTO rate_limits (bucket_key, tokens, updated_at, full_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE bucket_key = bucket_key;", key, limit.Requests, now, now)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	defer tx.Rollback()
+
+	var tokens float64
+	var updated time.Time
+
+	row := tx.QueryRow("SELECT tokens, updated_at FROM rate_limits WHERE bucket_key = ? LIMIT 1 FOR UPDATE;", key)
+	if err := row.Scan(&tokens, &updated); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	tokens, res := takeToken(tokens, updated, now, limit)
+
+	_, err = tx.Exec("UPDATE rate_limits SET tokens = ?, updated_at = ?, full_at = ? WHERE bucket_key = ? LIMIT 1;", tokens, now, now.Add(res.Reset), key)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return res, tx.Commit()
+}
+
+// Removes the buckets that are already full, each one refills in its own window.
+// Meant to be called periodically
+func (s *DBStore) Purge() error {
+	stm, err := s.db.Prepare("DELETE FROM rate_limits WHERE full_at < ?;")
+	if err != nil {
+		return err
+	}
+	defer stm.Close()
+
+	_, err = stm.Exec(time.Now())
+	return err
+}
@@ -0,0 +1,161 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	testFree    = RateLimit{Requests: 60, Per: time.Minute}
+	testPremium = RateLimit{Requests: 600, Per: time.Minute}
+)
+
+func TestTakeToken(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		limit   RateLimit
+		want    float64
+		res     RateLimitResult
+	}{
+		{"full bucket", 60, 0, testFree, 59, RateLimitResult{Allowed: true, Remaining: 59, Reset: time.Second}},
+		{"last token", 1, 0, testFree, 0, RateLimitResult{Allowed: true, Remaining: 0, Reset: time.Minute}},
+		{"empty bucket", 0, 0, testFree, 0, RateLimitResult{Remaining: 0, Reset: time.Minute, RetryAfter: time.Second}},
+		{"half a token", 0.5, 0, testFree, 0.5, RateLimitResult{Remaining: 0, Reset: 59500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{"refills with the elapsed time", 0, 10 * time.Second, testFree, 9, RateLimitResult{Allowed: true, Remaining: 9, Reset: 51 * time.Second}},
+		{"refills a partial token", 0, 500 * time.Millisecond, testFree, 0.5, RateLimitResult{Remaining: 0, Reset: 59500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{"refill stops at the capacity", 0, time.Hour, testFree, 59, RateLimitResult{Allowed: true, Remaining: 59, Reset: time.Second}},
+		{"clock going back doesn't refill", 0, -time.Minute, testFree, 0, RateLimitResult{Remaining: 0, Reset: time.Minute, RetryAfter: time.Second}},
+		{"premium refills faster", 0, time.Second, testPremium, 9, RateLimitResult{Allowed: true, Remaining: 9, Reset: 59100 * time.Millisecond}},
+		{"premium retries sooner", 0, 0, testPremium, 0, RateLimitResult{Remaining: 0, Reset: time.Minute, RetryAfter: 100 * time.Millisecond}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, res := takeToken(test.tokens, now.Add(-test.elapsed), now, test.limit)
+
+			if tokens != test.want {
+				t.Errorf("tokens = %v, want %v", tokens, test.want)
+			}
+
+			if res != test.res {
+				t.Errorf("result = %+v, want %+v", res, test.res)
+			}
+		})
+	}
+}
+
+func TestTakeTokenBurst(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, limit := range []RateLimit{testFree, testPremium} {
+		tokens := float64(limit.Requests)
+
+		for i := 0; i < limit.Requests; i++ {
+			var res RateLimitResult
+
+			if tokens, res = takeToken(tokens, now, now, limit); !res.Allowed {
+				t.Fatalf("request %d of a burst of %d was refused", i+1, limit.Requests)
+			}
+		}
+
+		if _, res := takeToken(tokens, now, now, limit); res.Allowed {
+			t.Errorf("request %d over a burst of %d was allowed", limit.Requests+1, limit.Requests)
+		}
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	def := RateLimit{Requests: 5, Per: time.Second}
+
+	tests := []struct {
+		value string
+		want  RateLimit
+	}{
+		{"60/1m", RateLimit{Requests: 60, Per: time.Minute}},
+		{"600/1m", RateLimit{Requests: 600, Per: time.Minute}},
+		{" 10 / 30s ", RateLimit{Requests: 10, Per: 30 * time.Second}},
+		{"1000/1h30m", RateLimit{Requests: 1000, Per: 90 * time.Minute}},
+		{"", def},
+		{"60", def},
+		{"60/", def},
+		{"/1m", def},
+		{"0/1m", def},
+		{"-1/1m", def},
+		{"abc/1m", def},
+		{"60/0s", def},
+		{"60/-1m", def},
+		{"60/minute", def},
+		{"60/1m/2", def},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if got := ParseRateLimit(test.value, def); got != test.want {
+				t.Errorf("ParseRateLimit(%q) = %+v, want %+v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+// The premium tier gets its own limit, the client is left in the context as
+// identifyClient does once it has looked the user up
+func TestRateLimiterTiers(t *testing.T) {
+	config := RateLimiterConfig{
+		Scope:   "test",
+		Free:    RateLimit{Requests: 2, Per: time.Minute},
+		Premium: RateLimit{Requests: 4, Per: time.Minute},
+		Store:   NewMemoryStore(),
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		client := rateLimitClient{key: "user:" + c.Get("X-User")}
+		client.premium = c.Get("X-Premium") == "1"
+		c.Locals("rate_limit_client", client)
+		return c.Next()
+	})
+	app.Use(RateLimiter(config, nil))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	tests := []struct {
+		name    string
+		user    string
+		premium string
+		allowed int
+	}{
+		{"free", "1", "0", 2},
+		{"premium", "2", "1", 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i <= test.allowed; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set("X-User", test.user)
+				req.Header.Set("X-Premium", test.premium)
+
+				res, err := app.Test(req)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := fiber.StatusOK
+				if i == test.allowed {
+					want = fiber.StatusTooManyRequests
+				}
+
+				if res.StatusCode != want {
+					t.Errorf("request %d answered %d, want %d", i+1, res.StatusCode, want)
+				}
+			}
+		})
+	}
+}
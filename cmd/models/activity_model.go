@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// The activity feed merges the revisions of the to dos with their comments, the
+// newest first. Before is the at of the last entry of the previous page
+const (
+	ACTIVITY_CHANGE  = "change"
+	ACTIVITY_COMMENT = "comment"
+
+	MAX_ACTIVITY_PER_PAGE = 100
+)
+
+type ActivityFilter struct {
+	Before *time.Time
+	Limit  int
+}
+
+func (f *ActivityFilter) Validate() bool {
+	return f.Limit > 0 && f.Limit <= MAX_ACTIVITY_PER_PAGE
+}
+
+// Each part is filtered by its condition on the to do, the comments one can widen it
+func queryActivity(db *sql.DB, filter ActivityFilter, changes string, changesArgs []any, comments string, commentsArgs []any) ([]map[string]any, error) {
+	before := time.Now().Add(time.Minute)
+	if filter.Before != nil {
+		before = *filter.Before
+	}
+
+	query := "SELECT * FROM (" +
+		"SELECT '" + ACTIVITY_CHANGE + "' AS kind, r.id_revision AS id, r.id_todo, t.title, r.changed_by, r.action, r.changes, '' AS body, r.changed_at " +
+		"FROM todo_revisions r JOIN todos t ON t.id_todo = r.id_todo WHERE (" + changes + ") AND r.changed_at < ? " +
+		"UNION ALL " +
+		"SELECT '" + ACTIVITY_COMMENT + "', c.id_comment, c.id_todo, t.title, c.created_by, 'commented', '{}', c.body, c.created_at " +
+		"FROM todo_comments c JOIN todos t ON t.id_todo = c.id_todo WHERE (" + comments + ") AND c.status = 1 AND c.created_at < ?" +
+		") activity ORDER BY changed_at DESC, id DESC LIMIT ?;"
+
+	args := append(changesArgs, before)
+	args = append(args, commentsArgs...)
+	args = append(args, before, filter.Limit)
+
+	stm, err := db.Prepare(query)
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(args...)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	entries := make([]map[string]any, 0)
+
+	for rows.Next() {
+		var kind, title, action, changes, body string
+		var id, todo, user int64
+		var at time.Time
+
+		if err := rows.Scan(&kind, &id, &todo, &title, &user, &action, &changes, &body, &at); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		entry := map[string]any{
+			"type":       kind,
+			"id":         id,
+			"todo":       todo,
+			"todo_title": title,
+			"user":       user,
+			"action":     action,
+			"at":         at,
+		}
+
+		if kind == ACTIVITY_COMMENT {
+			entry["body"] = body
+		} else {
+			fields := map[string]FieldChange{}
+
+			if json.Unmarshal([]byte(changes), &fields) != nil {
+				return nil, locales.New("internal_error")
+			}
+
+			entry["changes"] = fields
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Activity of a to do for anyone that can see it
+func GetToDoActivity(todoId int64, userId int64, filter ActivityFilter, db *sql.DB) ([]map[string]any, error) {
+	if err := CheckToDoAccess(todoId, userId, db); err != nil {
+		return nil, err
+	}
+
+	return queryActivity(db, filter, "r.id_todo = ?", []any{todoId}, "c.id_todo = ?", []any{todoId})
+}
+
+// Activity of the to dos the user created or is assigned to, with the comments that
+// mention them anywhere
+func GetUserActivity(userId int64, filter ActivityFilter, db *sql.DB) ([]map[string]any, error) {
+	user := UserDTO{}
+
+	if active, err := user.VerifyUserIdIsActive(int(userId), db); !active || err != nil {
+		return nil, locales.New("invalid_user")
+	}
+
+	owned := "t.status = 1 AND (t.created_by = ? OR t.assigned_to = ?)"
+	mentioned := "(" + owned + ") OR c.id_comment IN (SELECT cm.id_comment FROM comment_mentions cm WHERE cm.id_user = ?)"
+
+	return queryActivity(db, filter, owned, []any{userId, userId}, mentioned, []any{userId, userId, userId})
+}
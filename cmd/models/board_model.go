@@ -0,0 +1,355 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// The board shows the personal to dos of the user in the columns they define, kept in
+// board_columns. A to do is in one column (todos.id_column) at column_position, the
+// ones with no column are listed apart as unsorted
+const MAX_COLUMNS_PER_USER = 10
+
+type Column struct {
+	Title     string `json:"title"`
+	Color     uint   `json:"color"`
+	CreatedBy int64  `json:"created_by"`
+}
+
+func (c *Column) ValidateColumn() bool {
+	c.Title = NormalizeText(c.Title)
+	return ValidateField(FIELD_COLUMN_TITLE, c.Title)
+}
+
+func (c *Column) CheckUserIsActive(db *sql.DB) (bool, error) {
+	userDto := UserDTO{}
+
+	return userDto.VerifyUserIdIsActive(int(c.CreatedBy), db)
+}
+
+func (c *Column) ColumnIsOwned(id int64, db *sql.DB) (bool, error) {
+	count := 0
+	err := db.QueryRow("SELECT COUNT(*) FROM board_columns WHERE id_column = ? AND created_by = ? AND status = 1;", id, c.CreatedBy).Scan(&count)
+
+	if err != nil {
+		return false, locales.New("internal_error")
+	}
+
+	return count > 0, nil
+}
+
+func (c *Column) CountColumnsPerUserId(db *sql.DB) (int, error) {
+	stm, err := db.Prepare("SELECT COUNT(*) FROM board_columns WHERE created_by = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return -1, err
+	}
+	defer stm.Close()
+
+	count := -1
+	err = stm.QueryRow(c.CreatedBy).Scan(&count)
+
+	return count, err
+}
+
+// The order of the columns of the user
+func (c *Column) order() ordering {
+	return ordering{
+		table:    "board_columns",
+		id:       "id_column",
+		position: "position",
+		scope:    "created_by = ? AND status = 1",
+		args:     []any{c.CreatedBy},
+	}
+}
+
+// New columns go at the end of the board
+func (c *Column) InsertColumn(db *sql.DB) (int64, error) {
+	if active, err := c.CheckUserIsActive(db); !active || err != nil {
+		return -1, locales.New("invalid_user")
+	}
+
+	count, err := c.CountColumnsPerUserId(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_COLUMNS_PER_USER {
+		return -1, locales.New("columns_limit")
+	}
+
+	stm, err := db.Prepare("INSERT INTO board_columns (title, color, created_by, position) " +
+		"SELECT ?, ?, ?, COALESCE(MAX(position), 0) + 1 FROM board_columns WHERE created_by = ? AND status = 1;")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(c.Title, c.Color, c.CreatedBy, c.CreatedBy)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+// Deleting a column takes its to dos off the board, they are kept
+func (c *Column) UpdateColumnById(id int64, delete bool, db *sql.DB) error {
+	if owned, err := c.ColumnIsOwned(id, db); !owned || err != nil {
+		return locales.New("column_not_found")
+	}
+
+	if !delete {
+		stm, err := db.Prepare("UPDATE board_columns SET title = ?, color = ?, updated_at = now() WHERE id_column = ? AND created_by = ? LIMIT 1;")
+
+		if err != nil {
+			return locales.New("column_update_failed")
+		}
+		defer stm.Close()
+
+		if _, err := stm.Exec(c.Title, c.Color, id, c.CreatedBy); err != nil {
+			return locales.New("column_update_failed")
+		}
+
+		return nil
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("column_delete_failed")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE board_columns SET status = 0, updated_at = now() WHERE id_column = ? AND created_by = ? LIMIT 1;", id, c.CreatedBy); err != nil {
+		return locales.New("column_delete_failed")
+	}
+
+	if _, err := tx.Exec("UPDATE todos SET id_column = NULL, column_position = NULL WHERE id_column = ?;", id); err != nil {
+		return locales.New("column_delete_failed")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("column_delete_failed")
+	}
+
+	return nil
+}
+
+func (c *Column) GetAllColumnsFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := c.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	stm, err := db.Prepare("SELECT id_column, title, color, position FROM board_columns WHERE created_by = ? AND status = 1 ORDER BY position ASC, id_column ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(c.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	columns := make([]map[string]any, 0)
+
+	for rows.Next() {
+		column := Column{CreatedBy: c.CreatedBy}
+		var id int64
+		var position float64
+
+		if err := rows.Scan(&id, &column.Title, &column.Color, &position); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		columns = append(columns, map[string]any{
+			"id":         id,
+			"title":      column.Title,
+			"color":      column.Color,
+			"position":   position,
+			"created_by": column.CreatedBy,
+		})
+	}
+
+	return columns, nil
+}
+
+// Moves the column right after the column after, or to the start of the board when after is 0
+func (c *Column) MoveColumn(id int64, after int64, db *sql.DB) (float64, error) {
+	if owned, err := c.ColumnIsOwned(id, db); !owned || err != nil {
+		return 0, locales.New("column_not_found")
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	position, err := c.order().move(id, after, tx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE board_columns SET position = ?, updated_at = now() WHERE id_column = ? LIMIT 1;", position, id); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	return position, nil
+}
+
+// Puts the to do in the column right after the to do after, at the top when after is 0.
+// Column and position change together, column 0 takes it off the board
+func (t *ToDo) MoveToColumn(id int64, column int64, after int64, db *sql.DB) (float64, error) {
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return 0, err
+	}
+
+	if current.Workspace != 0 {
+		return 0, locales.New("todo_in_workspace")
+	}
+
+	owner := Column{CreatedBy: t.CreatedBy}
+
+	if column != 0 {
+		if owned, err := owner.ColumnIsOwned(column, db); !owned || err != nil {
+			return 0, locales.New("column_not_found")
+		}
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	var position float64
+	columnPosition := sql.NullFloat64{}
+
+	if column != 0 {
+		order := ordering{
+			table:    "todos",
+			id:       "id_todo",
+			position: "column_position",
+			scope:    "created_by = ? AND id_column = ? AND status = 1",
+			args:     []any{t.CreatedBy, column},
+		}
+
+		if position, err = order.move(id, after, tx); err != nil {
+			return 0, err
+		}
+
+		columnPosition = sql.NullFloat64{Float64: position, Valid: true}
+	}
+
+	_, err = tx.Exec("UPDATE todos SET id_column = NULLIF(?, 0), column_position = ?, updated_at = now() WHERE id_todo = ? AND created_by = ? LIMIT 1;", column, columnPosition, id, t.CreatedBy)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	if column != current.Column {
+		if err := t.recordState(tx, id, REVISION_MOVED, "column", current.Column, column, &current); err != nil {
+			return 0, locales.New("internal_error")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	return position, nil
+}
+
+// The columns of the user with their to dos in order, and the to dos with no column.
+// Archived and workspace to dos are left out
+func GetBoard(userId int64, db *sql.DB) (map[string]any, error) {
+	owner := Column{CreatedBy: userId}
+	columns, err := owner.GetAllColumnsFromUserId(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stm, err := db.Prepare("SELECT id_todo, title, color, deadline, priority, completed, COALESCE(id_column, 0), COALESCE(column_position, 0), " +
+		"(SELECT GROUP_CONCAT(tt.id_tag ORDER BY tt.id_tag) FROM todo_tags tt WHERE tt.id_todo = todos.id_todo) " +
+		"FROM todos WHERE created_by = ? AND id_workspace IS NULL AND status = 1 AND archived_at IS NULL " +
+		"ORDER BY column_position IS NULL, column_position ASC, position ASC, id_todo ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(userId)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	byColumn := map[int64][]map[string]any{}
+
+	for rows.Next() {
+		todo := ToDo{CreatedBy: userId}
+		var id int64
+		var deadline time.Time
+		var position float64
+		var tags sql.NullString
+
+		if err := rows.Scan(&id, &todo.Title, &todo.Color, &deadline, &todo.Priority, &todo.Completed, &todo.Column, &position, &tags); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		byColumn[todo.Column] = append(byColumn[todo.Column], map[string]any{
+			"id":              id,
+			"title":           todo.Title,
+			"color":           todo.Color,
+			"deadline":        deadline,
+			"priority":        todo.Priority,
+			"completed":       todo.Completed,
+			"tags":            parseIdList(tags),
+			"column":          todo.Column,
+			"column_position": position,
+		})
+	}
+
+	for _, column := range columns {
+		todos := byColumn[column["id"].(int64)]
+
+		if todos == nil {
+			todos = make([]map[string]any, 0)
+		}
+
+		column["todos"] = todos
+	}
+
+	unsorted := byColumn[0]
+
+	if unsorted == nil {
+		unsorted = make([]map[string]any, 0)
+	}
+
+	return map[string]any{
+		"columns":  columns,
+		"unsorted": unsorted,
+	}, nil
+}
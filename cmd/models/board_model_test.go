@@ -0,0 +1,191 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// To dos of the column from top to bottom
+func columnOrder(user int64, column int64) ordering {
+	return ordering{
+		table:    "todos",
+		id:       "id_todo",
+		position: "column_position",
+		scope:    "created_by = ? AND id_column = ? AND status = 1",
+		args:     []any{user, column},
+	}
+}
+
+func insertTestColumn(t *testing.T, db *sql.DB, title string, user int64) int64 {
+	t.Helper()
+
+	column := Column{Title: title, CreatedBy: user}
+	id, err := column.InsertColumn(db)
+
+	if err != nil {
+		t.Fatalf("InsertColumn(%q): %v", title, err)
+	}
+
+	return id
+}
+
+func TestMoveColumnToStart(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	owner := Column{CreatedBy: user}
+	ids := make([]int64, 0)
+
+	for _, title := range []string{"todo", "doing", "done"} {
+		ids = append(ids, insertTestColumn(t, db, title, user))
+	}
+
+	position, err := owner.MoveColumn(ids[2], 0, db)
+
+	if err != nil {
+		t.Fatalf("MoveColumn to the start: %v", err)
+	}
+
+	if position != 0 {
+		t.Errorf("position = %v, want 0", position)
+	}
+
+	if got, want := orderedIds(t, db, owner.order()), []int64{ids[2], ids[0], ids[1]}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+
+	if _, err := owner.MoveColumn(ids[1], 0, db); err != nil {
+		t.Fatalf("MoveColumn to the start again: %v", err)
+	}
+
+	if got, want := orderedIds(t, db, owner.order()), []int64{ids[1], ids[2], ids[0]}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestMoveToColumnTop(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	todo := ToDo{CreatedBy: user}
+	columnId := insertTestColumn(t, db, "doing", user)
+	ids := make([]int64, 0)
+
+	// Each one goes after the previous one, so the column is numbered from 1
+	for i, title := range []string{"first", "second", "third"} {
+		id := insertTestToDo(t, db, ToDo{Title: title, CreatedBy: user})
+		after := int64(0)
+
+		if i != 0 {
+			after = ids[i-1]
+		}
+
+		if _, err := todo.MoveToColumn(id, columnId, after, db); err != nil {
+			t.Fatalf("MoveToColumn(%q): %v", title, err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if _, err := db.Exec("UPDATE todos SET column_position = id_todo - ? WHERE id_column = ?;", ids[0]-1, columnId); err != nil {
+		t.Fatal(err)
+	}
+
+	position, err := todo.MoveToColumn(ids[2], columnId, 0, db)
+
+	if err != nil {
+		t.Fatalf("MoveToColumn to the top: %v", err)
+	}
+
+	if position != 0 {
+		t.Errorf("position = %v, want 0", position)
+	}
+
+	if got, want := orderedIds(t, db, columnOrder(user, columnId)), []int64{ids[2], ids[0], ids[1]}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestMoveToColumnBetweenColumns(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	todo := ToDo{CreatedBy: user}
+	doing := insertTestColumn(t, db, "doing", user)
+	done := insertTestColumn(t, db, "done", user)
+
+	first := insertTestToDo(t, db, ToDo{Title: "first", CreatedBy: user})
+	second := insertTestToDo(t, db, ToDo{Title: "second", CreatedBy: user})
+
+	for _, id := range []int64{first, second} {
+		if _, err := todo.MoveToColumn(id, doing, 0, db); err != nil {
+			t.Fatalf("MoveToColumn(%d, doing): %v", id, err)
+		}
+	}
+
+	if _, err := todo.MoveToColumn(first, done, 0, db); err != nil {
+		t.Fatalf("MoveToColumn(first, done): %v", err)
+	}
+
+	if got, want := orderedIds(t, db, columnOrder(user, doing)), []int64{second}; !slices.Equal(got, want) {
+		t.Errorf("doing = %v, want %v", got, want)
+	}
+
+	if got, want := orderedIds(t, db, columnOrder(user, done)), []int64{first}; !slices.Equal(got, want) {
+		t.Errorf("done = %v, want %v", got, want)
+	}
+
+	history, err := todo.GetHistory(first, db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if history[0].Action != REVISION_MOVED || history[0].Changes["column"].To != float64(done) {
+		t.Errorf("last revision = %+v, want moved to %d", history[0], done)
+	}
+
+	// Column 0 takes it off the board
+	if _, err := todo.MoveToColumn(first, 0, 0, db); err != nil {
+		t.Fatalf("MoveToColumn(first, 0): %v", err)
+	}
+
+	if got := orderedIds(t, db, columnOrder(user, done)); len(got) != 0 {
+		t.Errorf("done = %v, want it empty", got)
+	}
+}
+
+func TestMoveToColumnIsolation(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	other := insertTestUser(t, db, "other")
+	workspace := insertTestWorkspace(t, db, "team", user)
+	todo := ToDo{CreatedBy: user}
+
+	mine := insertTestColumn(t, db, "mine", user)
+	theirs := insertTestColumn(t, db, "theirs", other)
+	personal := insertTestToDo(t, db, ToDo{Title: "personal", CreatedBy: user})
+	shared := insertTestToDo(t, db, ToDo{Title: "shared", CreatedBy: user, Workspace: workspace})
+
+	tests := []struct {
+		name   string
+		id     int64
+		column int64
+		err    string
+	}{
+		{"column of another user", personal, theirs, "column_not_found"},
+		{"to do of a workspace", shared, mine, "todo_in_workspace"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := todo.MoveToColumn(test.id, test.column, 0, db); locales.CodeOf(err) != test.err {
+				t.Errorf("MoveToColumn = %v, want %q", err, test.err)
+			}
+		})
+	}
+
+	if got := orderedIds(t, db, columnOrder(other, theirs)); len(got) != 0 {
+		t.Errorf("column of the other user = %v, want it empty", got)
+	}
+}
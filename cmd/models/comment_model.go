@@ -0,0 +1,254 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Comments on a to do live in todo_comments, a reply points to the comment it answers
+// with id_parent. The users mentioned in a comment are kept in comment_mentions
+const MAX_MENTIONS_PER_COMMENT = 10
+
+type Comment struct {
+	Body string `json:"body"`
+	// Comment it replies to, 0 for the ones that start a thread
+	Parent    int64   `json:"parent"`
+	Mentions  []int64 `json:"mentions"`
+	ToDo      int64   `json:"todo"`
+	CreatedBy int64   `json:"created_by"`
+}
+
+// Anyone that can see the to do comments on it: its creator and the members of its
+// list or its workspace
+const toDoAccessQuery = "SELECT COUNT(*) FROM todos t WHERE t.id_todo = ? AND t.status = 1 AND (t.created_by = ? " +
+	"OR EXISTS (SELECT 1 FROM list_members m JOIN lists l ON l.id_list = m.id_list WHERE m.id_list = t.id_list AND m.id_user = ? AND m.status = 1 AND l.status = 1) " +
+	"OR EXISTS (SELECT 1 FROM workspace_members m JOIN workspaces w ON w.id_workspace = m.id_workspace WHERE m.id_workspace = t.id_workspace AND m.id_user = ? AND m.status = 1 AND w.status = 1));"
+
+func CheckToDoAccess(todoId int64, userId int64, db *sql.DB) error {
+	count := 0
+	err := db.QueryRow(toDoAccessQuery, todoId, userId, userId, userId).Scan(&count)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if count == 0 {
+		return locales.New("todo_not_found")
+	}
+
+	return nil
+}
+
+// Removes the repeated mentions, the author mentioning themselves is dropped
+func (c *Comment) ValidateComment() bool {
+	c.Body = NormalizeText(c.Body)
+	mentions := make([]int64, 0, len(c.Mentions))
+
+	for _, user := range c.Mentions {
+		if user <= 0 {
+			return false
+		}
+
+		if user != c.CreatedBy && !slices.Contains(mentions, user) {
+			mentions = append(mentions, user)
+		}
+	}
+
+	c.Mentions = mentions
+
+	return c.Parent >= 0 && len(mentions) <= MAX_MENTIONS_PER_COMMENT && ValidateField(FIELD_COMMENT_BODY, c.Body)
+}
+
+// Only the users that can see the to do can be mentioned in it
+func (c *Comment) checkMentions(db *sql.DB) error {
+	for _, user := range c.Mentions {
+		if err := CheckToDoAccess(c.ToDo, user, db); err != nil {
+			return locales.New("mention_not_member")
+		}
+	}
+
+	return nil
+}
+
+func setMentions(id int64, mentions []int64, tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM comment_mentions WHERE id_comment = ?;", id); err != nil {
+		return err
+	}
+
+	for _, user := range mentions {
+		if _, err := tx.Exec("INSERT INTO comment_mentions (id_comment, id_user) VALUES ( ?, ? );", id, user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Comment) InsertComment(db *sql.DB) (int64, error) {
+	if err := CheckToDoAccess(c.ToDo, c.CreatedBy, db); err != nil {
+		return -1, err
+	}
+
+	if c.Parent != 0 {
+		count := 0
+		err := db.QueryRow("SELECT COUNT(*) FROM todo_comments WHERE id_comment = ? AND id_todo = ? AND status = 1;", c.Parent, c.ToDo).Scan(&count)
+
+		if err != nil || count == 0 {
+			return -1, locales.New("comment_not_found")
+		}
+	}
+
+	if err := c.checkMentions(db); err != nil {
+		return -1, err
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO todo_comments (id_todo, id_parent, created_by, body) VALUES ( ?, NULLIF(?, 0), ?, ? );", c.ToDo, c.Parent, c.CreatedBy, c.Body)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if err := setMentions(id, c.Mentions, tx); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return id, nil
+}
+
+// Only the author edits or deletes a comment, the replies of a deleted one are kept
+func (c *Comment) UpdateCommentById(id int64, delete bool, db *sql.DB) error {
+	if err := CheckToDoAccess(c.ToDo, c.CreatedBy, db); err != nil {
+		return err
+	}
+
+	count := 0
+	err := db.QueryRow("SELECT COUNT(*) FROM todo_comments WHERE id_comment = ? AND id_todo = ? AND created_by = ? AND status = 1;", id, c.ToDo, c.CreatedBy).Scan(&count)
+
+	if err != nil || count == 0 {
+		return locales.New("comment_not_found")
+	}
+
+	if delete {
+		stm, err := db.Prepare("UPDATE todo_comments SET status = 0, deleted_at = now() WHERE id_comment = ? LIMIT 1;")
+
+		if err != nil {
+			return locales.New("comment_delete_failed")
+		}
+		defer stm.Close()
+
+		if _, err := stm.Exec(id); err != nil {
+			return locales.New("comment_delete_failed")
+		}
+
+		return nil
+	}
+
+	if err := c.checkMentions(db); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("comment_update_failed")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE todo_comments SET body = ?, updated_at = now() WHERE id_comment = ? LIMIT 1;", c.Body, id); err != nil {
+		return locales.New("comment_update_failed")
+	}
+
+	if err := setMentions(id, c.Mentions, tx); err != nil {
+		return locales.New("comment_update_failed")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("comment_update_failed")
+	}
+
+	return nil
+}
+
+// Lists the comments of the to do oldest first, each reply after the comment it answers is
+// up to the client. A deleted comment with replies stays with an empty body to keep the thread
+func (c *Comment) GetComments(db *sql.DB) ([]map[string]any, error) {
+	if err := CheckToDoAccess(c.ToDo, c.CreatedBy, db); err != nil {
+		return nil, err
+	}
+
+	stm, err := db.Prepare("SELECT c.id_comment, COALESCE(c.id_parent, 0), c.created_by, u.name, c.body, c.status, c.created_at, COALESCE(c.updated_at, c.created_at), " +
+		"(SELECT GROUP_CONCAT(cm.id_user ORDER BY cm.id_user) FROM comment_mentions cm WHERE cm.id_comment = c.id_comment) " +
+		"FROM todo_comments c JOIN users u ON u.id_user = c.created_by WHERE c.id_todo = ? " +
+		"AND (c.status = 1 OR EXISTS (SELECT 1 FROM todo_comments r WHERE r.id_parent = c.id_comment AND r.status = 1)) " +
+		"ORDER BY c.created_at ASC, c.id_comment ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(c.ToDo)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	comments := make([]map[string]any, 0)
+
+	for rows.Next() {
+		comment := Comment{ToDo: c.ToDo}
+		var id int64
+		var author string
+		var status int
+		var createdAt, updatedAt time.Time
+		var mentions sql.NullString
+
+		if err := rows.Scan(&id, &comment.Parent, &comment.CreatedBy, &author, &comment.Body, &status, &createdAt, &updatedAt, &mentions); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		deleted := status == 0
+		comment.Mentions = parseIdList(mentions)
+
+		if deleted {
+			comment.Body = ""
+			comment.Mentions = make([]int64, 0)
+		}
+
+		comments = append(comments, map[string]any{
+			"id":         id,
+			"todo":       comment.ToDo,
+			"parent":     comment.Parent,
+			"created_by": comment.CreatedBy,
+			"author":     author,
+			"body":       comment.Body,
+			"mentions":   comment.Mentions,
+			"deleted":    deleted,
+			"created_at": createdAt,
+			"updated_at": updatedAt,
+		})
+	}
+
+	return comments, nil
+}
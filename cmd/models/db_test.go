@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Opens the test database of NAILIT_TEST_DSN with a fresh schema, the tests that
+// need it are skipped when it is not set. The database is wiped on every call, e.g.
+//
+//	NAILIT_TEST_DSN="nailit:nailit@tcp(127.0.0.1:3306)/nailit_test?parseTime=true" go test ./cmd/models
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("NAILIT_TEST_DSN")
+
+	if dsn == "" {
+		t.Skip("NAILIT_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	execSQLFile(t, db, "testdata/schema.sql")
+	return db
+}
+
+// Runs the statements of a SQL file, each one ends its line with ";" and the lines
+// starting with "--" are comments
+func execSQLFile(t *testing.T, db *sql.DB, path string) {
+	t.Helper()
+
+	script, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make([]string, 0)
+
+	for _, line := range strings.Split(string(script), "\n") {
+		if !strings.HasPrefix(line, "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";\n") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v\n%s", path, err, statement)
+		}
+	}
+}
+
+func insertTestUser(t *testing.T, db *sql.DB, name string) int64 {
+	t.Helper()
+
+	res, err := db.Exec("INSERT INTO users (name, mail) VALUES ( ?, ? );", name, name+"@nailit.test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := res.LastInsertId()
+	return id
+}
+
+func insertTestToDo(t *testing.T, db *sql.DB, todo ToDo) int64 {
+	t.Helper()
+
+	if todo.Deadline.IsZero() {
+		todo.Deadline = time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	}
+
+	if todo.Timezone == "" {
+		todo.Timezone = "UTC"
+	}
+
+	id, err := todo.InsertToDo(db)
+
+	if err != nil {
+		t.Fatalf("InsertToDo(%q): %v", todo.Title, err)
+	}
+
+	return id
+}
+
+func insertTestWorkspace(t *testing.T, db *sql.DB, name string, owner int64) int64 {
+	t.Helper()
+
+	workspace := Workspace{Name: name, CreatedBy: owner}
+	id, err := workspace.InsertWorkspace(db)
+
+	if err != nil {
+		t.Fatalf("InsertWorkspace(%q): %v", name, err)
+	}
+
+	return id
+}
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// A dependency in todo_dependencies says the to do id_blocked can't start until
+// id_blocker is completed. Both belong to the same user. A to do is blocked while any
+// of its blockers is still open, so completing the last one unblocks it with no write
+const MAX_BLOCKERS_PER_TODO = 20
+
+// Condition on the todos table of the listings, true for the blocked ones
+const blockedQuery = "EXISTS (SELECT 1 FROM todo_dependencies d JOIN todos b ON b.id_todo = d.id_blocker " +
+	"WHERE d.id_blocked = todos.id_todo AND b.status = 1 AND b.completed = 0)"
+
+// Makes the to do id wait for blocker. Fails when blocker already waits for id,
+// directly or through other to dos, as neither could ever start
+func (t *ToDo) AddBlocker(id int64, blocker int64, db *sql.DB) error {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	if id == blocker {
+		return locales.New("dependency_cycle")
+	}
+
+	for _, todo := range []int64{id, blocker} {
+		if owned, err := t.ToDoIsOwned(todo, db); !owned || err != nil {
+			return locales.New("todo_not_found")
+		}
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	blockers, err := t.dependencies(tx)
+
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(blockers[id], blocker) {
+		return locales.New("dependency_exists")
+	}
+
+	if len(blockers[id]) >= MAX_BLOCKERS_PER_TODO {
+		return locales.New("blockers_limit")
+	}
+
+	if waitsFor(blockers, blocker, id) {
+		return locales.New("dependency_cycle")
+	}
+
+	if _, err := tx.Exec("INSERT INTO todo_dependencies (id_blocker, id_blocked) VALUES ( ?, ? );", blocker, id); err != nil {
+		return locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+func (t *ToDo) RemoveBlocker(id int64, blocker int64, db *sql.DB) error {
+	if owned, err := t.ToDoIsOwned(id, db); !owned || err != nil {
+		return locales.New("todo_not_found")
+	}
+
+	res, err := db.Exec("DELETE FROM todo_dependencies WHERE id_blocker = ? AND id_blocked = ? LIMIT 1;", blocker, id)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("dependency_not_found")
+	}
+
+	return nil
+}
+
+// The blockers of every to do of the user. The to dos of the user are locked first
+// until the transaction ends: locking the dependencies alone lets two requests that
+// see no rows each add one half of a cycle
+func (t *ToDo) dependencies(tx *sql.Tx) (map[int64][]int64, error) {
+	locked, err := tx.Query("SELECT id_todo FROM todos WHERE created_by = ? ORDER BY id_todo FOR UPDATE;", t.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	locked.Close()
+
+	rows, err := tx.Query("SELECT d.id_blocked, d.id_blocker FROM todo_dependencies d JOIN todos t ON t.id_todo = d.id_blocked "+
+		"WHERE t.created_by = ? FOR UPDATE;", t.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	blockers := map[int64][]int64{}
+
+	for rows.Next() {
+		var blocked, blocker int64
+
+		if err := rows.Scan(&blocked, &blocker); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		blockers[blocked] = append(blockers[blocked], blocker)
+	}
+
+	return blockers, nil
+}
+
+// Tells if from waits for to, following the blockers of each to do
+func waitsFor(blockers map[int64][]int64, from int64, to int64) bool {
+	seen := map[int64]bool{from: true}
+	pending := []int64{from}
+
+	for len(pending) != 0 {
+		todo := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, blocker := range blockers[todo] {
+			if blocker == to {
+				return true
+			}
+
+			if !seen[blocker] {
+				seen[blocker] = true
+				pending = append(pending, blocker)
+			}
+		}
+	}
+
+	return false
+}
+
+// The to dos the to do id waits for and the ones waiting for it, the ones in the trash
+// are left out
+func (t *ToDo) GetDependencies(id int64, db *sql.DB) (map[string]any, error) {
+	if owned, err := t.ToDoIsOwned(id, db); !owned || err != nil {
+		return nil, locales.New("todo_not_found")
+	}
+
+	blockedBy, err := queryDependencies(db, "id_blocked", "id_blocker", id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := queryDependencies(db, "id_blocker", "id_blocked", id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := false
+
+	for _, blocker := range blockedBy {
+		if !blocker["completed"].(bool) {
+			blocked = true
+		}
+	}
+
+	return map[string]any{
+		"id":         id,
+		"blocked":    blocked,
+		"blocked_by": blockedBy,
+		"blocks":     blocks,
+	}, nil
+}
+
+// The to dos on the other side of the dependencies where field is id
+func queryDependencies(db *sql.DB, field string, other string, id int64) ([]map[string]any, error) {
+	rows, err := db.Query("SELECT t.id_todo, t.title, t.completed FROM todo_dependencies d JOIN todos t ON t.id_todo = d."+other+
+		" WHERE d."+field+" = ? AND t.status = 1 ORDER BY t.id_todo ASC;", id)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	todos := make([]map[string]any, 0)
+
+	for rows.Next() {
+		todo := ToDo{}
+		var todoId int64
+
+		if err := rows.Scan(&todoId, &todo.Title, &todo.Completed); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		todos = append(todos, map[string]any{
+			"id":        todoId,
+			"title":     todo.Title,
+			"completed": todo.Completed,
+		})
+	}
+
+	return todos, nil
+}
+
+// The to dos that no longer wait for anything once the to do id is completed
+func (t *ToDo) UnblockedBy(id int64, db *sql.DB) ([]int64, error) {
+	rows, err := db.Query("SELECT todos.id_todo FROM todo_dependencies w JOIN todos ON todos.id_todo = w.id_blocked "+
+		"WHERE w.id_blocker = ? AND todos.status = 1 AND todos.completed = 0 AND NOT "+blockedQuery+" ORDER BY todos.id_todo ASC;", id)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+
+	for rows.Next() {
+		var todo int64
+
+		if err := rows.Scan(&todo); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		ids = append(ids, todo)
+	}
+
+	return ids, nil
+}
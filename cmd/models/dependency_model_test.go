@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+func TestAddBlockerRejectsCycles(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "planner")
+	other := insertTestUser(t, db, "other")
+	todo := ToDo{CreatedBy: user}
+
+	first := insertTestToDo(t, db, ToDo{Title: "first", CreatedBy: user})
+	second := insertTestToDo(t, db, ToDo{Title: "second", CreatedBy: user})
+	third := insertTestToDo(t, db, ToDo{Title: "third", CreatedBy: user})
+	theirs := insertTestToDo(t, db, ToDo{Title: "theirs", CreatedBy: other})
+
+	// Each one is checked with the ones added before it
+	tests := []struct {
+		name    string
+		id      int64
+		blocker int64
+		err     string
+	}{
+		{"second waits for first", second, first, ""},
+		{"third waits for second", third, second, ""},
+		{"same dependency again", third, second, "dependency_exists"},
+		{"itself", first, first, "dependency_cycle"},
+		{"direct cycle", first, second, "dependency_cycle"},
+		{"cycle through another to do", first, third, "dependency_cycle"},
+		{"third waits for first too", third, first, ""},
+		{"to do of another user", first, theirs, "todo_not_found"},
+	}
+
+	for _, test := range tests {
+		if err := todo.AddBlocker(test.id, test.blocker, db); locales.CodeOf(err) != test.err {
+			t.Errorf("%s: AddBlocker(%d, %d) = %v, want %q", test.name, test.id, test.blocker, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,671 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Shared lists, a user can see and change the to dos of the lists they are a member of
+// depending on their role. Members are kept in list_members and the invitations,
+// addressed to a mail, in list_invites until they are accepted or declined
+const (
+	ROLE_VIEWER = "viewer"
+	ROLE_EDITOR = "editor"
+	ROLE_OWNER  = "owner"
+
+	MAX_LISTS_PER_USER   = 10
+	MAX_MEMBERS_PER_LIST = 20
+)
+
+var roleRanks = map[string]int{
+	ROLE_VIEWER: 1,
+	ROLE_EDITOR: 2,
+	ROLE_OWNER:  3,
+}
+
+type List struct {
+	Title     string `json:"title"`
+	Color     uint   `json:"color"`
+	CreatedBy int64  `json:"created_by"`
+}
+
+type ListInvite struct {
+	Mail      string `json:"mail"`
+	Role      string `json:"role"`
+	List      int64  `json:"list"`
+	InvitedBy int64  `json:"invited_by"`
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+func (l *List) ValidateList() bool {
+	l.Title = NormalizeText(l.Title)
+	return ValidateField(FIELD_LIST_TITLE, l.Title)
+}
+
+func (l *List) CheckUserIsActive(db *sql.DB) (bool, error) {
+	userDto := UserDTO{}
+
+	return userDto.VerifyUserIdIsActive(int(l.CreatedBy), db)
+}
+
+// Role of the user in the list, empty when they are not a member
+func MemberRole(listId int64, userId int64, db *sql.DB) (string, error) {
+	stm, err := db.Prepare("SELECT m.role FROM list_members m JOIN lists l ON l.id_list = m.id_list WHERE m.id_list = ? AND m.id_user = ? AND m.status = 1 AND l.status = 1 LIMIT 1;")
+
+	if err != nil {
+		return "", locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	role := ""
+	err = stm.QueryRow(listId, userId).Scan(&role)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", locales.New("internal_error")
+	}
+
+	return role, nil
+}
+
+// Fails unless the user has at least the needed role in the list
+func CheckListRole(listId int64, userId int64, needed string, db *sql.DB) error {
+	role, err := MemberRole(listId, userId, db)
+
+	if err != nil {
+		return err
+	}
+
+	if role == "" {
+		return locales.New("list_not_found")
+	}
+
+	if roleRanks[role] < roleRanks[needed] {
+		return locales.New("not_allowed")
+	}
+
+	return nil
+}
+
+func (l *List) CountListsPerUserId(db *sql.DB) (int, error) {
+	stm, err := db.Prepare("SELECT COUNT(*) FROM lists WHERE created_by = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return -1, err
+	}
+	defer stm.Close()
+
+	count := -1
+	err = stm.QueryRow(l.CreatedBy).Scan(&count)
+
+	return count, err
+}
+
+// Creates the list with its creator as owner
+func (l *List) InsertList(db *sql.DB) (int64, error) {
+	if active, err := l.CheckUserIsActive(db); !active || err != nil {
+		return -1, locales.New("invalid_user")
+	}
+
+	count, err := l.CountListsPerUserId(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_LISTS_PER_USER {
+		return -1, locales.New("lists_limit")
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO lists (title, color, created_by) VALUES ( ?, ?, ? );", l.Title, l.Color, l.CreatedBy)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	_, err = tx.Exec("INSERT INTO list_members (id_list, id_user, role) VALUES ( ?, ?, ? );", id, l.CreatedBy, ROLE_OWNER)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return id, nil
+}
+
+// Only owners change or delete a list, its to dos are kept by their creators when it is deleted
+func (l *List) UpdateListById(id int64, delete bool, db *sql.DB) error {
+	if err := CheckListRole(id, l.CreatedBy, ROLE_OWNER, db); err != nil {
+		return err
+	}
+
+	if !delete {
+		stm, err := db.Prepare("UPDATE lists SET title = ?, color = ?, updated_at = now() WHERE id_list = ? AND status = 1 LIMIT 1;")
+
+		if err != nil {
+			return locales.New("list_update_failed")
+		}
+		defer stm.Close()
+
+		if _, err := stm.Exec(l.Title, l.Color, id); err != nil {
+			return locales.New("list_update_failed")
+		}
+
+		return nil
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("list_delete_failed")
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		"UPDATE lists SET status = 0, updated_at = now() WHERE id_list = ? LIMIT 1;",
+		"UPDATE list_members SET status = 0 WHERE id_list = ?;",
+		"UPDATE list_invites SET status = 0 WHERE id_list = ? AND status = 1;",
+		"UPDATE todos SET id_list = NULL, assigned_to = NULL WHERE id_list = ?;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return locales.New("list_delete_failed")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("list_delete_failed")
+	}
+
+	return nil
+}
+
+// Lists the lists the user is a member of, with their role in each one
+func (l *List) GetAllListsFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := l.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	stm, err := db.Prepare("SELECT l.id_list, l.title, l.color, l.created_by, m.role, " +
+		"(SELECT COUNT(*) FROM list_members lm WHERE lm.id_list = l.id_list AND lm.status = 1), " +
+		"(SELECT COUNT(*) FROM todos t WHERE t.id_list = l.id_list AND t.status = 1 AND t.archived_at IS NULL) " +
+		"FROM lists l JOIN list_members m ON m.id_list = l.id_list WHERE m.id_user = ? AND m.status = 1 AND l.status = 1 ORDER BY l.title ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(l.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	lists := make([]map[string]any, 0)
+
+	for rows.Next() {
+		list := List{}
+		var id int64
+		var role string
+		members, todos := 0, 0
+
+		if err := rows.Scan(&id, &list.Title, &list.Color, &list.CreatedBy, &role, &members, &todos); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		lists = append(lists, map[string]any{
+			"id":         id,
+			"title":      list.Title,
+			"color":      list.Color,
+			"created_by": list.CreatedBy,
+			"role":       role,
+			"members":    members,
+			"todos":      todos,
+		})
+	}
+
+	return lists, nil
+}
+
+func (l *List) GetMembers(id int64, db *sql.DB) ([]map[string]any, error) {
+	if err := CheckListRole(id, l.CreatedBy, ROLE_VIEWER, db); err != nil {
+		return nil, err
+	}
+
+	stm, err := db.Prepare("SELECT u.id_user, u.name, u.mail, u.image_url, m.role FROM list_members m JOIN users u ON u.id_user = m.id_user WHERE m.id_list = ? AND m.status = 1 AND u.status = 1 ORDER BY u.name ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(id)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	members := make([]map[string]any, 0)
+
+	for rows.Next() {
+		var userId int64
+		var name, mail, role string
+		var image sql.NullString
+
+		if err := rows.Scan(&userId, &name, &mail, &image, &role); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		members = append(members, map[string]any{
+			"id":        userId,
+			"name":      name,
+			"mail":      mail,
+			"image_url": image.String,
+			"role":      role,
+		})
+	}
+
+	return members, nil
+}
+
+func countOwners(id int64, db *sql.DB) (int, error) {
+	count := -1
+	err := db.QueryRow("SELECT COUNT(*) FROM list_members WHERE id_list = ? AND role = ? AND status = 1;", id, ROLE_OWNER).Scan(&count)
+
+	return count, err
+}
+
+// A list always keeps an owner, the last one can't leave nor be demoted
+func (l *List) SetMemberRole(id int64, userId int64, role string, db *sql.DB) error {
+	if err := CheckListRole(id, l.CreatedBy, ROLE_OWNER, db); err != nil {
+		return err
+	}
+
+	current, err := MemberRole(id, userId, db)
+
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		return locales.New("member_not_found")
+	}
+
+	if current == ROLE_OWNER && role != ROLE_OWNER {
+		if owners, err := countOwners(id, db); err != nil || owners <= 1 {
+			return locales.New("list_needs_owner")
+		}
+	}
+
+	stm, err := db.Prepare("UPDATE list_members SET role = ? WHERE id_list = ? AND id_user = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	if _, err := stm.Exec(role, id, userId); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+// Owners remove members and anyone can leave, the to dos assigned to them are unassigned
+func (l *List) RemoveMember(id int64, userId int64, db *sql.DB) error {
+	needed := ROLE_OWNER
+	if userId == l.CreatedBy {
+		needed = ROLE_VIEWER
+	}
+
+	if err := CheckListRole(id, l.CreatedBy, needed, db); err != nil {
+		return err
+	}
+
+	current, err := MemberRole(id, userId, db)
+
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		return locales.New("member_not_found")
+	}
+
+	if current == ROLE_OWNER {
+		if owners, err := countOwners(id, db); err != nil || owners <= 1 {
+			return locales.New("list_needs_owner")
+		}
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE list_members SET status = 0 WHERE id_list = ? AND id_user = ? LIMIT 1;", id, userId); err != nil {
+		return locales.New("internal_error")
+	}
+
+	if _, err := tx.Exec("UPDATE todos SET assigned_to = NULL WHERE id_list = ? AND assigned_to = ?;", id, userId); err != nil {
+		return locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+func (i *ListInvite) Validate() bool {
+	i.Mail = strings.ToLower(strings.TrimSpace(i.Mail))
+	valid, err := validate(mailRegex, i.Mail)
+
+	return valid && err == nil && IsValidRole(i.Role)
+}
+
+// Invites a mail to the list, the user with that mail sees it among their invitations
+func (i *ListInvite) InsertInvite(db *sql.DB) (int64, error) {
+	if err := CheckListRole(i.List, i.InvitedBy, ROLE_OWNER, db); err != nil {
+		return -1, err
+	}
+
+	member := 0
+	err := db.QueryRow("SELECT COUNT(*) FROM list_members m JOIN users u ON u.id_user = m.id_user WHERE m.id_list = ? AND m.status = 1 AND u.mail = ? AND u.status = 1;", i.List, i.Mail).Scan(&member)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if member > 0 {
+		return -1, locales.New("already_member")
+	}
+
+	members, pending := 0, 0
+	err = db.QueryRow("SELECT (SELECT COUNT(*) FROM list_members WHERE id_list = ? AND status = 1), "+
+		"(SELECT COUNT(*) FROM list_invites WHERE id_list = ? AND status = 1 AND mail = ?);", i.List, i.List, i.Mail).Scan(&members, &pending)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if pending > 0 {
+		return -1, locales.New("invite_sent")
+	}
+
+	if members >= MAX_MEMBERS_PER_LIST {
+		return -1, locales.New("members_limit")
+	}
+
+	stm, err := db.Prepare("INSERT INTO list_invites (id_list, mail, role, invited_by) VALUES ( ?, ?, ?, ? );")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(i.List, i.Mail, i.Role, i.InvitedBy)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+// Pending invitations of the list, only for its owners
+func (l *List) GetInvites(id int64, db *sql.DB) ([]map[string]any, error) {
+	if err := CheckListRole(id, l.CreatedBy, ROLE_OWNER, db); err != nil {
+		return nil, err
+	}
+
+	return queryInvites(db, "i.id_list = ?", id)
+}
+
+// Pending invitations sent to the mail of the user
+func GetUserInvites(userId int64, db *sql.DB) ([]map[string]any, error) {
+	user := UserDTO{}
+
+	if err := user.GetUserById(userId, db); err != nil {
+		return nil, locales.New("invalid_user")
+	}
+
+	return queryInvites(db, "i.mail = ?", strings.ToLower(user.Mail))
+}
+
+func queryInvites(db *sql.DB, condition string, args ...any) ([]map[string]any, error) {
+	stm, err := db.Prepare("SELECT i.id_invite, i.id_list, l.title, i.mail, i.role, i.invited_by, u.name, i.created_at " +
+		"FROM list_invites i JOIN lists l ON l.id_list = i.id_list JOIN users u ON u.id_user = i.invited_by " +
+		"WHERE " + condition + " AND i.status = 1 AND l.status = 1 ORDER BY i.created_at DESC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(args...)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	invites := make([]map[string]any, 0)
+
+	for rows.Next() {
+		invite := ListInvite{}
+		var id int64
+		var title, inviter string
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &invite.List, &title, &invite.Mail, &invite.Role, &invite.InvitedBy, &inviter, &createdAt); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		invites = append(invites, map[string]any{
+			"id":              id,
+			"list":            invite.List,
+			"list_title":      title,
+			"mail":            invite.Mail,
+			"role":            invite.Role,
+			"invited_by":      invite.InvitedBy,
+			"invited_by_name": inviter,
+			"created_at":      createdAt,
+		})
+	}
+
+	return invites, nil
+}
+
+func (l *List) CancelInvite(id int64, inviteId int64, db *sql.DB) error {
+	if err := CheckListRole(id, l.CreatedBy, ROLE_OWNER, db); err != nil {
+		return err
+	}
+
+	stm, err := db.Prepare("UPDATE list_invites SET status = 0 WHERE id_invite = ? AND id_list = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(inviteId, id)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("invite_not_found")
+	}
+
+	return nil
+}
+
+// Accepts or declines an invitation sent to the mail of the user, accepting makes them a member
+func AnswerInvite(inviteId int64, userId int64, accept bool, db *sql.DB) (int64, error) {
+	user := UserDTO{}
+
+	if err := user.GetUserById(userId, db); err != nil {
+		return 0, locales.New("invalid_user")
+	}
+
+	invite := ListInvite{}
+	row := db.QueryRow("SELECT i.id_list, i.role FROM list_invites i JOIN lists l ON l.id_list = i.id_list WHERE i.id_invite = ? AND i.mail = ? AND i.status = 1 AND l.status = 1 LIMIT 1;", inviteId, strings.ToLower(user.Mail))
+
+	if err := row.Scan(&invite.List, &invite.Role); err != nil {
+		return 0, locales.New("invite_not_found")
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE list_invites SET status = 0, accepted = ?, answered_at = now() WHERE id_invite = ? AND status = 1 LIMIT 1;", accept, inviteId)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return 0, locales.New("invite_not_found")
+	}
+
+	// A member that left or was removed comes back with the new role
+	if accept {
+		_, err = tx.Exec("INSERT INTO list_members (id_list, id_user, role) VALUES ( ?, ?, ? ) ON DUPLICATE KEY UPDATE role = VALUES(role), status = 1;", invite.List, userId, invite.Role)
+
+		if err != nil {
+			return 0, locales.New("internal_error")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	return invite.List, nil
+}
+
+// Lets a member of the list change one of its to dos on behalf of its creator
+func (t *ToDo) ActAsListMember(listId int64, todoId int64, actor int64, needed string, db *sql.DB) error {
+	if err := CheckListRole(listId, actor, needed, db); err != nil {
+		return err
+	}
+
+	var owner int64
+	row := db.QueryRow("SELECT created_by FROM todos WHERE id_todo = ? AND id_list = ? AND status = 1 LIMIT 1;", todoId, listId)
+
+	if err := row.Scan(&owner); err != nil {
+		return locales.New("todo_not_found")
+	}
+
+	t.CreatedBy = owner
+	t.Actor = 0
+
+	if actor != owner {
+		t.Actor = actor
+	}
+
+	return nil
+}
+
+// Assigns the to do to a member of its list, 0 leaves it unassigned
+func (t *ToDo) AssignToDo(id int64, assignee int64, db *sql.DB) error {
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return err
+	}
+
+	if current.List == 0 {
+		return locales.New("todo_not_in_list")
+	}
+
+	if assignee != 0 {
+		if role, err := MemberRole(current.List, assignee, db); role == "" || err != nil {
+			return locales.New("member_not_found")
+		}
+	}
+
+	stm, err := db.Prepare("UPDATE todos SET assigned_to = NULLIF(?, 0), updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	if _, err := stm.Exec(assignee, id, t.CreatedBy); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return t.recordState(db, id, REVISION_ASSIGNED, "assigned_to", current.AssignedTo, assignee, &current)
+}
+
+// Puts a to do of the user in a list where they are an editor, or takes it out with 0
+func (t *ToDo) SetList(id int64, listId int64, db *sql.DB) error {
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return err
+	}
+
+	if listId != 0 {
+		if current.Workspace != 0 {
+			return locales.New("todo_in_workspace")
+		}
+
+		if err := CheckListRole(listId, t.CreatedBy, ROLE_EDITOR, db); err != nil {
+			return err
+		}
+	}
+
+	stm, err := db.Prepare("UPDATE todos SET id_list = NULLIF(?, 0), assigned_to = NULL, updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	if _, err := stm.Exec(listId, id, t.CreatedBy); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return t.recordState(db, id, REVISION_MOVED, "list", current.List, listId, &current)
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Opens an empty database next to the one of NAILIT_TEST_DSN for the migrations to run on
+func migrationsDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("NAILIT_TEST_DSN")
+
+	if dsn == "" {
+		t.Skip("NAILIT_TEST_DSN is not set")
+	}
+
+	cfg, err := mysql.ParseDSN(dsn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := cfg.DBName + "_migrations"
+	cfg.DBName = ""
+	server, err := sql.Open("mysql", cfg.FormatDSN())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, statement := range []string{"DROP DATABASE IF EXISTS " + name + ";", "CREATE DATABASE " + name + ";"} {
+		if _, err := server.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg.DBName = name
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// Runs the up migrations with a version from from to to, both included, in order
+func migrate(t *testing.T, db *sql.DB, from int, to int) {
+	t.Helper()
+
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations: %v", err)
+	}
+
+	slices.Sort(files)
+
+	for _, file := range files {
+		version, err := strconv.Atoi(strings.SplitN(filepath.Base(file), "_", 2)[0])
+
+		if err != nil {
+			t.Fatalf("%s has no version: %v", file, err)
+		}
+
+		if version >= from && version <= to {
+			execSQLFile(t, db, file)
+		}
+	}
+}
+
+// Columns and indexes of every table of the current database, a line each
+func describeSchema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	lines := make([]string, 0)
+	rows, err := db.Query("SELECT table_name, column_name, ordinal_position, column_type, is_nullable, COALESCE(column_default, 'NULL'), column_key = 'PRI' " +
+		"FROM information_schema.columns WHERE table_schema = DATABASE() ORDER BY table_name, ordinal_position;")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rows.Next() {
+		var table, column, position, kind, nullable, def string
+		var primary bool
+		if err := rows.Scan(&table, &column, &position, &kind, &nullable, &def, &primary); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, fmt.Sprintf("%s column %s %s %s null=%s default=%s primary=%t", table, position, column, kind, nullable, def, primary))
+	}
+	rows.Close()
+
+	// The names of the indexes depend on how they were added, only their columns count.
+	// The primary keys are already marked on their columns
+	rows, err = db.Query("SELECT table_name, index_name, column_name, non_unique FROM information_schema.statistics " +
+		"WHERE table_schema = DATABASE() AND index_name != 'PRIMARY' ORDER BY table_name, index_name, seq_in_index;")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexes := make(map[string][]string)
+
+	for rows.Next() {
+		var table, index, column, nonUnique string
+		if err := rows.Scan(&table, &index, &column, &nonUnique); err != nil {
+			t.Fatal(err)
+		}
+		key := table + " " + index + " " + nonUnique
+		indexes[key] = append(indexes[key], column)
+	}
+	rows.Close()
+
+	for key, columns := range indexes {
+		parts := strings.Fields(key)
+		lines = append(lines, fmt.Sprintf("%s index (%s) non_unique=%s", parts[0], strings.Join(columns, ", "), parts[2]))
+	}
+
+	slices.Sort(lines)
+	return lines
+}
+
+func TestMigrationsBuildTheTestSchema(t *testing.T) {
+	db := migrationsDB(t)
+	migrate(t, db, 0, 999)
+
+	got, want := describeSchema(t, db), describeSchema(t, testDB(t))
+
+	for _, line := range want {
+		if !slices.Contains(got, line) {
+			t.Errorf("the migrations miss: %s", line)
+		}
+	}
+
+	for _, line := range got {
+		if !slices.Contains(want, line) {
+			t.Errorf("schema.sql misses: %s", line)
+		}
+	}
+}
+
+func TestMigrationsBackfill(t *testing.T) {
+	db := migrationsDB(t)
+	migrate(t, db, 0, 34)
+
+	// Rows as they were before the manual order, multiple tags and the trash
+	changed := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, statement := range []string{
+		"INSERT INTO tags (id_tag, title, created_by) VALUES (1, 'work', 1);",
+		"INSERT INTO tags (id_tag, title, created_by, status, updated_at) VALUES (2, 'old', 1, 0, '2026-05-01 10:00:00');",
+		"INSERT INTO todos (id_todo, title, deadline, tag, created_by) VALUES (1, 'later', '2026-06-02', 1, 1), (2, 'sooner', '2026-06-01', 0, 1), (3, 'other', '2026-06-03', 0, 2);",
+		"INSERT INTO todos (id_todo, title, deadline, created_by, status, updated_at) VALUES (4, 'deleted', '2026-05-30', 1, 0, '2026-05-01 10:00:00');",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrate(t, db, 35, 999)
+
+	// The order of every user follows the deadlines, from 1
+	rows, err := db.Query("SELECT id_todo, position FROM todos ORDER BY id_todo;")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	positions := make(map[int64]float64)
+	for rows.Next() {
+		var id int64
+		var position float64
+		if err := rows.Scan(&id, &position); err != nil {
+			t.Fatal(err)
+		}
+		positions[id] = position
+	}
+	rows.Close()
+
+	for id, want := range map[int64]float64{1: 3, 2: 2, 3: 1, 4: 1} {
+		if positions[id] != want {
+			t.Errorf("position of %d = %v, want %v", id, positions[id], want)
+		}
+	}
+
+	var tag int64
+	if err := db.QueryRow("SELECT id_tag FROM todo_tags WHERE id_todo = 1;").Scan(&tag); err != nil || tag != 1 {
+		t.Errorf("todo_tags of 1 = %d, %v, want 1", tag, err)
+	}
+
+	var tagged int
+	if err := db.QueryRow("SELECT COUNT(*) FROM todo_tags;").Scan(&tagged); err != nil || tagged != 1 {
+		t.Errorf("%d todo_tags rows, %v, want 1", tagged, err)
+	}
+
+	for _, table := range []string{"todos", "tags"} {
+		var deletedAt time.Time
+		if err := db.QueryRow("SELECT deleted_at FROM " + table + " WHERE status = 0;").Scan(&deletedAt); err != nil || !deletedAt.Equal(changed) {
+			t.Errorf("deleted_at in %s = %v, %v, want %v", table, deletedAt, err, changed)
+		}
+	}
+}
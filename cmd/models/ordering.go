@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Manual order kept in a float column. A moved row gets a position between its new
+// neighbours so it is the only one written, and when the gap between them is too
+// small for a float the whole order of the scope is spread again
+type ordering struct {
+	table    string
+	id       string
+	position string
+	// Condition of the rows ordered together, with its args
+	scope string
+	args  []any
+}
+
+// Moves the row right after the row after, or to the top when after is 0
+func (o ordering) move(id int64, after int64, tx *sql.Tx) (float64, error) {
+	position, ok, err := o.positionAfter(id, after, tx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		if err := o.renumber(tx); err != nil {
+			return 0, err
+		}
+
+		if position, ok, err = o.positionAfter(id, after, tx); err != nil || !ok {
+			return 0, locales.New("internal_error")
+		}
+	}
+
+	return position, nil
+}
+
+// Position between after and the row that follows it, false when there is no room left
+// or after shares its position with another row. Any float is a valid position, 0 and
+// negatives included
+func (o ordering) positionAfter(id int64, after int64, tx *sql.Tx) (float64, bool, error) {
+	var prev, next sql.NullFloat64
+	from := " FROM " + o.table + " WHERE " + o.scope + " AND " + o.id + " != ?"
+	args := append(append([]any{}, o.args...), id)
+
+	if after != 0 {
+		row := tx.QueryRow("SELECT "+o.position+" FROM "+o.table+" WHERE "+o.id+" = ? AND "+o.scope+" LIMIT 1 FOR UPDATE;", append([]any{after}, o.args...)...)
+
+		if err := row.Scan(&prev); err != nil || after == id || !prev.Valid {
+			return 0, false, locales.New("invalid_order")
+		}
+
+		// Rows tied with after, like the ones from before the manual order that are all
+		// at 0, are ordered by id and leave no room after it until the scope is spread
+		var tied int
+		row = tx.QueryRow("SELECT COUNT(*)"+from+" AND "+o.id+" != ? AND "+o.position+" = ?;", append(args, after, prev.Float64)...)
+
+		if err := row.Scan(&tied); err != nil {
+			return 0, false, locales.New("internal_error")
+		}
+
+		if tied != 0 {
+			return 0, false, nil
+		}
+
+		row = tx.QueryRow("SELECT MIN("+o.position+")"+from+" AND "+o.position+" > ?;", append(args, prev.Float64)...)
+
+		if err := row.Scan(&next); err != nil {
+			return 0, false, locales.New("internal_error")
+		}
+	} else {
+		row := tx.QueryRow("SELECT MIN("+o.position+")"+from+";", args...)
+
+		if err := row.Scan(&next); err != nil {
+			return 0, false, locales.New("internal_error")
+		}
+	}
+
+	switch {
+	case !next.Valid && !prev.Valid:
+		return 1, true, nil
+	case !next.Valid:
+		return prev.Float64 + 1, true, nil
+	case !prev.Valid:
+		return next.Float64 - 1, true, nil
+	}
+
+	position := (prev.Float64 + next.Float64) / 2
+
+	if position <= prev.Float64 || position >= next.Float64 || next.Float64-prev.Float64 < 1e-9 {
+		return 0, false, nil
+	}
+
+	return position, true, nil
+}
+
+func (o ordering) renumber(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT "+o.id+" FROM "+o.table+" WHERE "+o.scope+" ORDER BY "+o.position+" ASC, "+o.id+" ASC FOR UPDATE;", o.args...)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	ids := make([]int64, 0)
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return locales.New("internal_error")
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for i, id := range ids {
+		if _, err := tx.Exec("UPDATE "+o.table+" SET "+o.position+" = ? WHERE "+o.id+" = ? LIMIT 1;", i+1, id); err != nil {
+			return locales.New("internal_error")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+// Ids of the rows of the ordering from first to last
+func orderedIds(t *testing.T, db *sql.DB, o ordering) []int64 {
+	t.Helper()
+
+	rows, err := db.Query("SELECT "+o.id+" FROM "+o.table+" WHERE "+o.scope+" ORDER BY "+o.position+" ASC, "+o.id+" ASC;", o.args...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func TestMoveToDoToTop(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	todo := ToDo{CreatedBy: user}
+
+	// The positions start at 1, so the top is at 0 and then below it
+	first := insertTestToDo(t, db, ToDo{Title: "first", CreatedBy: user})
+	second := insertTestToDo(t, db, ToDo{Title: "second", CreatedBy: user})
+	third := insertTestToDo(t, db, ToDo{Title: "third", CreatedBy: user})
+
+	position, err := todo.MoveToDo(third, 0, db)
+
+	if err != nil {
+		t.Fatalf("MoveToDo to the top: %v", err)
+	}
+
+	if position != 0 {
+		t.Errorf("position = %v, want 0", position)
+	}
+
+	if got, want := orderedIds(t, db, todo.manualOrder()), []int64{third, first, second}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+
+	if _, err := todo.MoveToDo(second, 0, db); err != nil {
+		t.Fatalf("MoveToDo to the top again: %v", err)
+	}
+
+	if _, err := todo.MoveToDo(first, second, db); err != nil {
+		t.Fatalf("MoveToDo after the top one: %v", err)
+	}
+
+	if got, want := orderedIds(t, db, todo.manualOrder()), []int64{second, first, third}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestMoveToDoRenumbers(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	todo := ToDo{CreatedBy: user}
+
+	first := insertTestToDo(t, db, ToDo{Title: "first", CreatedBy: user})
+	second := insertTestToDo(t, db, ToDo{Title: "second", CreatedBy: user})
+	third := insertTestToDo(t, db, ToDo{Title: "third", CreatedBy: user})
+
+	// No float fits between the first two, the order is spread again from 1
+	if _, err := db.Exec("UPDATE todos SET position = ? WHERE id_todo = ?;", 1+1e-12, second); err != nil {
+		t.Fatal(err)
+	}
+
+	position, err := todo.MoveToDo(third, first, db)
+
+	if err != nil {
+		t.Fatalf("MoveToDo with no room: %v", err)
+	}
+
+	if position <= 1 || position >= 2 {
+		t.Errorf("position = %v, want between 1 and 2", position)
+	}
+
+	if got, want := orderedIds(t, db, todo.manualOrder()), []int64{first, third, second}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestMoveToDoAfterTiedRows(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "mover")
+	todo := ToDo{CreatedBy: user}
+
+	first := insertTestToDo(t, db, ToDo{Title: "first", CreatedBy: user})
+	second := insertTestToDo(t, db, ToDo{Title: "second", CreatedBy: user})
+	third := insertTestToDo(t, db, ToDo{Title: "third", CreatedBy: user})
+	fourth := insertTestToDo(t, db, ToDo{Title: "fourth", CreatedBy: user})
+
+	// The to dos from before the manual order are all at 0, ordered by id
+	if _, err := db.Exec("UPDATE todos SET position = 0 WHERE created_by = ? AND id_todo != ?;", user, fourth); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := todo.MoveToDo(fourth, first, db); err != nil {
+		t.Fatalf("MoveToDo after a tied row: %v", err)
+	}
+
+	if got, want := orderedIds(t, db, todo.manualOrder()), []int64{first, fourth, second, third}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
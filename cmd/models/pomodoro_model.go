@@ -0,0 +1,355 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// A pomodoro session in pomodoro_sessions is a work interval on a to do followed by
+// its break. The time worked is elapsed_seconds plus the time since resumed_at while it
+// runs, so pausing only writes the session. Each user has one active session at most
+const (
+	POMODORO_RUNNING  = "running"
+	POMODORO_PAUSED   = "paused"
+	POMODORO_FINISHED = "finished"
+
+	POMODORO_PHASE_WORK  = "work"
+	POMODORO_PHASE_BREAK = "break"
+	POMODORO_PHASE_DONE  = "done"
+
+	DEFAULT_POMODORO_WORK  = 25
+	DEFAULT_POMODORO_BREAK = 5
+	MAX_POMODORO_WORK      = 120
+	MAX_POMODORO_BREAK     = 60
+)
+
+type Pomodoro struct {
+	ToDo int64 `json:"todo"`
+	// Lengths in minutes, 0 uses the defaults
+	WorkMinutes  int   `json:"work_minutes"`
+	BreakMinutes int   `json:"break_minutes"`
+	CreatedBy    int64 `json:"created_by"`
+}
+
+func (p *Pomodoro) ValidatePomodoro() bool {
+	if p.WorkMinutes == 0 {
+		p.WorkMinutes = DEFAULT_POMODORO_WORK
+	}
+
+	if p.BreakMinutes == 0 {
+		p.BreakMinutes = DEFAULT_POMODORO_BREAK
+	}
+
+	return p.ToDo > 0 && p.WorkMinutes > 0 && p.WorkMinutes <= MAX_POMODORO_WORK && p.BreakMinutes > 0 && p.BreakMinutes <= MAX_POMODORO_BREAK
+}
+
+// Seconds of the session so far, work and break together, until the time bound to it.
+// The sessions are written in UTC, so it is time.Now().UTC() and not now(), which is in
+// the zone of the database
+const pomodoroElapsed = "elapsed_seconds + COALESCE(TIMESTAMPDIFF(SECOND, resumed_at, ?), 0)"
+
+// Seconds of the session so far, no more than the work and the break
+const pomodoroElapsedCapped = "LEAST(" + pomodoroElapsed + ", (work_minutes + break_minutes) * 60)"
+
+// Finishes the sessions of the user that ran past their break, as done and completed
+// at the moment the break ended. Paused ones end when they were paused
+func finishOverrunPomodoros(userId int64, db execer) error {
+	now := time.Now().UTC()
+	_, err := db.Exec("UPDATE pomodoro_sessions SET "+
+		"finished_at = COALESCE(DATE_ADD(resumed_at, INTERVAL (work_minutes + break_minutes) * 60 - elapsed_seconds SECOND), ?), "+
+		"state = ?, elapsed_seconds = (work_minutes + break_minutes) * 60, resumed_at = NULL, completed = 1 "+
+		"WHERE created_by = ? AND state != ? AND "+pomodoroElapsed+" >= (work_minutes + break_minutes) * 60;",
+		now, POMODORO_FINISHED, userId, POMODORO_FINISHED, now)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+// Starts a session on a to do the user can see, fails while another one is active
+func (p *Pomodoro) StartPomodoro(db *sql.DB) (int64, error) {
+	if err := CheckToDoAccess(p.ToDo, p.CreatedBy, db); err != nil {
+		return -1, err
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	// The same lock as the timers keeps two sessions from starting at once
+	if err := lockTimer(p.CreatedBy, tx); err != nil {
+		return -1, err
+	}
+
+	if err := finishOverrunPomodoros(p.CreatedBy, tx); err != nil {
+		return -1, err
+	}
+
+	count := 0
+	err = tx.QueryRow("SELECT COUNT(*) FROM pomodoro_sessions WHERE created_by = ? AND state != ?;", p.CreatedBy, POMODORO_FINISHED).Scan(&count)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count > 0 {
+		return -1, locales.New("pomodoro_running")
+	}
+
+	now := time.Now().UTC()
+	res, err := tx.Exec("INSERT INTO pomodoro_sessions (id_todo, created_by, work_minutes, break_minutes, state, elapsed_seconds, resumed_at, started_at) "+
+		"VALUES ( ?, ?, ?, ?, ?, 0, ?, ? );", p.ToDo, p.CreatedBy, p.WorkMinutes, p.BreakMinutes, POMODORO_RUNNING, now, now)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return id, nil
+}
+
+// Moves the session of the user to the next state. Finishing counts it as a completed
+// pomodoro when the whole work interval was done
+func (p *Pomodoro) SetPomodoroState(id int64, state string, db *sql.DB) error {
+	var query string
+	var set, from []any
+	now := time.Now().UTC()
+
+	switch state {
+	case POMODORO_PAUSED:
+		query = "UPDATE pomodoro_sessions SET state = ?, elapsed_seconds = " + pomodoroElapsedCapped + ", resumed_at = NULL"
+		set = []any{state, now}
+		from = []any{POMODORO_RUNNING, POMODORO_RUNNING}
+	case POMODORO_RUNNING:
+		query = "UPDATE pomodoro_sessions SET state = ?, resumed_at = ?"
+		set = []any{state, now}
+		from = []any{POMODORO_PAUSED, POMODORO_PAUSED}
+	case POMODORO_FINISHED:
+		query = "UPDATE pomodoro_sessions SET state = ?, elapsed_seconds = " + pomodoroElapsedCapped + ", resumed_at = NULL, finished_at = ?, " +
+			"completed = (elapsed_seconds >= work_minutes * 60)"
+		set = []any{state, now, now}
+		from = []any{POMODORO_RUNNING, POMODORO_PAUSED}
+	default:
+		return locales.New("invalid_state")
+	}
+
+	// MySQL assigns from left to right, elapsed_seconds is read after it was updated
+	args := append(append(set, id, p.CreatedBy), from...)
+	res, err := db.Exec(query+" WHERE id_session = ? AND created_by = ? AND state IN (?, ?) LIMIT 1;", args...)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("pomodoro_not_found")
+	}
+
+	return nil
+}
+
+// The active session of the user with its phase and the seconds left of it, ok is
+// false when there is none. A session past its break is finished first
+func (p *Pomodoro) GetCurrentPomodoro(db *sql.DB) (map[string]any, bool, error) {
+	user := UserDTO{}
+
+	if active, err := user.VerifyUserIdIsActive(int(p.CreatedBy), db); !active || err != nil {
+		return nil, false, locales.New("invalid_user")
+	}
+
+	if err := finishOverrunPomodoros(p.CreatedBy, db); err != nil {
+		return nil, false, err
+	}
+
+	row := db.QueryRow("SELECT id_session FROM pomodoro_sessions WHERE created_by = ? AND state != ? LIMIT 1;", p.CreatedBy, POMODORO_FINISHED)
+	var id int64
+
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+
+		return nil, false, locales.New("internal_error")
+	}
+
+	session, err := p.GetPomodoroById(id, db)
+
+	return session, err == nil, err
+}
+
+func (p *Pomodoro) GetPomodoroById(id int64, db *sql.DB) (map[string]any, error) {
+	row := db.QueryRow("SELECT s.id_todo, t.title, s.work_minutes, s.break_minutes, s.state, "+pomodoroElapsed+", s.started_at, s.finished_at, s.completed "+
+		"FROM pomodoro_sessions s JOIN todos t ON t.id_todo = s.id_todo WHERE s.id_session = ? AND s.created_by = ? LIMIT 1;", time.Now().UTC(), id, p.CreatedBy)
+
+	session := Pomodoro{CreatedBy: p.CreatedBy}
+	var title, state string
+	var elapsed int64
+	var startedAt time.Time
+	var finishedAt sql.NullTime
+	var completed bool
+
+	err := row.Scan(&session.ToDo, &title, &session.WorkMinutes, &session.BreakMinutes, &state, &elapsed, &startedAt, &finishedAt, &completed)
+
+	if err != nil {
+		return nil, locales.New("pomodoro_not_found")
+	}
+
+	work := int64(session.WorkMinutes) * 60
+	rest := int64(session.BreakMinutes) * 60
+	phase, left := POMODORO_PHASE_WORK, work-elapsed
+
+	if elapsed >= work {
+		phase, left = POMODORO_PHASE_BREAK, work+rest-elapsed
+	}
+
+	if elapsed >= work+rest || state == POMODORO_FINISHED {
+		phase, left = POMODORO_PHASE_DONE, 0
+	}
+
+	var finished *time.Time
+	if finishedAt.Valid {
+		finished = &finishedAt.Time
+	}
+
+	return map[string]any{
+		"id":              id,
+		"todo":            session.ToDo,
+		"todo_title":      title,
+		"work_minutes":    session.WorkMinutes,
+		"break_minutes":   session.BreakMinutes,
+		"state":           state,
+		"phase":           phase,
+		"elapsed_seconds": elapsed,
+		"seconds_left":    left,
+		"started_at":      startedAt,
+		"finished_at":     finished,
+		"completed":       completed,
+	}, nil
+}
+
+// Completed pomodoros of the user in the range by day or week, and by tag. A pomodoro
+// counts for the period it started in and for every tag of its to do
+func GetPomodoroStats(userId int64, filter TimeReportFilter, db *sql.DB) (map[string]any, error) {
+	user := UserDTO{}
+
+	if active, err := user.VerifyUserIdIsActive(int(userId), db); !active || err != nil {
+		return nil, locales.New("invalid_user")
+	}
+
+	rows, err := db.Query("SELECT s.started_at, s.work_minutes, (SELECT GROUP_CONCAT(tt.id_tag ORDER BY tt.id_tag) FROM todo_tags tt WHERE tt.id_todo = s.id_todo) "+
+		"FROM pomodoro_sessions s WHERE s.created_by = ? AND s.completed = 1 AND s.started_at >= ? AND s.started_at < ? ORDER BY s.started_at ASC;",
+		userId, filter.From, filter.To)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	periods := make([]map[string]any, 0)
+	byPeriod := map[string]map[string]any{}
+	byTag := map[int64]int{}
+	total := 0
+
+	for rows.Next() {
+		var startedAt time.Time
+		var minutes int
+		var tags sql.NullString
+
+		if err := rows.Scan(&startedAt, &minutes, &tags); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		key := filter.periodOf(startedAt)
+		period, ok := byPeriod[key]
+
+		if !ok {
+			period = map[string]any{
+				"period":       key,
+				"completed":    0,
+				"work_minutes": 0,
+			}
+			byPeriod[key] = period
+			periods = append(periods, period)
+		}
+
+		period["completed"] = period["completed"].(int) + 1
+		period["work_minutes"] = period["work_minutes"].(int) + minutes
+		total++
+
+		for _, tag := range parseIdList(tags) {
+			byTag[tag]++
+		}
+	}
+
+	tags, err := pomodoroTags(byTag, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"completed": total,
+		"periods":   periods,
+		"tags":      tags,
+	}, nil
+}
+
+// The active tags among the counted ones with their count, the most used first
+func pomodoroTags(counts map[int64]int, db *sql.DB) ([]map[string]any, error) {
+	tags := make([]map[string]any, 0)
+
+	if len(counts) == 0 {
+		return tags, nil
+	}
+
+	args := make([]any, 0, len(counts))
+	for tag := range counts {
+		args = append(args, tag)
+	}
+
+	rows, err := db.Query("SELECT id_tag, title FROM tags WHERE status = 1 AND id_tag IN ("+placeholders(len(args))+") ORDER BY id_tag ASC;", args...)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var title string
+
+		if err := rows.Scan(&id, &title); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		tags = append(tags, map[string]any{
+			"id":        id,
+			"title":     title,
+			"completed": counts[id],
+		})
+	}
+
+	slices.SortStableFunc(tags, func(a, b map[string]any) int {
+		return b["completed"].(int) - a["completed"].(int)
+	})
+
+	return tags, nil
+}
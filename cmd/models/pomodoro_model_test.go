@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Inserts a running session of 25 and 5 minutes resumed the given minutes ago
+func insertRunningPomodoro(t *testing.T, db *sql.DB, user int64, todo int64, minutes int) int64 {
+	t.Helper()
+
+	started := time.Now().UTC().Add(-time.Duration(minutes) * time.Minute)
+	res, err := db.Exec("INSERT INTO pomodoro_sessions (id_todo, created_by, work_minutes, break_minutes, state, elapsed_seconds, resumed_at, started_at) "+
+		"VALUES ( ?, ?, 25, 5, ?, 0, ?, ? );", todo, user, POMODORO_RUNNING, started, started)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := res.LastInsertId()
+	return id
+}
+
+func TestPomodoroPastItsBreakIsFinished(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes int
+		current bool
+		err     string
+	}{
+		{"in the work interval", 10, true, "pomodoro_running"},
+		{"in the break", 27, true, "pomodoro_running"},
+		{"past the break", 40, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := testDB(t)
+			user := insertTestUser(t, db, "focused")
+			todo := insertTestToDo(t, db, ToDo{Title: "write", CreatedBy: user})
+			id := insertRunningPomodoro(t, db, user, todo, test.minutes)
+			pomodoro := Pomodoro{ToDo: todo, WorkMinutes: 25, BreakMinutes: 5, CreatedBy: user}
+
+			_, ok, err := pomodoro.GetCurrentPomodoro(db)
+
+			if err != nil || ok != test.current {
+				t.Fatalf("GetCurrentPomodoro ok = %v, %v, want %v", ok, err, test.current)
+			}
+
+			if _, err := pomodoro.StartPomodoro(db); locales.CodeOf(err) != test.err {
+				t.Fatalf("StartPomodoro = %v, want %q", err, test.err)
+			}
+
+			if test.current {
+				return
+			}
+
+			session, err := pomodoro.GetPomodoroById(id, db)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if session["state"] != POMODORO_FINISHED || session["completed"] != true || session["elapsed_seconds"] != int64(30*60) {
+				t.Errorf("session = %v, want it finished and completed after 30 minutes", session)
+			}
+
+			var minutes int
+			if err := db.QueryRow("SELECT TIMESTAMPDIFF(MINUTE, started_at, finished_at) FROM pomodoro_sessions WHERE id_session = ?;", id).Scan(&minutes); err != nil {
+				t.Fatal(err)
+			}
+
+			if minutes != 30 {
+				t.Errorf("finished %d minutes after it started, want 30", minutes)
+			}
+		})
+	}
+}
+
+func TestPomodoroFinishedLateCountsUpToItsBreak(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "focused")
+	todo := insertTestToDo(t, db, ToDo{Title: "write", CreatedBy: user})
+	id := insertRunningPomodoro(t, db, user, todo, 90)
+	pomodoro := Pomodoro{CreatedBy: user}
+
+	if err := pomodoro.SetPomodoroState(id, POMODORO_FINISHED, db); err != nil {
+		t.Fatalf("SetPomodoroState: %v", err)
+	}
+
+	session, err := pomodoro.GetPomodoroById(id, db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session["elapsed_seconds"] != int64(30*60) || session["completed"] != true {
+		t.Errorf("session = %v, want 30 minutes and completed", session)
+	}
+}
+
+// The sessions are timed with the UTC time of the api, whatever the zone of the database
+func TestPomodoroTimesAreUTC(t *testing.T) {
+	db := testDB(t)
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("SET time_zone = '-05:00';"); err != nil {
+		t.Skip("the database can't change its time zone:", err)
+	}
+
+	user := insertTestUser(t, db, "focused")
+	todo := insertTestToDo(t, db, ToDo{Title: "write", CreatedBy: user})
+	overrun := insertRunningPomodoro(t, db, user, todo, 40)
+	pomodoro := Pomodoro{ToDo: todo, WorkMinutes: 25, BreakMinutes: 5, CreatedBy: user}
+
+	if _, ok, err := pomodoro.GetCurrentPomodoro(db); ok || err != nil {
+		t.Fatalf("GetCurrentPomodoro ok = %v, %v, want the overrun session finished", ok, err)
+	}
+
+	if session, _ := pomodoro.GetPomodoroById(overrun, db); session["state"] != POMODORO_FINISHED {
+		t.Fatalf("overrun session = %v, want it finished", session)
+	}
+
+	id, err := pomodoro.StartPomodoro(db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := pomodoro.GetPomodoroById(id, db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if started := session["started_at"].(time.Time); time.Since(started).Abs() > time.Minute {
+		t.Errorf("started at %v, want about %v", started, time.Now().UTC())
+	}
+
+	if elapsed := session["elapsed_seconds"].(int64); elapsed < 0 || elapsed > 60 {
+		t.Errorf("elapsed %d seconds right after starting", elapsed)
+	}
+
+	if err := pomodoro.SetPomodoroState(id, POMODORO_PAUSED, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pomodoro.SetPomodoroState(id, POMODORO_RUNNING, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pomodoro.SetPomodoroState(id, POMODORO_FINISHED, db); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err = pomodoro.GetPomodoroById(id, db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := session["elapsed_seconds"].(int64); elapsed < 0 || elapsed > 60 || session["completed"] != false {
+		t.Errorf("session = %v, want a few seconds and not completed", session)
+	}
+
+	if finished := session["finished_at"].(*time.Time); finished == nil || time.Since(*finished).Abs() > time.Minute {
+		t.Errorf("finished at %v, want about %v", finished, time.Now().UTC())
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/quickadd"
+)
+
+// A line of text to turn into a to do, see the quickadd package
+type QuickAdd struct {
+	Text string `json:"text"`
+	// IANA zone of the user, the dates of the text are read in it
+	Timezone  string `json:"timezone"`
+	CreatedBy int64  `json:"created_by"`
+}
+
+// What the text was read as, nothing is stored until the to do is created
+type QuickAddResult struct {
+	ToDo ToDo
+	// The text had a date or a time, otherwise the deadline is the end of today
+	Dated bool
+	// #names that matched no tag of the user
+	UnknownTags []string
+}
+
+// Tags are matched by title ignoring case, spaces, _ and -, so #deep_work finds "Deep work"
+func tagKey(title string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(title))
+}
+
+func (q *QuickAdd) Parse(locale string, db *sql.DB) (QuickAddResult, error) {
+	userDto := UserDTO{}
+
+	if active, err := userDto.VerifyUserIdIsActive(int(q.CreatedBy), db); !active || err != nil {
+		return QuickAddResult{}, locales.New("invalid_user")
+	}
+
+	if q.Timezone == "" {
+		q.Timezone = "UTC"
+	}
+
+	location, err := time.LoadLocation(q.Timezone)
+
+	if err != nil {
+		return QuickAddResult{}, locales.New("invalid_timezone")
+	}
+
+	parsed, err := quickadd.Parse(q.Text, locale, time.Now().In(location))
+
+	if err != nil {
+		return QuickAddResult{}, locales.New("invalid_quick_add")
+	}
+
+	result := QuickAddResult{
+		ToDo: ToDo{
+			Title:     parsed.Title,
+			Deadline:  parsed.Deadline,
+			Tags:      make([]int64, 0),
+			CreatedBy: q.CreatedBy,
+			Timezone:  q.Timezone,
+		},
+		Dated:       parsed.Dated,
+		UnknownTags: make([]string, 0),
+	}
+
+	if parsed.Color != nil {
+		result.ToDo.Color = *parsed.Color
+	}
+
+	if len(parsed.Tags) == 0 {
+		return result, nil
+	}
+
+	rows, err := db.Query("SELECT id_tag, title FROM tags WHERE created_by = ? AND id_workspace IS NULL AND status = 1;", q.CreatedBy)
+
+	if err != nil {
+		return QuickAddResult{}, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	tags := make(map[string]int64)
+
+	for rows.Next() {
+		var id int64
+		var title string
+
+		if err := rows.Scan(&id, &title); err != nil {
+			return QuickAddResult{}, locales.New("internal_error")
+		}
+
+		tags[tagKey(title)] = id
+	}
+
+	for _, name := range parsed.Tags {
+		id, ok := tags[tagKey(name)]
+
+		if !ok {
+			result.UnknownTags = append(result.UnknownTags, name)
+			continue
+		}
+
+		if !slices.Contains(result.ToDo.Tags, id) {
+			result.ToDo.Tags = append(result.ToDo.Tags, id)
+		}
+	}
+
+	if len(result.ToDo.Tags) != 0 {
+		result.ToDo.Tag = result.ToDo.Tags[0]
+	}
+
+	return result, nil
+}
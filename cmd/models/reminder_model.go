@@ -0,0 +1,290 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+const (
+	MAX_REMINDERS_PER_TODO = 5
+	// Failed deliveries are retried until this many attempts
+	MAX_REMINDER_ATTEMPTS = 5
+	// Minutes after which a claimed reminder that was not marked as sent is claimed again
+	REMINDER_CLAIM_TIMEOUT = 5
+)
+
+// Due time of a reminder, an absolute time or an offset before the deadline of its to do.
+// Offsets follow the deadline when it moves
+const reminderDueAt = "COALESCE(r.remind_at, DATE_SUB(t.deadline, INTERVAL r.offset_minutes MINUTE))"
+
+type Reminder struct {
+	RemindAt *time.Time `json:"remind_at"`
+	// Minutes before the deadline, used when RemindAt is nil
+	Offset    int        `json:"offset"`
+	Channel   string     `json:"channel"`
+	ToDo      int64      `json:"todo"`
+	CreatedBy int64      `json:"created_by"`
+	SentAt    *time.Time `json:"sent_at"`
+}
+
+// Reminder ready to be delivered, with what the notifiers need to know
+type DueReminder struct {
+	Id       int64
+	Channel  string
+	ToDo     int64
+	Title    string
+	Deadline time.Time
+	DueAt    time.Time
+	UserName string
+	UserMail string
+}
+
+func (r *Reminder) Validate() bool {
+	if r.RemindAt == nil {
+		return r.Offset >= 0 && r.Offset <= 60*24*30
+	}
+
+	return r.RemindAt.After(time.Now())
+}
+
+func (r *Reminder) CountRemindersPerToDo(db *sql.DB) (int, error) {
+	stm, err := db.Prepare("SELECT COUNT(*) FROM reminders WHERE id_todo = ? AND status = 1 AND sent_at IS NULL LIMIT 1;")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	count := -1
+	row := stm.QueryRow(r.ToDo)
+	err = row.Scan(&count)
+
+	return count, err
+}
+
+func (r *Reminder) InsertReminder(db *sql.DB) (int64, error) {
+	item := ToDoItem{ToDo: r.ToDo, CreatedBy: r.CreatedBy}
+
+	if err := item.CheckToDoIsOwned(db); err != nil {
+		return -1, err
+	}
+
+	count, err := r.CountRemindersPerToDo(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_REMINDERS_PER_TODO {
+		return -1, locales.New("reminders_limit")
+	}
+
+	stm, err := db.Prepare("INSERT INTO reminders (id_todo, remind_at, offset_minutes, channel) VALUES ( ?, ?, ?, ? );")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(r.ToDo, r.RemindAt, r.Offset, r.Channel)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *Reminder) DeleteReminderById(id int64, db *sql.DB) error {
+	item := ToDoItem{ToDo: r.ToDo, CreatedBy: r.CreatedBy}
+
+	if err := item.CheckToDoIsOwned(db); err != nil {
+		return err
+	}
+
+	stm, err := db.Prepare("UPDATE reminders SET status = 0 WHERE id_reminder = ? AND id_todo = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("reminder_delete_failed")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(id, r.ToDo)
+
+	if err != nil {
+		return locales.New("reminder_delete_failed")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("reminder_not_found")
+	}
+
+	return nil
+}
+
+func (r *Reminder) GetAllRemindersFromToDo(db *sql.DB) ([]map[string]any, error) {
+	item := ToDoItem{ToDo: r.ToDo, CreatedBy: r.CreatedBy}
+
+	if err := item.CheckToDoIsOwned(db); err != nil {
+		return nil, err
+	}
+
+	stm, err := db.Prepare("SELECT r.id_reminder, r.remind_at, r.offset_minutes, r.channel, r.sent_at, " + reminderDueAt + " FROM reminders r JOIN todos t ON t.id_todo = r.id_todo WHERE r.id_todo = ? AND r.status = 1 ORDER BY 6 ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(r.ToDo)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	reminders := make([]map[string]any, 0)
+
+	for rows.Next() {
+		var id int64 = 0
+		var remindAt, sentAt sql.NullTime
+		reminder := Reminder{ToDo: r.ToDo}
+		dueAt := time.Time{}
+
+		err = rows.Scan(&id, &remindAt, &reminder.Offset, &reminder.Channel, &sentAt, &dueAt)
+
+		if err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		if remindAt.Valid {
+			reminder.RemindAt = &remindAt.Time
+		}
+
+		if sentAt.Valid {
+			reminder.SentAt = &sentAt.Time
+		}
+
+		reminders = append(reminders, map[string]any{
+			"id":        id,
+			"remind_at": reminder.RemindAt,
+			"offset":    reminder.Offset,
+			"channel":   reminder.Channel,
+			"sent_at":   reminder.SentAt,
+			"due_at":    dueAt,
+			"todo":      reminder.ToDo,
+		})
+	}
+
+	return reminders, nil
+}
+
+// Lists the reminders that are due at now and not sent nor claimed by another worker.
+// Reminders of deleted, completed or archived to dos are skipped. now is compared with
+// the UTC times the api stores, not with the clock of the database
+func GetDueReminders(now time.Time, limit int, db *sql.DB) ([]DueReminder, error) {
+	stm, err := db.Prepare("SELECT r.id_reminder, r.channel, t.id_todo, t.title, t.deadline, " + reminderDueAt + ", u.name, u.mail " +
+		"FROM reminders r JOIN todos t ON t.id_todo = r.id_todo JOIN users u ON u.id_user = t.created_by " +
+		"WHERE r.status = 1 AND r.sent_at IS NULL AND r.attempts < ? AND (r.claimed_at IS NULL OR r.claimed_at < ?) " +
+		"AND t.status = 1 AND t.completed = 0 AND t.archived_at IS NULL AND u.status = 1 AND " + reminderDueAt + " <= ? " +
+		"ORDER BY 6 ASC LIMIT ?;")
+
+	if err != nil {
+		return nil, err
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(MAX_REMINDER_ATTEMPTS, claimExpiry(now), now, limit)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make([]DueReminder, 0)
+
+	for rows.Next() {
+		r := DueReminder{}
+		err = rows.Scan(&r.Id, &r.Channel, &r.ToDo, &r.Title, &r.Deadline, &r.DueAt, &r.UserName, &r.UserMail)
+
+		if err != nil {
+			return nil, err
+		}
+
+		due = append(due, r)
+	}
+
+	return due, rows.Err()
+}
+
+// Claims older than this were abandoned by their worker
+func claimExpiry(now time.Time) time.Time {
+	return now.Add(-REMINDER_CLAIM_TIMEOUT * time.Minute)
+}
+
+// Takes the reminder for this worker, false means another one got it first
+func ClaimReminder(id int64, now time.Time, db *sql.DB) (bool, error) {
+	stm, err := db.Prepare("UPDATE reminders SET claimed_at = ?, attempts = attempts + 1 WHERE id_reminder = ? AND sent_at IS NULL AND (claimed_at IS NULL OR claimed_at < ?) LIMIT 1;")
+
+	if err != nil {
+		return false, err
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(now, id, claimExpiry(now))
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// Moves the claim of a reminder that is still being delivered to now, so it doesn't time out
+// and get claimed again. False means the claim was lost, to another worker or to a sent mark
+func RenewReminderClaim(id int64, claimedAt, now time.Time, db *sql.DB) (bool, error) {
+	stm, err := db.Prepare("UPDATE reminders SET claimed_at = ? WHERE id_reminder = ? AND sent_at IS NULL AND claimed_at = ? LIMIT 1;")
+
+	if err != nil {
+		return false, err
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(now, id, claimedAt)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// Marks a claimed reminder as sent, it only happens once per reminder
+func MarkReminderSent(id int64, now time.Time, db *sql.DB) error {
+	stm, err := db.Prepare("UPDATE reminders SET sent_at = ?, claimed_at = NULL WHERE id_reminder = ? AND sent_at IS NULL LIMIT 1;")
+
+	if err != nil {
+		return err
+	}
+	defer stm.Close()
+
+	_, err = stm.Exec(now, id)
+	return err
+}
+
+// Gives back a claimed reminder whose delivery failed, so it is retried
+func ReleaseReminder(id int64, db *sql.DB) error {
+	stm, err := db.Prepare("UPDATE reminders SET claimed_at = NULL WHERE id_reminder = ? AND sent_at IS NULL LIMIT 1;")
+
+	if err != nil {
+		return err
+	}
+	defer stm.Close()
+
+	_, err = stm.Exec(id)
+	return err
+}
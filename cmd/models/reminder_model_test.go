@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenewReminderClaim(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "current")
+	todo := insertTestToDo(t, db, ToDo{Title: "standup", CreatedBy: user})
+
+	res, err := db.Exec("INSERT INTO reminders (id_todo, remind_at, channel) VALUES ( ?, ?, 'log' );", todo, time.Now().UTC().Add(-time.Minute))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := res.LastInsertId()
+	claimedAt := time.Now().UTC().Truncate(time.Second).Add(-4 * time.Minute)
+
+	if claimed, err := ClaimReminder(id, claimedAt, db); !claimed || err != nil {
+		t.Fatalf("ClaimReminder = %v, %v", claimed, err)
+	}
+
+	renewedAt := claimedAt.Add(3 * time.Minute)
+
+	if renewed, err := RenewReminderClaim(id, claimedAt, renewedAt, db); !renewed || err != nil {
+		t.Fatalf("RenewReminderClaim = %v, %v", renewed, err)
+	}
+
+	// Past the timeout of the first claim, the renewed one keeps other workers out
+	if claimed, err := ClaimReminder(id, claimedAt.Add((REMINDER_CLAIM_TIMEOUT+1)*time.Minute), db); claimed || err != nil {
+		t.Errorf("claiming a renewed reminder = %v, %v, want false", claimed, err)
+	}
+
+	if renewed, _ := RenewReminderClaim(id, claimedAt, renewedAt.Add(time.Minute), db); renewed {
+		t.Error("renewed with a claim that was already moved")
+	}
+
+	if err := MarkReminderSent(id, renewedAt, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if renewed, _ := RenewReminderClaim(id, renewedAt, renewedAt.Add(time.Minute), db); renewed {
+		t.Error("renewed the claim of a sent reminder")
+	}
+}
@@ -3,5 +3,6 @@ package models
 type Response struct {
 	Status int `json:"status"`
 	ErrorMsg string `json:"error_msg,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
 	Body any `json:"body"`
 }
\ No newline at end of file
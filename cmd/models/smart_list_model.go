@@ -0,0 +1,354 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// A smart list is a named filter of the personal to dos kept as JSON in
+// smart_lists.filter. Deadlines are relative to the day it is evaluated on, in the
+// timezone of the filter, so "due this week" keeps meaning the current week
+const (
+	MAX_SMART_LISTS_PER_USER = 20
+	MAX_SMART_FILTER_TEXT    = 100
+	MAX_SMART_FILTER_DAYS    = 366
+
+	DEADLINE_TODAY      = "today"
+	DEADLINE_TOMORROW   = "tomorrow"
+	DEADLINE_THIS_WEEK  = "this_week"
+	DEADLINE_NEXT_WEEK  = "next_week"
+	DEADLINE_THIS_MONTH = "this_month"
+	DEADLINE_OVERDUE    = "overdue"
+)
+
+type SmartList struct {
+	Name      string      `json:"name"`
+	Filter    SmartFilter `json:"filter"`
+	CreatedBy int64       `json:"created_by"`
+}
+
+type SmartFilter struct {
+	// To dos with any or all of these tags, see TagMatch
+	Tags     []int64 `json:"tags"`
+	TagMatch string  `json:"tag_match"`
+	Color    *uint   `json:"color"`
+	// Completed or open to dos, null for both
+	Completed *bool          `json:"completed"`
+	Text      string         `json:"text"`
+	Deadline  *DeadlineRange `json:"deadline"`
+	// IANA zone the days of the deadline range are counted in
+	Timezone string `json:"timezone"`
+}
+
+// Either a preset or a range of days from today, both ends included. A missing end
+// leaves that side open
+type DeadlineRange struct {
+	Preset string `json:"preset"`
+	From   *int   `json:"from"`
+	To     *int   `json:"to"`
+}
+
+var deadlinePresets = []string{DEADLINE_TODAY, DEADLINE_TOMORROW, DEADLINE_THIS_WEEK, DEADLINE_NEXT_WEEK, DEADLINE_THIS_MONTH, DEADLINE_OVERDUE}
+
+func (r *DeadlineRange) Validate() bool {
+	if r.Preset != "" {
+		return r.From == nil && r.To == nil && slices.Contains(deadlinePresets, r.Preset)
+	}
+
+	if r.From == nil && r.To == nil {
+		return false
+	}
+
+	for _, days := range []*int{r.From, r.To} {
+		if days != nil && (*days < -MAX_SMART_FILTER_DAYS || *days > MAX_SMART_FILTER_DAYS) {
+			return false
+		}
+	}
+
+	return r.From == nil || r.To == nil || *r.From <= *r.To
+}
+
+func (f *SmartFilter) Validate() bool {
+	if f.Timezone == "" {
+		f.Timezone = "UTC"
+	}
+
+	if _, err := time.LoadLocation(f.Timezone); err != nil {
+		return false
+	}
+
+	if f.Deadline != nil && !f.Deadline.Validate() {
+		return false
+	}
+
+	f.Text = NormalizeText(f.Text)
+
+	if utf8.RuneCountInString(f.Text) > MAX_SMART_FILTER_TEXT {
+		return false
+	}
+
+	// The listing filter already knows how to check the tags
+	todos := ToDoFilter{Tags: f.Tags, TagMatch: f.TagMatch}
+
+	if !todos.Validate() {
+		return false
+	}
+
+	f.Tags, f.TagMatch = todos.Tags, todos.TagMatch
+
+	if f.Tags == nil {
+		f.Tags = make([]int64, 0)
+	}
+
+	return true
+}
+
+func (s *SmartList) ValidateSmartList() bool {
+	s.Name = NormalizeText(s.Name)
+	return ValidateField(FIELD_SMART_LIST_NAME, s.Name) && s.Filter.Validate()
+}
+
+// The listing filter of the smart list on the day of now
+func (f *SmartFilter) resolve(now time.Time) ToDoFilter {
+	filter := ToDoFilter{
+		State:    TODO_STATE_ALL,
+		Tags:     f.Tags,
+		TagMatch: f.TagMatch,
+		Color:    f.Color,
+		Search:   f.Text,
+	}
+
+	if f.Completed != nil {
+		filter.State = TODO_STATE_OPEN
+		if *f.Completed {
+			filter.State = TODO_STATE_COMPLETED
+		}
+	}
+
+	if f.Deadline == nil {
+		return filter
+	}
+
+	location, _ := time.LoadLocation(f.Timezone)
+	now = now.In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	var from, to time.Time
+
+	switch f.Deadline.Preset {
+	case DEADLINE_OVERDUE:
+		filter.Overdue = true
+		return filter
+	case DEADLINE_TODAY:
+		from, to = today, today.AddDate(0, 0, 1)
+	case DEADLINE_TOMORROW:
+		from, to = today.AddDate(0, 0, 1), today.AddDate(0, 0, 2)
+	case DEADLINE_THIS_WEEK:
+		from, to = monday, monday.AddDate(0, 0, 7)
+	case DEADLINE_NEXT_WEEK:
+		from, to = monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 14)
+	case DEADLINE_THIS_MONTH:
+		from = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, location)
+		to = from.AddDate(0, 1, 0)
+	default:
+		if f.Deadline.From != nil {
+			from = today.AddDate(0, 0, *f.Deadline.From)
+		}
+
+		if f.Deadline.To != nil {
+			to = today.AddDate(0, 0, *f.Deadline.To+1)
+		}
+	}
+
+	// The listing includes both ends, the range ends right before the next day
+	if !from.IsZero() {
+		filter.DeadlineFrom = &from
+	}
+
+	if !to.IsZero() {
+		to = to.Add(-time.Microsecond)
+		filter.DeadlineTo = &to
+	}
+
+	return filter
+}
+
+func (s *SmartList) CheckUserIsActive(db *sql.DB) (bool, error) {
+	userDto := UserDTO{}
+
+	return userDto.VerifyUserIdIsActive(int(s.CreatedBy), db)
+}
+
+func (s *SmartList) CountSmartListsPerUserId(db *sql.DB) (int, error) {
+	count := -1
+	err := db.QueryRow("SELECT COUNT(*) FROM smart_lists WHERE created_by = ? AND status = 1;", s.CreatedBy).Scan(&count)
+
+	return count, err
+}
+
+// The tags of the filter must be active tags of the user when it is saved, the ones
+// deleted later simply match nothing
+func (s *SmartList) checkTags(db *sql.DB) error {
+	todo := ToDo{CreatedBy: s.CreatedBy, Tags: s.Filter.Tags}
+
+	return todo.CheckTagsAreOwned(db)
+}
+
+func (s *SmartList) InsertSmartList(db *sql.DB) (int64, error) {
+	if active, err := s.CheckUserIsActive(db); !active || err != nil {
+		return -1, locales.New("invalid_user")
+	}
+
+	count, err := s.CountSmartListsPerUserId(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_SMART_LISTS_PER_USER {
+		return -1, locales.New("smart_lists_limit")
+	}
+
+	if err := s.checkTags(db); err != nil {
+		return -1, err
+	}
+
+	filter, err := json.Marshal(s.Filter)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	res, err := db.Exec("INSERT INTO smart_lists (name, filter, created_by) VALUES ( ?, ?, ? );", s.Name, filter, s.CreatedBy)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+func (s *SmartList) UpdateSmartListById(id int64, delete bool, db *sql.DB) error {
+	if active, err := s.CheckUserIsActive(db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	if delete {
+		res, err := db.Exec("UPDATE smart_lists SET status = 0, updated_at = now() WHERE id_smart_list = ? AND created_by = ? AND status = 1 LIMIT 1;", id, s.CreatedBy)
+
+		if err != nil {
+			return locales.New("smart_list_not_deleted")
+		}
+
+		if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+			return locales.New("smart_list_not_found")
+		}
+
+		return nil
+	}
+
+	if err := s.checkTags(db); err != nil {
+		return err
+	}
+
+	filter, err := json.Marshal(s.Filter)
+
+	if err != nil {
+		return locales.New("smart_list_not_updated")
+	}
+
+	res, err := db.Exec("UPDATE smart_lists SET name = ?, filter = ?, updated_at = now() WHERE id_smart_list = ? AND created_by = ? AND status = 1 LIMIT 1;", s.Name, filter, id, s.CreatedBy)
+
+	if err != nil {
+		return locales.New("smart_list_not_updated")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("smart_list_not_found")
+	}
+
+	return nil
+}
+
+// Loads the smart list into s, it must belong to s.CreatedBy
+func (s *SmartList) GetSmartListById(id int64, db *sql.DB) error {
+	var filter string
+	err := db.QueryRow("SELECT name, filter FROM smart_lists WHERE id_smart_list = ? AND created_by = ? AND status = 1 LIMIT 1;", id, s.CreatedBy).Scan(&s.Name, &filter)
+
+	if err != nil {
+		return locales.New("smart_list_not_found")
+	}
+
+	if json.Unmarshal([]byte(filter), &s.Filter) != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+func (s *SmartList) GetAllSmartListsFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := s.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	rows, err := db.Query("SELECT id_smart_list, name, filter FROM smart_lists WHERE created_by = ? AND status = 1 ORDER BY name ASC, id_smart_list ASC;", s.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	lists := make([]map[string]any, 0)
+
+	for rows.Next() {
+		list := SmartList{CreatedBy: s.CreatedBy}
+		var id int64
+		var filter string
+
+		if err := rows.Scan(&id, &list.Name, &filter); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		if json.Unmarshal([]byte(filter), &list.Filter) != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		lists = append(lists, map[string]any{
+			"id":         id,
+			"name":       list.Name,
+			"filter":     list.Filter,
+			"created_by": list.CreatedBy,
+		})
+	}
+
+	return lists, nil
+}
+
+// Lists the personal to dos matching the smart list today. Sort, order, limit and cursor
+// of page work as in the listing
+func (s *SmartList) Evaluate(id int64, page ToDoFilter, db *sql.DB) (ToDoPage, error) {
+	if err := s.GetSmartListById(id, db); err != nil {
+		return ToDoPage{}, err
+	}
+
+	filter := s.Filter.resolve(time.Now())
+	filter.Sort, filter.Order = page.Sort, page.Order
+	filter.Limit, filter.Cursor = page.Limit, page.Cursor
+
+	if !filter.Validate() {
+		return ToDoPage{}, locales.New("invalid_filter")
+	}
+
+	if err := filter.ValidateCursor(); err != nil {
+		return ToDoPage{}, err
+	}
+
+	todo := ToDo{CreatedBy: s.CreatedBy}
+
+	return todo.GetAllToDosFromUserId(filter, db)
+}
@@ -2,19 +2,21 @@ package models
 
 import (
 	"database/sql"
-	"errors"
-	"regexp"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
 )
 
 type Tag struct {
 	Title     string `json:"title"`
 	Color     uint  `json:"color"`
 	CreatedBy int64  `json:"created_by"`
+	// Workspace the tag belongs to, 0 for the personal ones
+	Workspace int64  `json:"workspace"`
 }
 
 func (t *Tag) ValidateTag() (bool, error) {
-	titleRegex := "^[a-zA-Z]{2,18}$"
-	return regexp.Match(titleRegex, []byte(t.Title))
+	t.Title = NormalizeText(t.Title)
+	return ValidateField(FIELD_TAG_TITLE, t.Title), nil
 }
 
 func (t *Tag) CheckUserIsActive(db *sql.DB) (bool, error) {
@@ -36,7 +38,7 @@ func (t *Tag) VerifyUserIsPremium(db *sql.DB) (bool, error) {
 
 func (t *Tag) TagExists(id int64, db *sql.DB) (bool, error) {
     stm, err := db.Prepare("SELECT COUNT(*) FROM tags WHERE id_tag = ? AND status = 1 LIMIT 1");
-    notExists := errors.New("tag not exists")
+    notExists := locales.New("tag_not_found")
 
     if err != nil {
         return false, notExists
@@ -56,7 +58,7 @@ func (t *Tag) TagExists(id int64, db *sql.DB) (bool, error) {
 }
 
 func (t *Tag) CountTagsPerUserId(db *sql.DB) (int, error) {
-    stm, err := db.Prepare("SELECT COUNT(*) AS count FROM tags WHERE created_by = ? AND status = 1 LIMIT 1;");
+    stm, err := db.Prepare("SELECT COUNT(*) AS count FROM tags WHERE created_by = ? AND id_workspace IS NULL AND status = 1 LIMIT 1;");
 
     if err != nil {
         return -1, err
@@ -74,17 +76,17 @@ func (t *Tag) GetTagById(id int, db *sql.DB) error {
     stm, err := db.Prepare("SELECT title, color, created_by FROM tags WHERE id_tag = ? AND status = 1 LIMIT 1");
 
     if err != nil {
-        return errors.New("couldnt get")
+        return locales.New("get_failed")
     }
     defer stm.Close()
 
     holder := Tag{}
     row := stm.QueryRow(id)
 
-    err = row.Scan(&holder)
+    err = row.Scan(&holder.Title, &holder.Color, &holder.CreatedBy)
 
     if err != nil {
-        return errors.New("invalid id")
+        return locales.New("invalid_id")
     }
 
     t.Color = holder.Color
@@ -94,18 +96,21 @@ func (t *Tag) GetTagById(id int, db *sql.DB) error {
     return nil
 }
 
-func (t *Tag) InsertTag(db *sql.DB) (int64, error) {
-    maxTagsCount := 10
+// The tags of a workspace count against its plan instead of the user one
+func (t *Tag) CheckQuota(db *sql.DB) error {
+    if t.Workspace != 0 {
+        return CheckWorkspaceQuota(t.Workspace, t.CreatedBy, WORKSPACE_TAGS, db)
+    }
 
-    var err error
+    maxTagsCount := 10
 
     if active, err := t.CheckUserIsActive(db) ; !active || err != nil {
-        return -1, errors.New("invalid user")
+        return locales.New("invalid_user")
     }
 
     if premium, err := t.VerifyUserIsPremium(db) ; premium {
         if err != nil {
-            return -1, errors.New("invalid user")
+            return locales.New("invalid_user")
         }
 
         maxTagsCount = 20
@@ -114,29 +119,37 @@ func (t *Tag) InsertTag(db *sql.DB) (int64, error) {
     count, err := t.CountTagsPerUserId(db)
 
     if err != nil {
-        return -1, errors.New("invalid user")
+        return locales.New("invalid_user")
     }
 
     if count >= maxTagsCount {
-        return -1, errors.New("tags limit exceeded")
+        return locales.New("tags_limit")
+    }
+
+    return nil
+}
+
+func (t *Tag) InsertTag(db *sql.DB) (int64, error) {
+    if err := t.CheckQuota(db); err != nil {
+        return -1, err
     }
 
-    stm, err := db.Prepare("INSERT INTO tags (title, color, created_by) VALUES ( ? , ? , ? ) LIMIT 1;")
+    stm, err := db.Prepare("INSERT INTO tags (title, color, created_by, id_workspace) VALUES ( ? , ? , ? , NULLIF(?, 0) ) LIMIT 1;")
 
     if err != nil {
-        return -1, errors.New("unexpected Error")
+        return -1, locales.New("unexpected_error")
     }
 
-    res, err := stm.Exec(t.Title, t.Color, t.CreatedBy)
+    res, err := stm.Exec(t.Title, t.Color, t.CreatedBy, t.Workspace)
 
     if err != nil {
-        return -1, errors.New("unexpected error")
+        return -1, locales.New("unexpected_error")
     }
 
     insertId, err := res.LastInsertId()
 
     if err != nil {
-        return -1, errors.New("unexpected error")
+        return -1, locales.New("unexpected_error")
     }
 
     return insertId, nil
@@ -144,52 +157,62 @@ func (t *Tag) InsertTag(db *sql.DB) (int64, error) {
 
 func (t *Tag) UpdateTagById(id int64, delete bool, db *sql.DB) (error) {
     if active, err := t.CheckUserIsActive(db); !active || err != nil {
-        return errors.New("invalid user")
+        return locales.New("invalid_user")
     }
 
     if exists, err := t.TagExists(id, db); !exists || err != nil {
-        return errors.New("tag not found")
+        return locales.New("tag_not_found")
     }
 
-    var query string
-    var errorMsg string
-
     if delete {
-        errorMsg = "Couldnt delete tag"
-        query = "UPDATE tags SET status = 0, updated_at = now() WHERE id_tag = ? AND created_by = ? LIMIT 1;";
-    } else {
-        errorMsg = "Coundnt update tag"
-        query = "UPDATE tags SET title = ?, color = ?, updated_at = now() WHERE id_tag = ? AND created_by = ? LIMIT 1;";
+        return t.deleteTag(id, db)
     }
-    
-    stm, err := db.Prepare(query)
+
+    errorMsg := locales.New("tag_update_failed")
+    stm, err := db.Prepare("UPDATE tags SET title = ?, color = ?, updated_at = now() WHERE id_tag = ? AND created_by = ? LIMIT 1;")
 
     if err != nil {
-        return errors.New(errorMsg)
+        return errorMsg
     }
 
     defer stm.Close()
 
-    if delete {
-        _, err = stm.Exec(id, t.CreatedBy)
+    res, _ := stm.Exec(t.Title, t.Color, id, t.CreatedBy)
+    affected, err := res.RowsAffected()
 
-        if err != nil {
-            return errors.New(errorMsg)
-        }
+    if affected != 1 || err != nil {
+        return errorMsg
+    }
 
-        to := ToDo{
-            CreatedBy: t.CreatedBy,
-            Tag: id,
-        }
+    return nil
+}
 
-        go to.DeleteAllToDosFromTagId(db)
-    } else {
-        res, _ := stm.Exec(t.Title, t.Color, id, t.CreatedBy)
-        affected, err := res.RowsAffected()
+// Deleting a tag only takes it off its to dos, they are kept
+func (t *Tag) deleteTag(id int64, db *sql.DB) error {
+    errorMsg := locales.New("tag_delete_failed")
+    tx, err := db.Begin()
 
-        if affected != 1 || err != nil {
-            return errors.New(errorMsg)
-        }
+    if err != nil {
+        return errorMsg
+    }
+    defer tx.Rollback()
+
+    res, err := tx.Exec("UPDATE tags SET status = 0, deleted_at = now(), updated_at = now() WHERE id_tag = ? AND created_by = ? AND status = 1 LIMIT 1;", id, t.CreatedBy)
+
+    if err != nil {
+        return errorMsg
+    }
+
+    if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+        return locales.New("tag_not_found")
+    }
+
+    if err := detachTagsWhere(tx, "id_tag = ? AND created_by = ?", id, t.CreatedBy); err != nil {
+        return errorMsg
+    }
+
+    if err := tx.Commit(); err != nil {
+        return errorMsg
     }
 
     return nil
@@ -197,41 +220,64 @@ func (t *Tag) UpdateTagById(id int64, delete bool, db *sql.DB) (error) {
 
 func (t *Tag) DeleteAllTagsFromUserId(db *sql.DB) (error) {
     if active, err := t.CheckUserIsActive(db); !active || err != nil {
-        return errors.New("invalid user")
+        return locales.New("invalid_user")
     }
 
-    stm, err := db.Prepare("UPDATE tags SET status = 0 WHERE created_by = ? AND status = 1;")
+    tx, err := db.Begin()
     if err != nil {
-        return errors.New("error deleting")
+        return locales.New("delete_failed")
     }
 
-    defer stm.Close()
+    defer tx.Rollback()
 
-    _, err = stm.Exec(t.CreatedBy)
+    _, err = tx.Exec("UPDATE tags SET status = 0, deleted_at = now() WHERE created_by = ? AND status = 1;", t.CreatedBy)
 
     if err != nil {
-        err = errors.New("error deleting")
+        return locales.New("delete_failed")
     }
 
-    return err
+    if err := detachTagsWhere(tx, "created_by = ?", t.CreatedBy); err != nil {
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return locales.New("delete_failed")
+    }
+
+    return nil
 }
 
+// Lists the personal tags of the user, or every tag of t.Workspace for its members
 func (t *Tag) GetAllTagsFromUserId(db *sql.DB) ([]map[string]any, error) {
     if active, err := t.CheckUserIsActive(db); !active || err != nil {
-        return nil, errors.New("couldnt get")
+        return nil, locales.New("get_failed")
     }
 
-    stm, err := db.Prepare("SELECT id_tag, title, color FROM tags WHERE created_by = ? AND status = 1;")
+    query := "SELECT id_tag, title, color, created_by FROM tags WHERE created_by = ? AND status = 1"
+    args := []any{t.CreatedBy}
+
+    if t.Workspace != 0 {
+        if err := CheckWorkspaceRole(t.Workspace, t.CreatedBy, ROLE_VIEWER, db); err != nil {
+            return nil, err
+        }
+
+        query = "SELECT id_tag, title, color, created_by FROM tags WHERE status = 1"
+        args = nil
+    }
+
+    condition, workspaceArgs := workspaceScope(t.Workspace)
+
+    stm, err := db.Prepare(query + condition + ";")
     if err != nil {
-        return nil, errors.New("couldnt get")
+        return nil, locales.New("get_failed")
     }
 
     defer stm.Close()
 
-    rows, err := stm.Query(t.CreatedBy)
+    rows, err := stm.Query(append(args, workspaceArgs...)...)
 
     if err != nil {
-        err = errors.New("internal server error")
+        err = locales.New("internal_error")
         return nil, err
     }
 
@@ -241,17 +287,18 @@ func (t *Tag) GetAllTagsFromUserId(db *sql.DB) ([]map[string]any, error) {
         tag := Tag{ CreatedBy: t.CreatedBy}
         var id int64 = 0;
 
-        err = rows.Scan(&id, &tag.Title, &tag.Color)
+        err = rows.Scan(&id, &tag.Title, &tag.Color, &tag.CreatedBy)
 
         tags = append(tags, map[string]any{
             "id" : id,
             "title": tag.Title,
             "color": tag.Color,
             "created_by" : tag.CreatedBy,
+            "workspace" : t.Workspace,
         })
 
         if err != nil {
-            err = errors.New("internal server error")
+            err = locales.New("internal_error")
             return nil, err
         }
     }
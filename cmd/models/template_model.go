@@ -0,0 +1,483 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// A template keeps a set of to dos to create again, as JSON in templates.todos. The
+// deadlines are days from the date the template is instantiated on, at a wall clock
+// time of the zone of the to do, so they don't move with DST
+const (
+	MAX_TEMPLATES_PER_USER   = 20
+	MAX_TODOS_PER_TEMPLATE   = 20
+	MAX_TEMPLATE_OFFSET_DAYS = 366
+
+	TEMPLATE_TIME_LAYOUT = "15:04"
+)
+
+type Template struct {
+	Title     string         `json:"title"`
+	ToDos     []TemplateToDo `json:"todos"`
+	CreatedBy int64          `json:"created_by"`
+}
+
+type TemplateToDo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       uint   `json:"color"`
+	Priority    int    `json:"priority"`
+	Recurrence  string `json:"recurrence"`
+	Timezone    string `json:"timezone"`
+	// Days from the chosen date to the deadline, negative ones fall before it
+	DeadlineDays int `json:"deadline_days"`
+	// Time of the deadline in Timezone, as "15:04". Empty keeps the time of the chosen date
+	DeadlineTime string   `json:"deadline_time"`
+	Tags         []int64  `json:"tags"`
+	Items        []string `json:"items"`
+}
+
+func (tt *TemplateToDo) location() *time.Location {
+	loc, err := time.LoadLocation(tt.Timezone)
+
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// The deadline on the day DeadlineDays after the date, both taken in the zone of the to do
+func (tt *TemplateToDo) deadline(date time.Time) time.Time {
+	loc := tt.location()
+	date = date.In(loc)
+	hour, minute, sec := date.Clock()
+
+	if at, err := time.Parse(TEMPLATE_TIME_LAYOUT, tt.DeadlineTime); err == nil {
+		hour, minute, sec = at.Hour(), at.Minute(), 0
+	}
+
+	year, month, day := date.AddDate(0, 0, tt.DeadlineDays).Date()
+	return time.Date(year, month, day, hour, minute, sec, 0, loc)
+}
+
+// The to do the template to do creates on the date
+func (tt *TemplateToDo) toDo(date time.Time, userId int64) ToDo {
+	return ToDo{
+		Title:       tt.Title,
+		Description: tt.Description,
+		Color:       tt.Color,
+		Deadline:    tt.deadline(date),
+		Tags:        slices.Clone(tt.Tags),
+		Recurrence:  tt.Recurrence,
+		Timezone:    tt.Timezone,
+		Priority:    tt.Priority,
+		CreatedBy:   userId,
+	}
+}
+
+// Normalizes the to dos as the to do validation does, so the template holds what
+// instantiating it creates
+func (t *Template) ValidateTemplate() bool {
+	t.Title = NormalizeText(t.Title)
+
+	if !ValidateField(FIELD_TEMPLATE_TITLE, t.Title) || len(t.ToDos) == 0 || len(t.ToDos) > MAX_TODOS_PER_TEMPLATE {
+		return false
+	}
+
+	for i := range t.ToDos {
+		tt := &t.ToDos[i]
+		todo := tt.toDo(time.Time{}, t.CreatedBy)
+		titleOk, _ := todo.ValidateTitle()
+		descOk, _ := todo.ValidateDescription()
+
+		if !titleOk || !descOk || !todo.ValidatePriority() || !todo.ValidateTags() || !todo.ValidateRecurrence() {
+			return false
+		}
+
+		if tt.DeadlineDays < -MAX_TEMPLATE_OFFSET_DAYS || tt.DeadlineDays > MAX_TEMPLATE_OFFSET_DAYS || len(tt.Items) > MAX_ITEMS_PER_TODO {
+			return false
+		}
+
+		if _, err := time.Parse(TEMPLATE_TIME_LAYOUT, tt.DeadlineTime); tt.DeadlineTime != "" && err != nil {
+			return false
+		}
+
+		for j := range tt.Items {
+			item := ToDoItem{Text: tt.Items[j]}
+
+			if !item.ValidateText() {
+				return false
+			}
+
+			tt.Items[j] = item.Text
+		}
+
+		tt.Title, tt.Description, tt.Tags = todo.Title, todo.Description, todo.Tags
+		tt.Recurrence, tt.Timezone = todo.Recurrence, todo.Timezone
+
+		if tt.Items == nil {
+			tt.Items = make([]string, 0)
+		}
+	}
+
+	return true
+}
+
+func (t *Template) CheckUserIsActive(db *sql.DB) (bool, error) {
+	userDto := UserDTO{}
+
+	return userDto.VerifyUserIdIsActive(int(t.CreatedBy), db)
+}
+
+// Every tag of the to dos must be an active tag of the user
+func (t *Template) checkTags(db *sql.DB) error {
+	todo := ToDo{CreatedBy: t.CreatedBy}
+
+	for _, tt := range t.ToDos {
+		for _, tag := range tt.Tags {
+			if !slices.Contains(todo.Tags, tag) {
+				todo.Tags = append(todo.Tags, tag)
+			}
+		}
+	}
+
+	return todo.CheckTagsAreOwned(db)
+}
+
+func (t *Template) CountTemplatesPerUserId(db *sql.DB) (int, error) {
+	count := -1
+	err := db.QueryRow("SELECT COUNT(*) FROM templates WHERE created_by = ? AND status = 1;", t.CreatedBy).Scan(&count)
+
+	return count, err
+}
+
+func (t *Template) InsertTemplate(db *sql.DB) (int64, error) {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return -1, locales.New("invalid_user")
+	}
+
+	count, err := t.CountTemplatesPerUserId(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_TEMPLATES_PER_USER {
+		return -1, locales.New("templates_limit")
+	}
+
+	if err := t.checkTags(db); err != nil {
+		return -1, err
+	}
+
+	todos, err := json.Marshal(t.ToDos)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	res, err := db.Exec("INSERT INTO templates (title, todos, created_by) VALUES ( ?, ?, ? );", t.Title, todos, t.CreatedBy)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+// Captures the to dos of the user as a template. The days are counted from the day of the
+// earliest deadline, each in the zone of its to do, and the checklists keep their text, unchecked
+func (t *Template) InsertTemplateFromToDos(ids []int64, db *sql.DB) (int64, error) {
+	if len(ids) == 0 || len(ids) > MAX_TODOS_PER_TEMPLATE {
+		return -1, locales.New("invalid_fields")
+	}
+
+	todos := make([]ToDo, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	var base time.Time
+
+	for _, id := range ids {
+		// A repeated id would put the same to do twice in the template
+		if seen[id] {
+			return -1, locales.New("invalid_fields")
+		}
+		seen[id] = true
+
+		todo := ToDo{CreatedBy: t.CreatedBy}
+
+		if err := todo.GetToDoById(id, db); err != nil {
+			return -1, err
+		}
+
+		if todo.Workspace != 0 {
+			return -1, locales.New("todo_in_workspace")
+		}
+
+		if base.IsZero() || todo.Deadline.Before(base) {
+			base = todo.Deadline
+		}
+
+		todos = append(todos, todo)
+	}
+
+	t.ToDos = make([]TemplateToDo, 0, len(todos))
+
+	for i, todo := range todos {
+		tags, err := queryTags(ids[i], db)
+
+		if err != nil {
+			return -1, err
+		}
+
+		items, err := queryItemTexts(ids[i], db)
+
+		if err != nil {
+			return -1, err
+		}
+
+		tt := TemplateToDo{
+			Title:       todo.Title,
+			Description: todo.Description,
+			Color:       todo.Color,
+			Priority:    todo.Priority,
+			Recurrence:  todo.Recurrence,
+			Timezone:    todo.Timezone,
+			Tags:        tags,
+			Items:       items,
+		}
+
+		loc := tt.location()
+		deadline, first := todo.Deadline.In(loc), base.In(loc)
+		tt.DeadlineDays = int(civilDate(deadline).Sub(civilDate(first)).Hours() / 24)
+		tt.DeadlineTime = deadline.Format(TEMPLATE_TIME_LAYOUT)
+
+		t.ToDos = append(t.ToDos, tt)
+	}
+
+	if !t.ValidateTemplate() {
+		return -1, locales.New("invalid_fields")
+	}
+
+	return t.InsertTemplate(db)
+}
+
+// Date without time nor zone, so counting days doesn't suffer from DST
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func queryTags(todoId int64, db *sql.DB) ([]int64, error) {
+	var tags sql.NullString
+	err := db.QueryRow("SELECT GROUP_CONCAT(id_tag ORDER BY id_tag) FROM todo_tags WHERE id_todo = ?;", todoId).Scan(&tags)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+
+	return parseIdList(tags), nil
+}
+
+func queryItemTexts(todoId int64, db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT text FROM todo_items WHERE id_todo = ? AND status = 1 ORDER BY position ASC, id_item ASC;", todoId)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	texts := make([]string, 0)
+
+	for rows.Next() {
+		var text string
+
+		if err := rows.Scan(&text); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		texts = append(texts, text)
+	}
+
+	return texts, nil
+}
+
+func (t *Template) UpdateTemplateById(id int64, delete bool, db *sql.DB) error {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	if delete {
+		res, err := db.Exec("UPDATE templates SET status = 0, updated_at = now() WHERE id_template = ? AND created_by = ? AND status = 1 LIMIT 1;", id, t.CreatedBy)
+
+		if err != nil {
+			return locales.New("template_delete_failed")
+		}
+
+		if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+			return locales.New("template_not_found")
+		}
+
+		return nil
+	}
+
+	if err := t.checkTags(db); err != nil {
+		return err
+	}
+
+	todos, err := json.Marshal(t.ToDos)
+
+	if err != nil {
+		return locales.New("template_update_failed")
+	}
+
+	res, err := db.Exec("UPDATE templates SET title = ?, todos = ?, updated_at = now() WHERE id_template = ? AND created_by = ? AND status = 1 LIMIT 1;", t.Title, todos, id, t.CreatedBy)
+
+	if err != nil {
+		return locales.New("template_update_failed")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("template_not_found")
+	}
+
+	return nil
+}
+
+// Loads the template into t, it must belong to t.CreatedBy
+func (t *Template) GetTemplateById(id int64, db *sql.DB) error {
+	var todos string
+	err := db.QueryRow("SELECT title, todos FROM templates WHERE id_template = ? AND created_by = ? AND status = 1 LIMIT 1;", id, t.CreatedBy).Scan(&t.Title, &todos)
+
+	if err != nil {
+		return locales.New("template_not_found")
+	}
+
+	if json.Unmarshal([]byte(todos), &t.ToDos) != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+func (t *Template) GetAllTemplatesFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	rows, err := db.Query("SELECT id_template, title, todos FROM templates WHERE created_by = ? AND status = 1 ORDER BY title ASC, id_template ASC;", t.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	templates := make([]map[string]any, 0)
+
+	for rows.Next() {
+		template := Template{CreatedBy: t.CreatedBy}
+		var id int64
+		var todos string
+
+		if err := rows.Scan(&id, &template.Title, &todos); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		if json.Unmarshal([]byte(todos), &template.ToDos) != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		templates = append(templates, map[string]any{
+			"id":         id,
+			"title":      template.Title,
+			"todos":      template.ToDos,
+			"created_by": template.CreatedBy,
+		})
+	}
+
+	return templates, nil
+}
+
+// Creates the to dos of the template with their deadlines from date. The quota is checked
+// for all of them first and they are created in one transaction, so either the whole
+// set is created or none of it. Tags deleted since are left out
+func (t *Template) Instantiate(id int64, date time.Time, db *sql.DB) ([]int64, error) {
+	if err := t.GetTemplateById(id, db); err != nil {
+		return nil, err
+	}
+
+	owner := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := owner.checkPersonalQuota(len(t.ToDos), db); err != nil {
+		return nil, err
+	}
+
+	todos := make([]ToDo, 0, len(t.ToDos))
+
+	for _, tt := range t.ToDos {
+		todo := tt.toDo(date, t.CreatedBy)
+		todo.Tags = make([]int64, 0, len(tt.Tags))
+
+		for _, tag := range tt.Tags {
+			owned := ToDo{CreatedBy: t.CreatedBy, Tags: []int64{tag}}
+
+			if owned.CheckTagsAreOwned(db) == nil {
+				todo.Tags = append(todo.Tags, tag)
+			}
+		}
+
+		todo.ValidateTags()
+		todos = append(todos, todo)
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(todos))
+
+	for i := range todos {
+		todoId, err := todos[i].insertToDo(tx)
+
+		if err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		if err := insertItemTexts(todoId, t.ToDos[i].Items, tx); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		ids = append(ids, todoId)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, locales.New("internal_error")
+	}
+
+	return ids, nil
+}
+
+func insertItemTexts(todoId int64, texts []string, db execer) error {
+	if len(texts) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(texts))
+	args := make([]any, 0, len(texts)*2)
+
+	for i, text := range texts {
+		values = append(values, "(?, ?, 0, ?)")
+		args = append(args, todoId, text, i)
+	}
+
+	_, err := db.Exec("INSERT INTO todo_items (id_todo, text, done, position) VALUES "+strings.Join(values, ", ")+";", args...)
+
+	return err
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func insertRawTemplate(t *testing.T, db *sql.DB, user int64, todos []TemplateToDo) int64 {
+	t.Helper()
+
+	raw, err := json.Marshal(todos)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Straight into the table, so it can hold what the validation would reject
+	res, err := db.Exec("INSERT INTO templates (title, todos, created_by) VALUES ( 'weekly', ?, ? );", string(raw), user)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := res.LastInsertId()
+	return id
+}
+
+func TestInstantiate(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "planner")
+	date := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	id := insertRawTemplate(t, db, user, []TemplateToDo{
+		{Title: "plan", Timezone: "UTC", Items: []string{"goals", "meetings"}},
+		{Title: "review", Timezone: "UTC", DeadlineDays: 4},
+	})
+
+	template := Template{CreatedBy: user}
+	ids, err := template.Instantiate(id, date, db)
+
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("Instantiate = %v, %v", ids, err)
+	}
+
+	review := ToDo{CreatedBy: user}
+
+	if err := review.GetToDoById(ids[1], db); err != nil {
+		t.Fatal(err)
+	}
+
+	if !review.Deadline.Equal(date.Add(4 * 24 * time.Hour)) {
+		t.Errorf("deadline = %v, want %v", review.Deadline, date.Add(4*24*time.Hour))
+	}
+
+	var items int
+	if err := db.QueryRow("SELECT COUNT(*) FROM todo_items WHERE id_todo = ? AND status = 1;", ids[0]).Scan(&items); err != nil {
+		t.Fatal(err)
+	}
+
+	if items != 2 {
+		t.Errorf("items = %d, want 2", items)
+	}
+}
+
+func TestInstantiateCreatesAllOrNothing(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "planner")
+
+	// The title of the last one doesn't fit in the table
+	id := insertRawTemplate(t, db, user, []TemplateToDo{
+		{Title: "plan", Timezone: "UTC", Items: []string{"goals"}},
+		{Title: strings.Repeat("x", 60), Timezone: "UTC"},
+	})
+
+	template := Template{CreatedBy: user}
+
+	if _, err := template.Instantiate(id, time.Now().UTC(), db); err == nil {
+		t.Fatal("Instantiate should fail")
+	}
+
+	for _, table := range []string{"todos", "todo_items", "todo_revisions"} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table + ";").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 0 {
+			t.Errorf("%d rows left in %s, want none", count, table)
+		}
+	}
+}
+
+func TestInstantiateKeepsWallClockAcrossDST(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "planner")
+	ny, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Skip("no zone data:", err)
+	}
+
+	// New York leaves DST on 2026-11-01, a day in between is 25 hours long
+	date := time.Date(2026, 10, 30, 8, 0, 0, 0, ny)
+
+	id := insertRawTemplate(t, db, user, []TemplateToDo{
+		{Title: "standup", Timezone: "America/New_York", DeadlineDays: 4, DeadlineTime: "09:30"},
+		{Title: "retro", Timezone: "America/New_York", DeadlineDays: 3},
+	})
+
+	template := Template{CreatedBy: user}
+	ids, err := template.Instantiate(id, date, db)
+
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("Instantiate = %v, %v", ids, err)
+	}
+
+	for i, want := range []time.Time{
+		time.Date(2026, 11, 3, 9, 30, 0, 0, ny),
+		time.Date(2026, 11, 2, 8, 0, 0, 0, ny),
+	} {
+		todo := ToDo{CreatedBy: user}
+
+		if err := todo.GetToDoById(ids[i], db); err != nil {
+			t.Fatal(err)
+		}
+
+		if !todo.Deadline.Equal(want) {
+			t.Errorf("deadline = %v, want %v", todo.Deadline.In(ny), want)
+		}
+	}
+}
+
+func TestInsertTemplateFromToDos(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "planner")
+	ny, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Skip("no zone data:", err)
+	}
+
+	first := insertTestToDo(t, db, ToDo{Title: "first", CreatedBy: user, Timezone: "America/New_York", Deadline: time.Date(2026, 10, 31, 23, 30, 0, 0, ny)})
+	later := insertTestToDo(t, db, ToDo{Title: "later", CreatedBy: user, Timezone: "America/New_York", Deadline: time.Date(2026, 11, 3, 9, 0, 0, 0, ny)})
+
+	template := Template{Title: "weekly", CreatedBy: user}
+	id, err := template.InsertTemplateFromToDos([]int64{later, first}, db)
+
+	if err != nil {
+		t.Fatalf("InsertTemplateFromToDos: %v", err)
+	}
+
+	saved := Template{CreatedBy: user}
+
+	if err := saved.GetTemplateById(id, db); err != nil {
+		t.Fatal(err)
+	}
+
+	// Days are counted between the dates in the zone, not in 24 hour blocks
+	for i, want := range []struct {
+		days int
+		at   string
+	}{{3, "09:00"}, {0, "23:30"}} {
+		if got := saved.ToDos[i]; got.DeadlineDays != want.days || got.DeadlineTime != want.at {
+			t.Errorf("to do %d = %d days at %q, want %d at %q", i, got.DeadlineDays, got.DeadlineTime, want.days, want.at)
+		}
+	}
+}
+
+func TestInsertTemplateFromRepeatedToDos(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "planner")
+	todo := insertTestToDo(t, db, ToDo{Title: "once", CreatedBy: user})
+
+	template := Template{Title: "weekly", CreatedBy: user}
+
+	if _, err := template.InsertTemplateFromToDos([]int64{todo, todo}, db); err == nil {
+		t.Fatal("InsertTemplateFromToDos should reject a repeated to do")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM templates;").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 0 {
+		t.Errorf("%d templates, want none", count)
+	}
+}
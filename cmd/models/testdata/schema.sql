@@ -0,0 +1,269 @@
+-- Schema the model tests run against, the one the migrations in /migrations build,
+-- which migrations_test.go checks. It is loaded into the database of NAILIT_TEST_DSN,
+-- dropping what is there
+
+DROP TABLE IF EXISTS comment_mentions, todo_comments, pomodoro_sessions, time_entries, templates, smart_lists,
+  todo_dependencies, board_columns, workspace_members, workspaces, list_invites, list_members, lists,
+  reminders, todo_revisions, todo_items, todo_tags, images, tags, todos, users, rate_limits;
+
+CREATE TABLE users (
+  id_user BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(50) NOT NULL,
+  mail VARCHAR(100) NOT NULL,
+  password VARCHAR(100) NOT NULL DEFAULT '',
+  phone VARCHAR(20) NOT NULL DEFAULT '',
+  user_type TINYINT NOT NULL DEFAULT 0,
+  premium_expiracy DATETIME NULL,
+  image_url VARCHAR(255) NOT NULL DEFAULT '',
+  image_public_id VARCHAR(255) NOT NULL DEFAULT '',
+  locale VARCHAR(5) NOT NULL DEFAULT 'en',
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE todos (
+  id_todo BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  deadline DATETIME(6) NOT NULL,
+  tag BIGINT NOT NULL DEFAULT 0,
+  created_by BIGINT NOT NULL,
+  completed BOOL NOT NULL DEFAULT 0,
+  completed_at DATETIME NULL,
+  recurrence VARCHAR(255) NOT NULL DEFAULT '',
+  timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  next_occurrence BIGINT NULL,
+  priority TINYINT NOT NULL DEFAULT 0,
+  position DOUBLE NOT NULL DEFAULT 0,
+  archived_at DATETIME NULL,
+  id_list BIGINT NULL,
+  assigned_to BIGINT NULL,
+  id_workspace BIGINT NULL,
+  id_column BIGINT NULL,
+  column_position DOUBLE NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  deleted_at DATETIME NULL,
+  INDEX (created_by, status),
+  INDEX (id_list),
+  INDEX (id_workspace),
+  INDEX (id_column)
+);
+
+CREATE TABLE tags (
+  id_tag BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  created_by BIGINT NOT NULL,
+  id_workspace BIGINT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  deleted_at DATETIME NULL
+);
+
+CREATE TABLE todo_tags (
+  id_todo BIGINT NOT NULL,
+  id_tag BIGINT NOT NULL,
+  PRIMARY KEY (id_todo, id_tag),
+  INDEX (id_tag)
+);
+
+CREATE TABLE images (
+  id_image BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  image_url VARCHAR(255) NOT NULL,
+  public_id VARCHAR(255) NOT NULL,
+  id_user BIGINT NOT NULL,
+  id_workspace BIGINT NULL
+);
+
+CREATE TABLE todo_items (
+  id_item BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  text VARCHAR(255) NOT NULL,
+  done BOOL NOT NULL DEFAULT 0,
+  position INT NOT NULL DEFAULT 0,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (id_todo, status)
+);
+
+CREATE TABLE todo_revisions (
+  id_revision BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  changed_by BIGINT NOT NULL,
+  changed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  action VARCHAR(20) NOT NULL,
+  changes JSON NOT NULL,
+  snapshot JSON NOT NULL,
+  restored_from BIGINT NULL,
+  INDEX (id_todo)
+);
+
+CREATE TABLE reminders (
+  id_reminder BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  remind_at DATETIME NULL,
+  offset_minutes INT NOT NULL DEFAULT 0,
+  channel VARCHAR(20) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  sent_at DATETIME NULL,
+  claimed_at DATETIME NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  INDEX (id_todo, status),
+  INDEX (status, sent_at)
+);
+
+CREATE TABLE lists (
+  id_list BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE list_members (
+  id_list BIGINT NOT NULL,
+  id_user BIGINT NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  PRIMARY KEY (id_list, id_user),
+  INDEX (id_user)
+);
+
+CREATE TABLE list_invites (
+  id_invite BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_list BIGINT NOT NULL,
+  mail VARCHAR(100) NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  invited_by BIGINT NOT NULL,
+  accepted BOOL NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  answered_at DATETIME NULL,
+  INDEX (id_list, mail)
+);
+
+CREATE TABLE workspaces (
+  id_workspace BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(50) NOT NULL,
+  created_by BIGINT NOT NULL,
+  premium_expiracy DATETIME NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE workspace_members (
+  id_workspace BIGINT NOT NULL,
+  id_user BIGINT NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  PRIMARY KEY (id_workspace, id_user),
+  INDEX (id_user)
+);
+
+CREATE TABLE board_columns (
+  id_column BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  position DOUBLE NOT NULL,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);
+
+CREATE TABLE todo_dependencies (
+  id_blocker BIGINT NOT NULL,
+  id_blocked BIGINT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id_blocker, id_blocked),
+  INDEX (id_blocked)
+);
+
+CREATE TABLE smart_lists (
+  id_smart_list BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(30) NOT NULL,
+  filter JSON NOT NULL,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);
+
+CREATE TABLE templates (
+  id_template BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  todos JSON NOT NULL,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);
+
+CREATE TABLE time_entries (
+  id_entry BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  created_by BIGINT NOT NULL,
+  started_at DATETIME(6) NOT NULL,
+  ended_at DATETIME(6) NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX (created_by, started_at),
+  INDEX (id_todo)
+);
+
+CREATE TABLE pomodoro_sessions (
+  id_session BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  created_by BIGINT NOT NULL,
+  work_minutes INT NOT NULL,
+  break_minutes INT NOT NULL,
+  state VARCHAR(20) NOT NULL,
+  elapsed_seconds INT NOT NULL DEFAULT 0,
+  resumed_at DATETIME(6) NULL,
+  started_at DATETIME(6) NOT NULL,
+  finished_at DATETIME(6) NULL,
+  completed BOOL NOT NULL DEFAULT 0,
+  INDEX (created_by, state),
+  INDEX (created_by, started_at)
+);
+
+CREATE TABLE todo_comments (
+  id_comment BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  id_parent BIGINT NULL,
+  created_by BIGINT NOT NULL,
+  body VARCHAR(1000) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  deleted_at DATETIME NULL,
+  INDEX (id_todo),
+  INDEX (id_parent)
+);
+
+CREATE TABLE comment_mentions (
+  id_comment BIGINT NOT NULL,
+  id_user BIGINT NOT NULL,
+  PRIMARY KEY (id_comment, id_user),
+  INDEX (id_user)
+);
+
+CREATE TABLE rate_limits (
+  bucket_key VARCHAR(191) NOT NULL PRIMARY KEY,
+  tokens DOUBLE NOT NULL,
+  updated_at DATETIME(6) NOT NULL,
+  full_at DATETIME(6) NOT NULL,
+  INDEX (full_at)
+);
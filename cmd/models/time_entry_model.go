@@ -0,0 +1,383 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Time spent on a to do is kept in time_entries, one row per timer run or manual
+// entry. A running timer has no ended_at, each user has one at most
+const (
+	MAX_TIME_ENTRY  = 24 * time.Hour
+	MAX_REPORT_DAYS = 366
+
+	REPORT_PERIOD_DAY  = "day"
+	REPORT_PERIOD_WEEK = "week"
+
+	TIME_TOTALS_TODO = "todo"
+	TIME_TOTALS_TAG  = "tag"
+)
+
+type TimeEntry struct {
+	ToDo      int64      `json:"todo"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	CreatedBy int64      `json:"created_by"`
+}
+
+// Range and grouping of the time reports. From and To are both required, To excluded
+type TimeReportFilter struct {
+	From     time.Time
+	To       time.Time
+	Period   string
+	Location *time.Location
+}
+
+func (f *TimeReportFilter) Validate() bool {
+	if f.Period != REPORT_PERIOD_DAY && f.Period != REPORT_PERIOD_WEEK {
+		return false
+	}
+
+	return f.To.After(f.From) && f.To.Sub(f.From) <= MAX_REPORT_DAYS*24*time.Hour
+}
+
+// Manual entries are closed, in the past and no longer than a day
+func (e *TimeEntry) ValidateTimeEntry() bool {
+	if e.EndedAt == nil || e.StartedAt.IsZero() {
+		return false
+	}
+
+	length := e.EndedAt.Sub(e.StartedAt)
+
+	return length > 0 && length <= MAX_TIME_ENTRY && !e.EndedAt.After(time.Now())
+}
+
+// Seconds of an entry, the running one counts until the time bound to it. The entries are
+// written in UTC, so it is time.Now().UTC() and not now(), which is in the zone of the database
+const entrySeconds = "TIMESTAMPDIFF(SECOND, e.started_at, COALESCE(e.ended_at, ?))"
+
+// Locks the user row so two requests can't both start a timer
+func lockTimer(userId int64, tx *sql.Tx) error {
+	var id int64
+
+	if err := tx.QueryRow("SELECT id_user FROM users WHERE id_user = ? AND status = 1 LIMIT 1 FOR UPDATE;", userId).Scan(&id); err != nil {
+		return locales.New("invalid_user")
+	}
+
+	return nil
+}
+
+// Starts a timer on the to do, anyone that can see it tracks time on it. Fails while
+// another timer of the user is running
+func (e *TimeEntry) StartTimer(db *sql.DB) (int64, error) {
+	if err := CheckToDoAccess(e.ToDo, e.CreatedBy, db); err != nil {
+		return -1, err
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	if err := lockTimer(e.CreatedBy, tx); err != nil {
+		return -1, err
+	}
+
+	count := 0
+	err = tx.QueryRow("SELECT COUNT(*) FROM time_entries WHERE created_by = ? AND ended_at IS NULL AND status = 1;", e.CreatedBy).Scan(&count)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count > 0 {
+		return -1, locales.New("timer_running")
+	}
+
+	e.StartedAt = time.Now().UTC()
+	e.EndedAt = nil
+	res, err := tx.Exec("INSERT INTO time_entries (id_todo, created_by, started_at) VALUES ( ?, ?, ? );", e.ToDo, e.CreatedBy, e.StartedAt)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return id, nil
+}
+
+// Stops the timer of the user running on the to do
+func (e *TimeEntry) StopTimer(db *sql.DB) (int64, error) {
+	var id int64
+	row := db.QueryRow("SELECT id_entry, started_at FROM time_entries WHERE created_by = ? AND id_todo = ? AND ended_at IS NULL AND status = 1 LIMIT 1;", e.CreatedBy, e.ToDo)
+
+	if err := row.Scan(&id, &e.StartedAt); err != nil {
+		return -1, locales.New("timer_not_running")
+	}
+
+	ended := time.Now().UTC()
+	res, err := db.Exec("UPDATE time_entries SET ended_at = ? WHERE id_entry = ? AND ended_at IS NULL LIMIT 1;", ended, id)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return -1, locales.New("timer_not_running")
+	}
+
+	e.EndedAt = &ended
+
+	return id, nil
+}
+
+// The running timer of the user, ok is false when there is none
+func (e *TimeEntry) GetRunningTimer(db *sql.DB) (map[string]any, bool, error) {
+	user := UserDTO{}
+
+	if active, err := user.VerifyUserIdIsActive(int(e.CreatedBy), db); !active || err != nil {
+		return nil, false, locales.New("invalid_user")
+	}
+
+	var id int64
+	var title string
+	row := db.QueryRow("SELECT e.id_entry, e.id_todo, t.title, e.started_at FROM time_entries e JOIN todos t ON t.id_todo = e.id_todo "+
+		"WHERE e.created_by = ? AND e.ended_at IS NULL AND e.status = 1 LIMIT 1;", e.CreatedBy)
+
+	if err := row.Scan(&id, &e.ToDo, &title, &e.StartedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+
+		return nil, false, locales.New("internal_error")
+	}
+
+	return map[string]any{
+		"id":         id,
+		"todo":       e.ToDo,
+		"todo_title": title,
+		"started_at": e.StartedAt,
+		"seconds":    int64(time.Since(e.StartedAt).Seconds()),
+	}, true, nil
+}
+
+func (e *TimeEntry) InsertTimeEntry(db *sql.DB) (int64, error) {
+	if err := CheckToDoAccess(e.ToDo, e.CreatedBy, db); err != nil {
+		return -1, err
+	}
+
+	stm, err := db.Prepare("INSERT INTO time_entries (id_todo, created_by, started_at, ended_at) VALUES ( ?, ?, ?, ? );")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(e.ToDo, e.CreatedBy, e.StartedAt, e.EndedAt)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+// Deletes an entry of the user, a running timer included
+func (e *TimeEntry) DeleteTimeEntry(id int64, db *sql.DB) error {
+	res, err := db.Exec("UPDATE time_entries SET status = 0 WHERE id_entry = ? AND id_todo = ? AND created_by = ? AND status = 1 LIMIT 1;", id, e.ToDo, e.CreatedBy)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("time_entry_not_found")
+	}
+
+	return nil
+}
+
+// The entries of the user on the to do, the newest first, with their total
+func (e *TimeEntry) GetTimeEntries(db *sql.DB) (map[string]any, error) {
+	if err := CheckToDoAccess(e.ToDo, e.CreatedBy, db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT e.id_entry, e.started_at, e.ended_at, "+entrySeconds+" FROM time_entries e "+
+		"WHERE e.id_todo = ? AND e.created_by = ? AND e.status = 1 ORDER BY e.started_at DESC, e.id_entry DESC;", time.Now().UTC(), e.ToDo, e.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	entries := make([]map[string]any, 0)
+	var total int64
+
+	for rows.Next() {
+		entry := TimeEntry{ToDo: e.ToDo, CreatedBy: e.CreatedBy}
+		var id, seconds int64
+		var endedAt sql.NullTime
+
+		if err := rows.Scan(&id, &entry.StartedAt, &endedAt, &seconds); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		if endedAt.Valid {
+			entry.EndedAt = &endedAt.Time
+		}
+
+		total += seconds
+		entries = append(entries, map[string]any{
+			"id":         id,
+			"todo":       entry.ToDo,
+			"started_at": entry.StartedAt,
+			"ended_at":   entry.EndedAt,
+			"running":    !endedAt.Valid,
+			"seconds":    seconds,
+		})
+	}
+
+	return map[string]any{
+		"todo":    e.ToDo,
+		"seconds": total,
+		"entries": entries,
+	}, nil
+}
+
+// Time of the user in the range grouped by to do or by tag. An entry counts for every
+// tag of its to do, so the tag totals can add up to more than the time tracked
+func GetTimeTotals(userId int64, by string, filter TimeReportFilter, db *sql.DB) ([]map[string]any, error) {
+	user := UserDTO{}
+
+	if active, err := user.VerifyUserIdIsActive(int(userId), db); !active || err != nil {
+		return nil, locales.New("invalid_user")
+	}
+
+	query := "SELECT t.id_todo, t.title, SUM(" + entrySeconds + ") FROM time_entries e JOIN todos t ON t.id_todo = e.id_todo "
+	group := " GROUP BY t.id_todo, t.title"
+
+	if by == TIME_TOTALS_TAG {
+		query = "SELECT g.id_tag, g.title, SUM(" + entrySeconds + ") FROM time_entries e JOIN todos t ON t.id_todo = e.id_todo " +
+			"JOIN todo_tags tt ON tt.id_todo = t.id_todo JOIN tags g ON g.id_tag = tt.id_tag AND g.status = 1 "
+		group = " GROUP BY g.id_tag, g.title"
+	} else if by != TIME_TOTALS_TODO {
+		return nil, locales.New("invalid_filter")
+	}
+
+	rows, err := db.Query(query+"WHERE e.created_by = ? AND e.status = 1 AND t.status = 1 AND e.started_at >= ? AND e.started_at < ?"+
+		group+" ORDER BY 3 DESC, 1 ASC;", time.Now().UTC(), userId, filter.From, filter.To)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	totals := make([]map[string]any, 0)
+
+	for rows.Next() {
+		var id, seconds int64
+		var title string
+
+		if err := rows.Scan(&id, &title, &seconds); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		totals = append(totals, map[string]any{
+			"id":      id,
+			"title":   title,
+			"seconds": seconds,
+		})
+	}
+
+	return totals, nil
+}
+
+// Time of the user in the range by day or week of the location, weeks start on monday.
+// An entry counts for the period it started in
+func GetTimeReport(userId int64, filter TimeReportFilter, db *sql.DB) ([]map[string]any, error) {
+	user := UserDTO{}
+
+	if active, err := user.VerifyUserIdIsActive(int(userId), db); !active || err != nil {
+		return nil, locales.New("invalid_user")
+	}
+
+	rows, err := db.Query("SELECT t.id_todo, t.title, e.started_at, "+entrySeconds+" FROM time_entries e JOIN todos t ON t.id_todo = e.id_todo "+
+		"WHERE e.created_by = ? AND e.status = 1 AND t.status = 1 AND e.started_at >= ? AND e.started_at < ? ORDER BY e.started_at ASC, e.id_entry ASC;",
+		time.Now().UTC(), userId, filter.From, filter.To)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	periods := make([]map[string]any, 0)
+	byPeriod := map[string]map[string]any{}
+	byToDo := map[string]map[int64]map[string]any{}
+
+	for rows.Next() {
+		var todo, seconds int64
+		var title string
+		var startedAt time.Time
+
+		if err := rows.Scan(&todo, &title, &startedAt, &seconds); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		key := filter.periodOf(startedAt)
+		period, ok := byPeriod[key]
+
+		if !ok {
+			period = map[string]any{
+				"period":  key,
+				"seconds": int64(0),
+				"todos":   make([]map[string]any, 0),
+			}
+			byPeriod[key] = period
+			byToDo[key] = map[int64]map[string]any{}
+			periods = append(periods, period)
+		}
+
+		period["seconds"] = period["seconds"].(int64) + seconds
+		entry, ok := byToDo[key][todo]
+
+		if !ok {
+			entry = map[string]any{
+				"id":      todo,
+				"title":   title,
+				"seconds": int64(0),
+			}
+			byToDo[key][todo] = entry
+			period["todos"] = append(period["todos"].([]map[string]any), entry)
+		}
+
+		entry["seconds"] = entry["seconds"].(int64) + seconds
+	}
+
+	return periods, nil
+}
+
+// The day, or the monday of the week, the time falls in as YYYY-MM-DD
+func (f *TimeReportFilter) periodOf(at time.Time) string {
+	at = at.In(f.Location)
+
+	if f.Period == REPORT_PERIOD_WEEK {
+		at = at.AddDate(0, 0, -((int(at.Weekday()) + 6) % 7))
+	}
+
+	return at.Format(time.DateOnly)
+}
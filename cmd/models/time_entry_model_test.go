@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// A running timer counts until the UTC time of the api, whatever the zone of the database
+func TestRunningEntrySecondsAreUTC(t *testing.T) {
+	db := testDB(t)
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("SET time_zone = '-05:00';"); err != nil {
+		t.Skip("the database can't change its time zone:", err)
+	}
+
+	user := insertTestUser(t, db, "tracker")
+	todo := insertTestToDo(t, db, ToDo{Title: "report", CreatedBy: user})
+	started := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Second)
+
+	if _, err := db.Exec("INSERT INTO time_entries (id_todo, created_by, started_at) VALUES ( ?, ?, ? );", todo, user, started); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := TimeEntry{ToDo: todo, CreatedBy: user}
+	entries, err := entry.GetTimeEntries(db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seconds := entries["seconds"].(int64); seconds < 590 || seconds > 660 {
+		t.Errorf("entry seconds = %d, want about 600", seconds)
+	}
+
+	filter := TimeReportFilter{From: started.Add(-time.Hour), To: started.Add(time.Hour)}
+	totals, err := GetTimeTotals(user, TIME_TOTALS_TODO, filter, db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(totals) != 1 {
+		t.Fatalf("totals = %v, want the to do", totals)
+	}
+
+	if seconds := totals[0]["seconds"].(int64); seconds < 590 || seconds > 660 {
+		t.Errorf("total seconds = %d, want about 600", seconds)
+	}
+}
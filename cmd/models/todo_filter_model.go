@@ -0,0 +1,289 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+const (
+	TODO_STATE_ALL       = "all"
+	TODO_STATE_OPEN      = "open"
+	TODO_STATE_COMPLETED = "completed"
+
+	MAX_TODOS_PER_PAGE = 100
+)
+
+// Column and natural direction of each sort
+var todoSorts = map[string][2]string{
+	"deadline": {"deadline", "ASC"},
+	"priority": {"priority", "DESC"},
+	"created":  {"created_at", "DESC"},
+	"updated":  {"COALESCE(updated_at, created_at)", "DESC"},
+	"manual":   {"position", "ASC"},
+	// Only for the archive
+	"archived": {"archived_at", "DESC"},
+}
+
+type ToDoFilter struct {
+	// open, completed or all
+	State string
+	// One of the keys of todoSorts
+	Sort string
+	// asc or desc, empty uses the natural order of the sort
+	Order string
+	// To dos with any or all of these tags, see TagMatch
+	Tags     []int64
+	TagMatch string
+	Color    *uint
+	// Deadline range, both ends included
+	DeadlineFrom *time.Time
+	DeadlineTo   *time.Time
+	// Open to dos whose deadline already passed
+	Overdue bool
+	// Text searched in the title and the description
+	Search string
+	// Page size, zero returns every to do
+	Limit int
+	// next_cursor of the previous page
+	Cursor string
+	// Lists the archive instead of the to dos in use
+	Archived bool
+	// Lists the to dos of a shared list instead of the ones of the user
+	List int64
+	// Member the to dos are assigned to, zero means anyone
+	AssignedTo int64
+	// Lists the to dos of a workspace instead of the personal ones of the user
+	Workspace int64
+
+	after *toDoCursor
+}
+
+// Page of a to do listing, NextCursor is empty on the last page
+type ToDoPage struct {
+	ToDos      []map[string]any `json:"todos"`
+	NextCursor string           `json:"next_cursor"`
+	Total      int              `json:"total"`
+}
+
+// Position of the last to do of a page, it is only valid with the sort it was made with
+type toDoCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	Id    int64  `json:"id"`
+}
+
+func (f *ToDoFilter) Validate() bool {
+	if f.State == "" {
+		f.State = TODO_STATE_ALL
+	}
+
+	switch f.State {
+	case TODO_STATE_ALL, TODO_STATE_OPEN, TODO_STATE_COMPLETED:
+	default:
+		return false
+	}
+
+	if f.Sort == "" {
+		f.Sort = "deadline"
+
+		if f.Archived {
+			f.Sort = "archived"
+		}
+	}
+
+	if _, ok := todoSorts[f.Sort]; !ok || (f.Sort == "archived" && !f.Archived) {
+		return false
+	}
+
+	if f.Order != "" && f.Order != "asc" && f.Order != "desc" {
+		return false
+	}
+
+	if f.TagMatch == "" {
+		f.TagMatch = TAG_MATCH_ANY
+	}
+
+	slices.Sort(f.Tags)
+	f.Tags = slices.Compact(f.Tags)
+
+	if f.TagMatch != TAG_MATCH_ANY && f.TagMatch != TAG_MATCH_ALL || len(f.Tags) > MAX_TAGS_PER_TODO {
+		return false
+	}
+
+	if f.DeadlineFrom != nil && f.DeadlineTo != nil && f.DeadlineTo.Before(*f.DeadlineFrom) {
+		return false
+	}
+
+	f.Search = NormalizeText(f.Search)
+
+	return f.Limit >= 0 && f.Limit <= MAX_TODOS_PER_PAGE
+}
+
+// Decodes the cursor, it fails when it was made with another sort or order
+func (f *ToDoFilter) ValidateCursor() error {
+	if f.Cursor == "" {
+		return nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	cursor := toDoCursor{}
+
+	if err != nil || json.Unmarshal(raw, &cursor) != nil {
+		return locales.New("invalid_cursor")
+	}
+
+	if cursor.Sort != f.Sort || cursor.Order != f.direction() {
+		return locales.New("invalid_cursor")
+	}
+
+	if _, err := cursor.arg(); err != nil {
+		return locales.New("invalid_cursor")
+	}
+
+	f.after = &cursor
+	return nil
+}
+
+func (f *ToDoFilter) direction() string {
+	if f.Order != "" {
+		return strings.ToUpper(f.Order)
+	}
+
+	return todoSorts[f.Sort][1]
+}
+
+// Conditions of the filter, without the cursor so they can be used for the total too
+func (f *ToDoFilter) where() (string, []any) {
+	query := " AND archived_at IS NULL"
+	args := make([]any, 0)
+
+	if f.Archived {
+		query = " AND archived_at IS NOT NULL"
+	}
+
+	switch f.State {
+	case TODO_STATE_OPEN:
+		query += " AND completed = 0"
+	case TODO_STATE_COMPLETED:
+		query += " AND completed = 1"
+	}
+
+	if len(f.Tags) != 0 {
+		tagged := "FROM todo_tags tt WHERE tt.id_todo = todos.id_todo AND tt.id_tag IN (" + placeholders(len(f.Tags)) + ")"
+
+		if f.TagMatch == TAG_MATCH_ALL {
+			query += " AND (SELECT COUNT(DISTINCT tt.id_tag) " + tagged + ") = ?"
+		} else {
+			query += " AND EXISTS (SELECT 1 " + tagged + ")"
+		}
+
+		for _, tag := range f.Tags {
+			args = append(args, tag)
+		}
+
+		if f.TagMatch == TAG_MATCH_ALL {
+			args = append(args, len(f.Tags))
+		}
+	}
+
+	if f.Color != nil {
+		query += " AND color = ?"
+		args = append(args, *f.Color)
+	}
+
+	if f.DeadlineFrom != nil {
+		query += " AND deadline >= ?"
+		args = append(args, *f.DeadlineFrom)
+	}
+
+	if f.DeadlineTo != nil {
+		query += " AND deadline <= ?"
+		args = append(args, *f.DeadlineTo)
+	}
+
+	if f.Overdue {
+		// Deadlines are written in UTC, now() would be in the zone of the database
+		query += " AND completed = 0 AND deadline < ?"
+		args = append(args, time.Now().UTC())
+	}
+
+	if f.AssignedTo != 0 {
+		query += " AND assigned_to = ?"
+		args = append(args, f.AssignedTo)
+	}
+
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		query += " AND (title LIKE ? OR description LIKE ?)"
+		args = append(args, pattern, pattern)
+	}
+
+	return query, args
+}
+
+// Keyset condition that starts the page right after the cursor, ties are broken by id
+func (f *ToDoFilter) afterCursor() (string, []any) {
+	if f.after == nil {
+		return "", nil
+	}
+
+	column := todoSorts[f.Sort][0]
+	comparison := ">"
+	if f.direction() == "DESC" {
+		comparison = "<"
+	}
+
+	value, _ := f.after.arg()
+	return " AND (" + column + " " + comparison + " ? OR (" + column + " = ? AND id_todo > ?))", []any{value, value, f.after.Id}
+}
+
+func (f *ToDoFilter) orderBy() string {
+	return " ORDER BY " + todoSorts[f.Sort][0] + " " + f.direction() + ", id_todo ASC"
+}
+
+// Cursor pointing at the to do with the given id and values of the sort columns
+func (f *ToDoFilter) cursorAt(id int64, todo *ToDo, createdAt time.Time, updatedAt time.Time) string {
+	cursor := toDoCursor{
+		Sort:  f.Sort,
+		Order: f.direction(),
+		Id:    id,
+	}
+
+	switch f.Sort {
+	case "deadline":
+		cursor.Value = todo.Deadline.Format(time.RFC3339Nano)
+	case "created":
+		cursor.Value = createdAt.Format(time.RFC3339Nano)
+	case "updated":
+		cursor.Value = updatedAt.Format(time.RFC3339Nano)
+	case "archived":
+		cursor.Value = todo.ArchivedAt.Format(time.RFC3339Nano)
+	case "priority":
+		cursor.Value = strconv.Itoa(todo.Priority)
+	case "manual":
+		cursor.Value = strconv.FormatFloat(todo.Position, 'g', -1, 64)
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func (c *toDoCursor) arg() (any, error) {
+	switch c.Sort {
+	case "priority", "manual":
+		return strconv.ParseFloat(c.Value, 64)
+	default:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	}
+}
+
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
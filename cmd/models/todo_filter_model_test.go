@@ -0,0 +1,46 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// A database in a zone behind UTC must not hide the to dos that are already overdue
+func TestOverdueFilterIsUTC(t *testing.T) {
+	db := testDB(t)
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("SET time_zone = '-05:00';"); err != nil {
+		t.Skip("the database can't change its time zone:", err)
+	}
+
+	user := insertTestUser(t, db, "late")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	overdue := insertTestToDo(t, db, ToDo{Title: "overdue", CreatedBy: user, Deadline: now.Add(-time.Hour)})
+	insertTestToDo(t, db, ToDo{Title: "upcoming", CreatedBy: user, Deadline: now.Add(time.Hour)})
+
+	filter := ToDoFilter{State: TODO_STATE_ALL, Overdue: true}
+
+	if !filter.Validate() {
+		t.Fatal("the filter is not valid")
+	}
+
+	todo := ToDo{CreatedBy: user}
+	page, err := todo.GetAllToDosFromUserId(filter, db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int64, 0)
+
+	for _, found := range page.ToDos {
+		ids = append(ids, found["id"].(int64))
+	}
+
+	if !slices.Equal(ids, []int64{overdue}) {
+		t.Errorf("overdue to dos = %v, want [%d]", ids, overdue)
+	}
+}
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+const MAX_ITEMS_PER_TODO = 50
+
+type ToDoItem struct {
+	Text      string `json:"text"`
+	Done      bool   `json:"done"`
+	Position  int    `json:"position"`
+	ToDo      int64  `json:"todo"`
+	CreatedBy int64  `json:"created_by"`
+}
+
+func (i *ToDoItem) ValidateText() bool {
+	i.Text = NormalizeText(i.Text)
+	return ValidateField(FIELD_TODO_ITEM_TEXT, i.Text)
+}
+
+// Checks the user is active and owns the to do of the item
+func (i *ToDoItem) CheckToDoIsOwned(db *sql.DB) error {
+	todo := ToDo{CreatedBy: i.CreatedBy}
+
+	if active, err := todo.CheckUserIsActive(db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	if owned, err := todo.ToDoIsOwned(i.ToDo, db); !owned || err != nil {
+		return locales.New("todo_not_found")
+	}
+
+	return nil
+}
+
+func (i *ToDoItem) CountItemsPerToDo(db *sql.DB) (int, error) {
+	stm, err := db.Prepare("SELECT COUNT(*) FROM todo_items WHERE id_todo = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	count := -1
+	row := stm.QueryRow(i.ToDo)
+	err = row.Scan(&count)
+
+	return count, err
+}
+
+// Appends the item at the end of the checklist
+func (i *ToDoItem) InsertItem(db *sql.DB) (int64, error) {
+	if err := i.CheckToDoIsOwned(db); err != nil {
+		return -1, err
+	}
+
+	count, err := i.CountItemsPerToDo(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_ITEMS_PER_TODO {
+		return -1, locales.New("items_limit")
+	}
+
+	stm, err := db.Prepare("INSERT INTO todo_items (id_todo, text, done, position) SELECT ?, ?, ?, COALESCE(MAX(position), -1) + 1 FROM todo_items WHERE id_todo = ? AND status = 1;")
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(i.ToDo, i.Text, i.Done, i.ToDo)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return res.LastInsertId()
+}
+
+func (i *ToDoItem) UpdateItemById(id int64, delete bool, db *sql.DB) error {
+	if err := i.CheckToDoIsOwned(db); err != nil {
+		return err
+	}
+
+	var query string
+	var errorMsg error
+	var args []any
+
+	if delete {
+		errorMsg = locales.New("item_delete_failed")
+		query = "UPDATE todo_items SET status = 0, updated_at = now() WHERE id_item = ? AND id_todo = ? AND status = 1 LIMIT 1;"
+		args = []any{id, i.ToDo}
+	} else {
+		errorMsg = locales.New("item_update_failed")
+		query = "UPDATE todo_items SET text = ?, done = ?, updated_at = now() WHERE id_item = ? AND id_todo = ? AND status = 1 LIMIT 1;"
+		args = []any{i.Text, i.Done, id, i.ToDo}
+	}
+
+	stm, err := db.Prepare(query)
+
+	if err != nil {
+		return errorMsg
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(args...)
+
+	if err != nil {
+		return errorMsg
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("item_not_found")
+	}
+
+	return nil
+}
+
+// Sets the position of every item following the given order, all the items of the to do must be listed
+func (i *ToDoItem) ReorderItems(order []int64, db *sql.DB) error {
+	if err := i.CheckToDoIsOwned(db); err != nil {
+		return err
+	}
+
+	// Every item must appear once, a repeated id would leave another one out
+	seen := make(map[int64]bool, len(order))
+
+	for _, id := range order {
+		if seen[id] {
+			return locales.New("invalid_order")
+		}
+		seen[id] = true
+	}
+
+	count, err := i.CountItemsPerToDo(db)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if count != len(order) {
+		return locales.New("invalid_order")
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	stm, err := tx.Prepare("UPDATE todo_items SET position = ? WHERE id_item = ? AND id_todo = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	for position, id := range order {
+		res, err := stm.Exec(position, id, i.ToDo)
+
+		if err != nil {
+			return locales.New("internal_error")
+		}
+
+		// MySQL reports 0 affected rows when the position didn't change, so the id is checked apart
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			if exists, err := i.itemExists(tx, id); !exists || err != nil {
+				return locales.New("invalid_order")
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+func (i *ToDoItem) itemExists(tx *sql.Tx, id int64) (bool, error) {
+	count := 0
+	row := tx.QueryRow("SELECT COUNT(*) FROM todo_items WHERE id_item = ? AND id_todo = ? AND status = 1 LIMIT 1;", id, i.ToDo)
+	err := row.Scan(&count)
+
+	return count == 1, err
+}
+
+func (i *ToDoItem) GetAllItemsFromToDo(db *sql.DB) ([]map[string]any, error) {
+	if err := i.CheckToDoIsOwned(db); err != nil {
+		return nil, err
+	}
+
+	stm, err := db.Prepare("SELECT id_item, text, done, position FROM todo_items WHERE id_todo = ? AND status = 1 ORDER BY position ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(i.ToDo)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	items := make([]map[string]any, 0)
+
+	for rows.Next() {
+		item := ToDoItem{ToDo: i.ToDo}
+		var id int64 = 0
+
+		err = rows.Scan(&id, &item.Text, &item.Done, &item.Position)
+
+		if err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		items = append(items, map[string]any{
+			"id":       id,
+			"text":     item.Text,
+			"done":     item.Done,
+			"position": item.Position,
+			"todo":     item.ToDo,
+		})
+	}
+
+	return items, nil
+}
@@ -2,28 +2,97 @@ package models
 
 import (
 	"database/sql"
-	"errors"
-	"regexp"
 	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/recurrence"
 )
 
+// New to dos go at the end of the manual order of the user, the last argument is created_by again
+const insertToDoQuery = "INSERT INTO todos (title, description, color, deadline, tag, created_by, recurrence, timezone, priority, id_list, assigned_to, id_workspace, position) " +
+	"SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0), COALESCE(MAX(position), 0) + 1 FROM todos WHERE created_by = ?;"
+
 type ToDo struct {
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Color       uint      `json:"color"`
 	Deadline    time.Time `json:"deadline"`
-	Tag         int64     `json:"tag"`
-	CreatedBy   int64     `json:"created_by"`
+	// First of Tags, kept for the clients of a single tag
+	Tag         int64      `json:"tag"`
+	Tags        []int64    `json:"tags"`
+	CreatedBy   int64      `json:"created_by"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	// RRULE subset, see the recurrence package. Empty for one-off to dos
+	Recurrence string `json:"recurrence"`
+	// IANA zone the recurrence is computed in
+	Timezone string `json:"timezone"`
+	Priority int    `json:"priority"`
+	// Manual order, only compared between the to dos of the same user
+	Position float64 `json:"position"`
+	// Archived to dos are kept out of the listings and the quota
+	ArchivedAt *time.Time `json:"archived_at"`
+	// Shared list the to do is in and the member it is assigned to, 0 when none
+	List       int64 `json:"list"`
+	AssignedTo int64 `json:"assigned_to"`
+	// Workspace the to do belongs to, 0 for the personal ones. It can't change once created
+	Workspace int64 `json:"workspace"`
+	// Board column the to do is in, 0 when it is not on the board
+	Column int64 `json:"column"`
+	// Member of the list making the change when it is not the creator of the to do
+	Actor int64 `json:"-"`
+	// Optional fields the request had, an update keeps the stored value of the others.
+	// Nil when the to do doesn't come from a request, then every field is written
+	Sent map[string]bool `json:"-"`
+}
+
+const (
+	PRIORITY_NONE   = 0
+	PRIORITY_LOW    = 1
+	PRIORITY_MEDIUM = 2
+	PRIORITY_HIGH   = 3
+)
+
+// The optional field was left out of the request, so its stored value stays
+func (t *ToDo) keeps(field string) bool {
+	return t.Sent != nil && !t.Sent[field]
 }
 
 func (t *ToDo) ValidateTitle() (bool, error) {
-	regex := "^[A-Za-z ]{3,15}$"
-	return regexp.Match(regex, []byte(t.Title))
+	t.Title = NormalizeText(t.Title)
+	return ValidateField(FIELD_TODO_TITLE, t.Title), nil
 }
 
 func (t *ToDo) ValidateDescription() (bool, error) {
-	regex := "^[A-Za-z0-9 ]{0,100}$"
-	return regexp.Match(regex, []byte(t.Description))
+	t.Description = NormalizeText(t.Description)
+	return ValidateField(FIELD_TODO_DESCRIPTION, t.Description), nil
+}
+
+func (t *ToDo) ValidatePriority() bool {
+	return t.Priority >= PRIORITY_NONE && t.Priority <= PRIORITY_HIGH
+}
+
+func (t *ToDo) ValidateRecurrence() bool {
+	if t.Timezone == "" {
+		t.Timezone = "UTC"
+	}
+
+	if _, err := time.LoadLocation(t.Timezone); err != nil {
+		return false
+	}
+
+	if t.Recurrence == "" {
+		return true
+	}
+
+	rule, err := recurrence.Parse(t.Recurrence)
+
+	if err != nil {
+		return false
+	}
+
+	t.Recurrence = rule.String()
+	return true
 }
 
 func (t *ToDo) CheckUserIsActive(db *sql.DB) (bool, error) {
@@ -45,10 +114,10 @@ func (t *ToDo) VerifyUserIsPremium(db *sql.DB) (bool, error) {
 
 func (t *ToDo) ToDoExists(id int64, db *sql.DB) (bool, error) {
 	stm, err := db.Prepare("SELECT COUNT(*) FROM todos WHERE id_todo = ? AND status = 1 LIMIT 1;")
-	notFound := errors.New("to do not found")
+	notFound := locales.New("todo_not_found")
 
 	if err != nil {
-		return false, errors.New("internal server error")
+		return false, locales.New("internal_error")
 	}
 	defer stm.Close()
 
@@ -63,11 +132,31 @@ func (t *ToDo) ToDoExists(id int64, db *sql.DB) (bool, error) {
 	return holder > 0, nil
 }
 
+// Checks the to do exists and belongs to t.CreatedBy
+func (t *ToDo) ToDoIsOwned(id int64, db *sql.DB) (bool, error) {
+	stm, err := db.Prepare("SELECT COUNT(*) FROM todos WHERE id_todo = ? AND created_by = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return false, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	holder := -1
+	row := stm.QueryRow(id, t.CreatedBy)
+	err = row.Scan(&holder)
+
+	if err != nil {
+		return false, locales.New("todo_not_found")
+	}
+
+	return holder > 0, nil
+}
+
 func (t *ToDo) CountToDosPerUserId(db *sql.DB) (int, error) {
-	stm, err := db.Prepare("SELECT COUNT(*) AS count FROM todos WHERE created_by = ? AND status = 1 LIMIT 1;")
+	stm, err := db.Prepare("SELECT COUNT(*) AS count FROM todos WHERE created_by = ? AND id_workspace IS NULL AND status = 1 AND completed = 0 AND archived_at IS NULL LIMIT 1;")
 
 	if err != nil {
-		return -1, errors.New("internal server error")
+		return -1, locales.New("internal_error")
 	}
 	defer stm.Close()
 
@@ -78,17 +167,27 @@ func (t *ToDo) CountToDosPerUserId(db *sql.DB) (int, error) {
 	return count, err
 }
 
-func (t *ToDo) InsertToDo(db *sql.DB) (int64, error) {
+// Checks the user can have one more active to do, the ones of a workspace count against its plan
+func (t *ToDo) CheckQuota(db *sql.DB) error {
+	if t.Workspace != 0 {
+		return CheckWorkspaceQuota(t.Workspace, t.CreatedBy, WORKSPACE_TODOS, db)
+	}
+
+	return t.checkPersonalQuota(1, db)
+}
+
+// Checks the user can have n more active personal to dos
+func (t *ToDo) checkPersonalQuota(n int, db *sql.DB) error {
 	maxTodoCount := 20
 
 	if active, err := t.CheckUserIsActive(db); !active || err != nil {
-		return -1, errors.New("invalid user")
+		return locales.New("invalid_user")
 	}
 
 	premiumUser, err := t.VerifyUserIsPremium(db)
 
 	if err != nil {
-		return -1, errors.New("invalid user")
+		return locales.New("invalid_user")
 	}
 
 	if premiumUser {
@@ -98,22 +197,59 @@ func (t *ToDo) InsertToDo(db *sql.DB) (int64, error) {
 	count, err := t.CountToDosPerUserId(db)
 
 	if err != nil {
+		return err
+	}
+
+	if count+n > maxTodoCount {
+		return locales.New("todos_limit")
+	}
+
+	return nil
+}
+
+func (t *ToDo) InsertToDo(db *sql.DB) (int64, error) {
+	if err := t.CheckQuota(db); err != nil {
 		return -1, err
 	}
 
-	if count >= maxTodoCount {
-		return -1, errors.New("to dos limit exceeded")
+	if t.List != 0 {
+		if t.Workspace != 0 {
+			return -1, locales.New("todo_in_workspace")
+		}
+
+		if err := CheckListRole(t.List, t.CreatedBy, ROLE_EDITOR, db); err != nil {
+			return -1, err
+		}
+	}
+
+	if err := t.CheckTagsAreOwned(db); err != nil {
+		return -1, err
 	}
 
-	var query = "INSERT INTO todos (title, description, color, deadline, tag, created_by) VALUES ( ?, ?, ?, ?, ?, ?);"
-	stm, err := db.Prepare(query)
+	tx, err := db.Begin()
 
 	if err != nil {
 		return -1, err
 	}
-	defer stm.Close()
+	defer tx.Rollback()
 
-	res, err := stm.Exec(t.Title, t.Description, t.Color, t.Deadline, t.Tag, t.CreatedBy)
+	insertId, err := t.insertToDo(tx)
+
+	if err != nil {
+		return -1, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, err
+	}
+
+	return insertId, nil
+}
+
+// Writes the to do with its tags and first revision inside tx, the caller checks
+// the quota, the list and the tags beforehand
+func (t *ToDo) insertToDo(tx *sql.Tx) (int64, error) {
+	res, err := tx.Exec(insertToDoQuery, t.Title, t.Description, t.Color, t.Deadline, t.Tag, t.CreatedBy, t.Recurrence, t.Timezone, t.Priority, t.List, t.AssignedTo, t.Workspace, t.CreatedBy)
 
 	if err != nil {
 		return -1, err
@@ -125,50 +261,119 @@ func (t *ToDo) InsertToDo(db *sql.DB) (int64, error) {
 		return -1, err
 	}
 
+	if err := t.setTags(insertId, tx); err != nil {
+		return -1, err
+	}
+
+	if err := t.recordChange(tx, insertId, REVISION_CREATED, nil); err != nil {
+		return -1, err
+	}
+
 	return insertId, nil
 }
 
 func (t *ToDo) UpdateToDoById(id int64, delete bool, db *sql.DB) error {
 	if active, err := t.CheckUserIsActive(db); !active || err != nil {
-		return errors.New("invalid user")
+		return locales.New("invalid_user")
 	}
 
 	if exists, err := t.ToDoExists(id, db); !exists || err != nil {
-		return errors.New("to do not found")
+		return locales.New("todo_not_found")
 	}
 
-	var query string
-	var errorMsg string
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return err
+	}
 
 	if delete {
-		errorMsg = "Couldnt delete to do"
-		query = "UPDATE todos SET status = 0, updated_at = now() WHERE id_todo = ? AND created_by = ? LIMIT 1;"
-	} else {
-		errorMsg = "Coundnt update todo"
-		query = "UPDATE todos SET title = ?, description = ?, color = ?, deadline = ?, tag = ?, updated_at = now() WHERE id_todo = ? AND created_by = ? LIMIT 1;"
+		errorMsg := locales.New("todo_delete_failed")
+		tx, err := db.Begin()
+
+		if err != nil {
+			return errorMsg
+		}
+		defer tx.Rollback()
+
+		res, err := tx.Exec("UPDATE todos SET status = 0, deleted_at = now(), updated_at = now() WHERE id_todo = ? AND created_by = ? LIMIT 1;", id, t.CreatedBy)
+
+		if err != nil {
+			return errorMsg
+		}
+
+		if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+			return errorMsg
+		}
+
+		if err := t.recordState(tx, id, REVISION_DELETED, "deleted", false, true, &current); err != nil {
+			return errorMsg
+		}
+
+		if err := deleteItemsWhere(tx, "id_todo = ?", id); err != nil {
+			return errorMsg
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errorMsg
+		}
+
+		return nil
 	}
 
-	stm, err := db.Prepare(query)
+	errorMsg := locales.New("todo_update_failed")
+	t.Workspace = current.Workspace
+
+	if t.keeps("recurrence") {
+		t.Recurrence = current.Recurrence
+	}
+
+	if t.keeps("timezone") {
+		t.Timezone = current.Timezone
+	}
+
+	if t.keeps("priority") {
+		t.Priority = current.Priority
+	}
+
+	if t.keeps("tags") {
+		t.keepStoredTags(&current)
+	}
+
+	if err := t.CheckTagsAreOwned(db); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
 
 	if err != nil {
-		return errors.New(errorMsg)
+		return errorMsg
 	}
+	defer tx.Rollback()
 
-	defer stm.Close()
+	res, err := tx.Exec("UPDATE todos SET title = ?, description = ?, color = ?, deadline = ?, tag = ?, recurrence = ?, timezone = ?, priority = ?, updated_at = now() WHERE id_todo = ? AND created_by = ? LIMIT 1;",
+		t.Title, t.Description, t.Color, t.Deadline, t.Tag, t.Recurrence, t.Timezone, t.Priority, id, t.CreatedBy)
 
-	if delete {
-		_, err = stm.Exec(id, t.CreatedBy)
+	if err != nil {
+		return errorMsg
+	}
 
-		if err != nil {
-			return errors.New(errorMsg)
-		}
-	} else {
-		res, _ := stm.Exec(t.Title, t.Description, t.Color, t.Deadline, t.Tag, id, t.CreatedBy)
-		affected, err := res.RowsAffected()
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return errorMsg
+	}
 
-		if affected != 1 || err != nil {
-			return errors.New(errorMsg)
-		}
+	if err := t.setTags(id, tx); err != nil {
+		return errorMsg
+	}
+
+	prev := current.snapshot()
+
+	if err := t.recordChange(tx, id, REVISION_UPDATED, &prev); err != nil {
+		return errorMsg
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorMsg
 	}
 
 	return nil
@@ -176,12 +381,12 @@ func (t *ToDo) UpdateToDoById(id int64, delete bool, db *sql.DB) error {
 
 func (t *ToDo) DeleteAllToDosFromUserId(db *sql.DB) error {
 	if active, err := t.CheckUserIsActive(db); !active || err != nil {
-		return errors.New("invalid user")
+		return locales.New("invalid_user")
 	}
 
-	stm, err := db.Prepare("UPDATE todos SET status = 0 WHERE created_by = ? AND status = 1;")
+	stm, err := db.Prepare("UPDATE todos SET status = 0, deleted_at = now() WHERE created_by = ? AND status = 1;")
 	if err != nil {
-		return errors.New("error deleting")
+		return locales.New("delete_failed")
 	}
 
 	defer stm.Close()
@@ -189,77 +394,467 @@ func (t *ToDo) DeleteAllToDosFromUserId(db *sql.DB) error {
 	_, err = stm.Exec(t.CreatedBy)
 
 	if err != nil {
-		err = errors.New("error deleting")
+		return locales.New("delete_failed")
+	}
+
+	return deleteItemsWhere(db, "created_by = ?", t.CreatedBy)
+}
+
+// Soft deletes the checklist items of the to dos matching the condition
+func deleteItemsWhere(db execer, condition string, args ...any) error {
+	_, err := db.Exec("UPDATE todo_items SET status = 0, updated_at = now() WHERE status = 1 AND id_todo IN (SELECT id_todo FROM todos WHERE "+condition+");", args...)
+
+	if err != nil {
+		err = locales.New("delete_failed")
 	}
 
 	return err
 }
 
-func (t *ToDo) DeleteAllToDosFromTagId(db *sql.DB) error {
+// Lists the to dos of the user that match the filter, a page at a time when the filter has a limit
+func (t *ToDo) GetAllToDosFromUserId(filter ToDoFilter, db *sql.DB) (ToDoPage, error) {
+	page := ToDoPage{ToDos: make([]map[string]any, 0)}
+
 	if active, err := t.CheckUserIsActive(db); !active || err != nil {
-		return errors.New("invalid user")
+		return page, locales.New("get_failed")
 	}
 
-	stm, err := db.Prepare("UPDATE todos SET status = 0 WHERE created_by = ? AND tag = ? AND status = 1;")
-	if err != nil {
-		return errors.New("error deleting")
-	}
+	// The to dos of a shared list or a workspace are listed for every member, whoever
+	// created them. The personal listing leaves the ones of the workspaces out
+	scope := " FROM todos WHERE created_by = ? AND status = 1"
+	args := []any{t.CreatedBy}
 
-	defer stm.Close()
+	if filter.List != 0 {
+		scope = " FROM todos WHERE id_list = ? AND status = 1"
+		args = []any{filter.List}
+	} else {
+		if filter.Workspace != 0 {
+			if err := CheckWorkspaceRole(filter.Workspace, t.CreatedBy, ROLE_VIEWER, db); err != nil {
+				return page, err
+			}
 
-	_, err = stm.Exec(t.CreatedBy, t.Tag)
+			scope = " FROM todos WHERE status = 1"
+			args = nil
+		}
 
-	if err != nil {
-		err = errors.New("error deleting")
+		condition, workspaceArgs := workspaceScope(filter.Workspace)
+		scope += condition
+		args = append(args, workspaceArgs...)
 	}
 
-	return err
-}
+	where, whereArgs := filter.where()
+	args = append(args, whereArgs...)
+	conditions := scope + where
 
-func (t *ToDo) GetAllToDosFromUserId(db *sql.DB) ([]map[string]any, error) {
-	if active, err := t.CheckUserIsActive(db); !active || err != nil {
-		return nil, errors.New("couldnt get")
+	row := db.QueryRow("SELECT COUNT(*)"+conditions+";", args...)
+
+	if err := row.Scan(&page.Total); err != nil {
+		return page, locales.New("internal_error")
+	}
+
+	after, afterArgs := filter.afterCursor()
+	args = append(args, afterArgs...)
+	query := "SELECT id_todo, title, description, color, deadline, tag, created_by, completed, completed_at, recurrence, timezone, priority, position, created_at, COALESCE(updated_at, created_at), archived_at, COALESCE(id_list, 0), COALESCE(assigned_to, 0), COALESCE(id_workspace, 0), COALESCE(id_column, 0), " +
+		blockedQuery + " AS blocked, " +
+		"(SELECT COUNT(*) FROM todo_items i WHERE i.id_todo = todos.id_todo AND i.status = 1) AS items_total, " +
+		"(SELECT COUNT(*) FROM todo_items i WHERE i.id_todo = todos.id_todo AND i.status = 1 AND i.done = 1) AS items_done, " +
+		"(SELECT GROUP_CONCAT(tt.id_tag ORDER BY tt.id_tag) FROM todo_tags tt WHERE tt.id_todo = todos.id_todo) AS tags" +
+		conditions + after + filter.orderBy()
+
+	// One more row than asked tells if there is a next page
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit+1)
 	}
 
-	stm, err := db.Prepare("SELECT id_todo, title, description, color, deadline, tag, created_by FROM todos WHERE created_by = ? AND status = 1 ORDER BY deadline ASC;")
+	stm, err := db.Prepare(query + ";")
 	if err != nil {
-		return nil, errors.New("couldnt get")
+		return page, locales.New("get_failed")
 	}
 
 	defer stm.Close()
 
-	rows, err := stm.Query(t.CreatedBy)
+	rows, err := stm.Query(args...)
 
 	if err != nil {
-		err = errors.New("internal server error")
-		return nil, err
+		err = locales.New("internal_error")
+		return page, err
 	}
+	defer rows.Close()
 
-	todos := make([]map[string]any, 0)
+	cursor := ""
 
 	for rows.Next() {
 		todo := ToDo{CreatedBy: t.CreatedBy}
 		var id int64 = 0
+		var completedAt, archivedAt sql.NullTime
+		var tags sql.NullString
+		var createdAt, updatedAt time.Time
+		itemsTotal, itemsDone := 0, 0
+		blocked := false
 
-		err = rows.Scan(&id, &todo.Title, &todo.Description, &todo.Color, &todo.Deadline, &todo.Tag, &todo.CreatedBy)
+		err = rows.Scan(&id, &todo.Title, &todo.Description, &todo.Color, &todo.Deadline, &todo.Tag, &todo.CreatedBy, &todo.Completed, &completedAt, &todo.Recurrence, &todo.Timezone, &todo.Priority, &todo.Position, &createdAt, &updatedAt, &archivedAt, &todo.List, &todo.AssignedTo, &todo.Workspace, &todo.Column, &blocked, &itemsTotal, &itemsDone, &tags)
 
 		if err != nil {
-			err = errors.New("internal server error scanning")
-			return nil, err
+			err = locales.New("internal_error")
+			return page, err
+		}
+
+		if filter.Limit > 0 && len(page.ToDos) == filter.Limit {
+			page.NextCursor = cursor
+			break
+		}
+
+		if completedAt.Valid {
+			todo.CompletedAt = &completedAt.Time
+		}
+
+		todo.Tags = parseIdList(tags)
+
+		if archivedAt.Valid {
+			todo.ArchivedAt = &archivedAt.Time
 		}
 
 		todoMap := map[string]any{
-			"id":          id,
-			"title":       todo.Title,
-			"deadline":    todo.Deadline,
-			"color":       todo.Color,
-			"description": todo.Description,
-			"tag":         todo.Tag,
-			"created_by":  todo.CreatedBy,
+			"id":           id,
+			"title":        todo.Title,
+			"deadline":     todo.Deadline,
+			"color":        todo.Color,
+			"description":  todo.Description,
+			"tag":          todo.Tag,
+			"tags":         todo.Tags,
+			"created_by":   todo.CreatedBy,
+			"completed":    todo.Completed,
+			"completed_at": todo.CompletedAt,
+			"recurrence":   todo.Recurrence,
+			"timezone":     todo.Timezone,
+			"priority":     todo.Priority,
+			"position":     todo.Position,
+			"created_at":   createdAt,
+			"updated_at":   updatedAt,
+			"archived_at":  todo.ArchivedAt,
+			"list":         todo.List,
+			"assigned_to":  todo.AssignedTo,
+			"workspace":    todo.Workspace,
+			"column":       todo.Column,
+			"blocked":      blocked,
+			"items_total":  itemsTotal,
+			"items_done":   itemsDone,
 		}
 
-		todos = append(todos, todoMap)
+		page.ToDos = append(page.ToDos, todoMap)
+
+		if filter.Limit > 0 {
+			cursor = filter.cursorAt(id, &todo, createdAt, updatedAt)
+		}
 	}
 
-	return todos, nil
+	return page, nil
+}
+
+// Loads the to do into t, it must belong to t.CreatedBy
+func (t *ToDo) GetToDoById(id int64, db *sql.DB) error {
+	return t.loadToDo(id, 1, db)
+}
+
+// Loads the to do with the given status, 0 for the ones in the trash
+func (t *ToDo) loadToDo(id int64, status int, db *sql.DB) error {
+	stm, err := db.Prepare("SELECT title, description, color, deadline, tag, completed, recurrence, timezone, priority, position, COALESCE(id_list, 0), COALESCE(assigned_to, 0), COALESCE(id_workspace, 0), COALESCE(id_column, 0) FROM todos WHERE id_todo = ? AND created_by = ? AND status = ? LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	row := stm.QueryRow(id, t.CreatedBy, status)
+	err = row.Scan(&t.Title, &t.Description, &t.Color, &t.Deadline, &t.Tag, &t.Completed, &t.Recurrence, &t.Timezone, &t.Priority, &t.Position, &t.List, &t.AssignedTo, &t.Workspace, &t.Column)
+
+	if err != nil {
+		return locales.New("todo_not_found")
+	}
+
+	// The first tag is the one in todos.tag
+	rows, err := db.Query("SELECT tt.id_tag FROM todo_tags tt WHERE tt.id_todo = ? ORDER BY tt.id_tag = ? DESC, tt.id_tag ASC;", id, t.Tag)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	t.Tags = make([]int64, 0)
+
+	for rows.Next() {
+		var tag int64
+		if err := rows.Scan(&tag); err != nil {
+			return locales.New("internal_error")
+		}
+		t.Tags = append(t.Tags, tag)
+	}
+
+	return nil
+}
+
+// Marks the to do as completed or open again, reopening it counts against the quota again.
+// Completing an occurrence of a recurring to do creates the next one, whose id is returned,
+// only the first time it is completed
+func (t *ToDo) SetCompleted(id int64, completed bool, db *sql.DB) (int64, error) {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return 0, locales.New("invalid_user")
+	}
+
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return 0, err
+	}
+
+	var query string
+
+	if completed {
+		query = "UPDATE todos SET completed = 1, completed_at = now(), updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 AND completed = 0 LIMIT 1;"
+	} else {
+		if err := current.CheckQuota(db); err != nil {
+			return 0, err
+		}
+
+		query = "UPDATE todos SET completed = 0, completed_at = NULL, updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 AND completed = 1 LIMIT 1;"
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, id, t.CreatedBy)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return 0, locales.New("todo_not_found")
+	}
+
+	action := REVISION_REOPENED
+	if completed {
+		action = REVISION_COMPLETED
+	}
+
+	if err := t.recordState(tx, id, action, "completed", !completed, completed, &current); err != nil {
+		return 0, err
+	}
+
+	var nextId int64 = 0
+
+	if completed && current.Recurrence != "" {
+		nextId, err = current.insertNextOccurrenceOnce(id, tx)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	return nextId, nil
+}
+
+// Archives the to do or takes it out of the archive, where it doesn't count against the
+// quota. Taking an open to do out of the archive checks the quota again
+func (t *ToDo) SetArchived(id int64, archived bool, db *sql.DB) error {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return err
+	}
+
+	var query string
+
+	if archived {
+		query = "UPDATE todos SET archived_at = now(), updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 AND archived_at IS NULL LIMIT 1;"
+	} else {
+		if !current.Completed {
+			if err := current.CheckQuota(db); err != nil {
+				return err
+			}
+		}
+
+		query = "UPDATE todos SET archived_at = NULL, updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 AND archived_at IS NOT NULL LIMIT 1;"
+	}
+
+	stm, err := db.Prepare(query)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(id, t.CreatedBy)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		if archived {
+			return locales.New("todo_archived")
+		}
+
+		return locales.New("todo_not_archived")
+	}
+
+	action := REVISION_UNARCHIVED
+	if archived {
+		action = REVISION_ARCHIVED
+	}
+
+	return t.recordState(db, id, action, "archived", !archived, archived, &current)
+}
+
+// Completing the occurrence again after reopening it doesn't create another one, the
+// row remembers the one it already created even when that one was deleted since
+func (t *ToDo) insertNextOccurrenceOnce(id int64, tx *sql.Tx) (int64, error) {
+	var created sql.NullInt64
+
+	if err := tx.QueryRow("SELECT next_occurrence FROM todos WHERE id_todo = ? LIMIT 1;", id).Scan(&created); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	if created.Valid {
+		return 0, nil
+	}
+
+	nextId, err := t.insertNextOccurrence(id, tx)
+
+	if err != nil || nextId == 0 {
+		return nextId, err
+	}
+
+	if _, err := tx.Exec("UPDATE todos SET next_occurrence = ? WHERE id_todo = ? LIMIT 1;", nextId, id); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	return nextId, nil
+}
+
+// Creates the occurrence that follows the to do id, with its checklist unchecked and
+// its reminders pending again. It takes the place of the completed one so it skips
+// the quota, a series never has more than one open occurrence. Returns 0 when the
+// series is over
+func (t *ToDo) insertNextOccurrence(id int64, tx *sql.Tx) (int64, error) {
+	rule, err := recurrence.Parse(t.Recurrence)
+
+	if err != nil {
+		return 0, locales.New("invalid_recurrence")
+	}
+
+	loc, err := time.LoadLocation(t.Timezone)
+
+	if err != nil {
+		loc = time.UTC
+	}
+
+	deadline, nextRule, ok := rule.Next(t.Deadline, loc)
+
+	if !ok {
+		return 0, nil
+	}
+
+	res, err := tx.Exec(insertToDoQuery, t.Title, t.Description, t.Color, deadline, t.Tag, t.CreatedBy, nextRule.String(), t.Timezone, t.Priority, t.List, t.AssignedTo, t.Workspace, t.CreatedBy)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	nextId, err := res.LastInsertId()
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	_, err = tx.Exec("INSERT INTO todo_items (id_todo, text, done, position) SELECT ?, text, 0, position FROM todo_items WHERE id_todo = ? AND status = 1;", nextId, id)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	_, err = tx.Exec("INSERT INTO todo_tags (id_todo, id_tag) SELECT ?, id_tag FROM todo_tags WHERE id_todo = ?;", nextId, id)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	// Offsets already follow the new deadline, the times are moved as far as it moved
+	shift := int64(deadline.Sub(t.Deadline).Seconds())
+	_, err = tx.Exec("INSERT INTO reminders (id_todo, remind_at, offset_minutes, channel) SELECT ?, DATE_ADD(remind_at, INTERVAL ? SECOND), offset_minutes, channel FROM reminders WHERE id_todo = ? AND status = 1;", nextId, shift, id)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	next := *t
+	next.Deadline = deadline
+	next.Recurrence = nextRule.String()
+
+	if err := next.recordChange(tx, nextId, REVISION_CREATED, nil); err != nil {
+		return 0, err
+	}
+
+	return nextId, nil
+}
+
+// Moves the to do right after the to do after in the manual order, or to the top when after is 0.
+// Only the moved row is written, it gets a position between its new neighbours
+func (t *ToDo) MoveToDo(id int64, after int64, db *sql.DB) (float64, error) {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return 0, locales.New("invalid_user")
+	}
+
+	if owned, err := t.ToDoIsOwned(id, db); !owned || err != nil {
+		return 0, locales.New("todo_not_found")
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	position, err := t.manualOrder().move(id, after, tx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE todos SET position = ?, updated_at = now() WHERE id_todo = ? AND created_by = ? LIMIT 1;", position, id, t.CreatedBy)
+
+	if err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, locales.New("internal_error")
+	}
+
+	return position, nil
+}
+
+// The manual order of the to dos of the user
+func (t *ToDo) manualOrder() ordering {
+	return ordering{
+		table:    "todos",
+		id:       "id_todo",
+		position: "position",
+		scope:    "created_by = ? AND status = 1",
+		args:     []any{t.CreatedBy},
+	}
 }
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The fields of a legacy PUT, before recurrence, priority and tags existed
+func legacyFields() map[string]bool {
+	return map[string]bool{"title": true, "description": true, "color": true, "deadline": true, "created_by": true, "tag": true}
+}
+
+func TestUpdateToDoKeepsMissingFields(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "legacy")
+
+	id := insertTestToDo(t, db, ToDo{
+		Title:      "standup",
+		CreatedBy:  user,
+		Recurrence: "FREQ=DAILY",
+		Timezone:   "America/New_York",
+		Priority:   PRIORITY_HIGH,
+	})
+
+	update := ToDo{
+		Title:     "daily standup",
+		Deadline:  time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second),
+		CreatedBy: user,
+		Timezone:  "UTC",
+		Sent:      legacyFields(),
+	}
+
+	if err := update.UpdateToDoById(id, false, db); err != nil {
+		t.Fatalf("UpdateToDoById: %v", err)
+	}
+
+	stored := ToDo{CreatedBy: user}
+
+	if err := stored.GetToDoById(id, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Title != "daily standup" {
+		t.Errorf("title = %q, want the new one", stored.Title)
+	}
+
+	if stored.Recurrence != "FREQ=DAILY" || stored.Timezone != "America/New_York" {
+		t.Errorf("recurrence = %q in %q, want the stored ones", stored.Recurrence, stored.Timezone)
+	}
+
+	if stored.Priority != PRIORITY_HIGH {
+		t.Errorf("priority = %d, want the stored %d", stored.Priority, PRIORITY_HIGH)
+	}
+}
+
+func TestUpdateToDoWritesSentFields(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "current")
+
+	id := insertTestToDo(t, db, ToDo{Title: "standup", CreatedBy: user, Recurrence: "FREQ=DAILY", Priority: PRIORITY_HIGH})
+
+	sent := legacyFields()
+	sent["recurrence"], sent["priority"] = true, true
+
+	update := ToDo{
+		Title:     "standup",
+		Deadline:  time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second),
+		CreatedBy: user,
+		Timezone:  "UTC",
+		Priority:  PRIORITY_NONE,
+		Sent:      sent,
+	}
+
+	if err := update.UpdateToDoById(id, false, db); err != nil {
+		t.Fatalf("UpdateToDoById: %v", err)
+	}
+
+	stored := ToDo{CreatedBy: user}
+
+	if err := stored.GetToDoById(id, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Recurrence != "" || stored.Priority != PRIORITY_NONE {
+		t.Errorf("recurrence = %q, priority = %d, want them cleared", stored.Recurrence, stored.Priority)
+	}
+}
+
+func insertTestTag(t *testing.T, db *sql.DB, title string, user int64) int64 {
+	t.Helper()
+
+	tag := Tag{Title: title, CreatedBy: user}
+	id, err := tag.InsertTag(db)
+
+	if err != nil {
+		t.Fatalf("InsertTag(%q): %v", title, err)
+	}
+
+	return id
+}
+
+func TestUpdateToDoLegacyTag(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "legacy")
+	home := insertTestTag(t, db, "home", user)
+	work := insertTestTag(t, db, "work", user)
+	urgent := insertTestTag(t, db, "urgent", user)
+
+	tests := []struct {
+		name string
+		tag  int64
+		want []int64
+	}{
+		{"same first tag keeps all", home, []int64{home, work}},
+		{"no tag keeps all", 0, []int64{home, work}},
+		{"new tag replaces the first one", urgent, []int64{urgent, work}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id := insertTestToDo(t, db, ToDo{Title: "groceries", CreatedBy: user, Tags: []int64{home, work}})
+
+			update := ToDo{
+				Title:     "groceries",
+				Deadline:  time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second),
+				CreatedBy: user,
+				Tag:       test.tag,
+				Sent:      legacyFields(),
+			}
+
+			if !update.ValidateTags() {
+				t.Fatal("ValidateTags failed")
+			}
+
+			if err := update.UpdateToDoById(id, false, db); err != nil {
+				t.Fatalf("UpdateToDoById: %v", err)
+			}
+
+			stored := ToDo{CreatedBy: user}
+
+			if err := stored.GetToDoById(id, db); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(stored.Tags, test.want) || stored.Tag != test.want[0] {
+				t.Errorf("tags = %v, tag = %d, want %v", stored.Tags, stored.Tag, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateToDoReplacesSentTags(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "current")
+	home := insertTestTag(t, db, "home", user)
+	work := insertTestTag(t, db, "work", user)
+
+	id := insertTestToDo(t, db, ToDo{Title: "groceries", CreatedBy: user, Tags: []int64{home, work}})
+
+	sent := legacyFields()
+	sent["tags"] = true
+
+	update := ToDo{
+		Title:     "groceries",
+		Deadline:  time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second),
+		CreatedBy: user,
+		Tags:      []int64{work},
+		Sent:      sent,
+	}
+
+	if !update.ValidateTags() {
+		t.Fatal("ValidateTags failed")
+	}
+
+	if err := update.UpdateToDoById(id, false, db); err != nil {
+		t.Fatalf("UpdateToDoById: %v", err)
+	}
+
+	stored := ToDo{CreatedBy: user}
+
+	if err := stored.GetToDoById(id, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(stored.Tags, []int64{work}) {
+		t.Errorf("tags = %v, want [%d]", stored.Tags, work)
+	}
+}
+
+func TestCompleteAgainKeepsOneNextOccurrence(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "current")
+	id := insertTestToDo(t, db, ToDo{Title: "standup", CreatedBy: user, Recurrence: "FREQ=DAILY"})
+
+	todo := ToDo{CreatedBy: user}
+	next, err := todo.SetCompleted(id, true, db)
+
+	if err != nil || next == 0 {
+		t.Fatalf("SetCompleted = %d, %v, want the next occurrence", next, err)
+	}
+
+	if _, err := todo.SetCompleted(id, false, db); err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+
+	again, err := todo.SetCompleted(id, true, db)
+
+	if err != nil || again != 0 {
+		t.Errorf("completing again = %d, %v, want no new occurrence", again, err)
+	}
+
+	var count int
+
+	if err := db.QueryRow("SELECT COUNT(*) FROM todos WHERE created_by = ? AND title = 'standup';", user).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Errorf("the series has %d occurrences, want 2", count)
+	}
+
+	// The next occurrence makes its own successor when it is completed
+	after, err := todo.SetCompleted(next, true, db)
+
+	if err != nil || after == 0 || after == next {
+		t.Errorf("completing the next occurrence = %d, %v, want a new one", after, err)
+	}
+}
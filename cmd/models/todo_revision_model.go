@@ -0,0 +1,320 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Every change to a to do is kept in todo_revisions with who made it, the fields
+// that changed and the editable fields as they were left, so any revision can be restored
+const (
+	REVISION_CREATED    = "created"
+	REVISION_UPDATED    = "updated"
+	REVISION_COMPLETED  = "completed"
+	REVISION_REOPENED   = "reopened"
+	REVISION_ARCHIVED   = "archived"
+	REVISION_UNARCHIVED = "unarchived"
+	REVISION_DELETED    = "deleted"
+	REVISION_RESTORED   = "restored"
+	REVISION_UNDONE     = "undone"
+	REVISION_ASSIGNED   = "assigned"
+	REVISION_MOVED      = "moved"
+)
+
+// Editable fields of a to do
+type ToDoSnapshot struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Color       uint      `json:"color"`
+	Deadline    time.Time `json:"deadline"`
+	Tags        []int64   `json:"tags"`
+	Recurrence  string    `json:"recurrence"`
+	Timezone    string    `json:"timezone"`
+	Priority    int       `json:"priority"`
+}
+
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type ToDoRevision struct {
+	Id        int64                  `json:"id"`
+	ToDo      int64                  `json:"todo"`
+	ChangedBy int64                  `json:"changed_by"`
+	ChangedAt time.Time              `json:"changed_at"`
+	Action    string                 `json:"action"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Snapshot  ToDoSnapshot           `json:"snapshot"`
+	// Revision whose snapshot an undo went back to
+	RestoredFrom int64 `json:"restored_from,omitempty"`
+}
+
+// Either a *sql.DB or a *sql.Tx, revisions are written inside the change when it has a transaction
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Members of a shared list change to dos on behalf of their creator
+func (t *ToDo) changedBy() int64 {
+	if t.Actor != 0 {
+		return t.Actor
+	}
+
+	return t.CreatedBy
+}
+
+func (t *ToDo) snapshot() ToDoSnapshot {
+	tags := t.Tags
+	if tags == nil {
+		tags = make([]int64, 0)
+	}
+
+	return ToDoSnapshot{
+		Title:       t.Title,
+		Description: t.Description,
+		Color:       t.Color,
+		Deadline:    t.Deadline,
+		Tags:        tags,
+		Recurrence:  t.Recurrence,
+		Timezone:    t.Timezone,
+		Priority:    t.Priority,
+	}
+}
+
+// Fields that differ from prev, all of them when there is no prev
+func (s *ToDoSnapshot) diff(prev *ToDoSnapshot) map[string]FieldChange {
+	if prev == nil {
+		prev = &ToDoSnapshot{}
+	}
+
+	changes := make(map[string]FieldChange)
+	add := func(field string, from any, to any, equal bool) {
+		if !equal {
+			changes[field] = FieldChange{From: from, To: to}
+		}
+	}
+
+	add("title", prev.Title, s.Title, prev.Title == s.Title)
+	add("description", prev.Description, s.Description, prev.Description == s.Description)
+	add("color", prev.Color, s.Color, prev.Color == s.Color)
+	// Deadlines are stored to the second
+	add("deadline", prev.Deadline, s.Deadline, prev.Deadline.Round(time.Second).Equal(s.Deadline.Round(time.Second)))
+	add("tags", prev.Tags, s.Tags, sameTags(prev.Tags, s.Tags))
+	add("recurrence", prev.Recurrence, s.Recurrence, prev.Recurrence == s.Recurrence)
+	add("timezone", prev.Timezone, s.Timezone, prev.Timezone == s.Timezone)
+	add("priority", prev.Priority, s.Priority, prev.Priority == s.Priority)
+
+	return changes
+}
+
+// Only the first tag has a meaning in the order
+func sameTags(a []int64, b []int64) bool {
+	if len(a) != len(b) || (len(a) != 0 && a[0] != b[0]) {
+		return false
+	}
+
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(a, b)
+}
+
+func insertRevision(db execer, r *ToDoRevision) error {
+	changes, err := json.Marshal(r.Changes)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	snapshot, err := json.Marshal(r.Snapshot)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	var restoredFrom sql.NullInt64
+	if r.RestoredFrom != 0 {
+		restoredFrom = sql.NullInt64{Int64: r.RestoredFrom, Valid: true}
+	}
+
+	res, err := db.Exec("INSERT INTO todo_revisions (id_todo, changed_by, action, changes, snapshot, restored_from) VALUES ( ?, ?, ?, ?, ?, ? );",
+		r.ToDo, r.ChangedBy, r.Action, string(changes), string(snapshot), restoredFrom)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	r.Id, _ = res.LastInsertId()
+	return nil
+}
+
+// Records a change of the editable fields, nothing is written when none changed
+func (t *ToDo) recordChange(db execer, id int64, action string, prev *ToDoSnapshot) error {
+	snapshot := t.snapshot()
+	changes := snapshot.diff(prev)
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return insertRevision(db, &ToDoRevision{
+		ToDo:      id,
+		ChangedBy: t.changedBy(),
+		Action:    action,
+		Changes:   changes,
+		Snapshot:  snapshot,
+	})
+}
+
+// Records a change of state, completed, archived or deleted, current holds the to do as it is
+func (t *ToDo) recordState(db execer, id int64, action string, field string, from any, to any, current *ToDo) error {
+	return insertRevision(db, &ToDoRevision{
+		ToDo:      id,
+		ChangedBy: t.changedBy(),
+		Action:    action,
+		Changes:   map[string]FieldChange{field: {From: from, To: to}},
+		Snapshot:  current.snapshot(),
+	})
+}
+
+// Lists the revisions of the to do, the last one first
+func (t *ToDo) GetHistory(id int64, db *sql.DB) ([]ToDoRevision, error) {
+	if owned, err := t.ToDoIsOwned(id, db); !owned || err != nil {
+		return nil, locales.New("todo_not_found")
+	}
+
+	stm, err := db.Prepare("SELECT id_revision, changed_by, changed_at, action, changes, snapshot, restored_from FROM todo_revisions WHERE id_todo = ? ORDER BY id_revision DESC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(id)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	revisions := make([]ToDoRevision, 0)
+
+	for rows.Next() {
+		r := ToDoRevision{ToDo: id}
+
+		if err := r.scan(rows); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	return revisions, nil
+}
+
+func (r *ToDoRevision) scan(row interface{ Scan(...any) error }) error {
+	var changes, snapshot string
+	var restoredFrom sql.NullInt64
+
+	if err := row.Scan(&r.Id, &r.ChangedBy, &r.ChangedAt, &r.Action, &changes, &snapshot, &restoredFrom); err != nil {
+		return locales.New("revision_not_found")
+	}
+
+	if json.Unmarshal([]byte(changes), &r.Changes) != nil || json.Unmarshal([]byte(snapshot), &r.Snapshot) != nil {
+		return locales.New("internal_error")
+	}
+
+	r.RestoredFrom = restoredFrom.Int64
+	return nil
+}
+
+// Puts the editable fields of the to do back as they were left by the revision, the
+// undo is a revision too so it can be undone. Tags deleted since then, or no longer
+// of the to do's workspace, are left out
+func (t *ToDo) RestoreRevision(id int64, revisionId int64, db *sql.DB) (ToDoRevision, error) {
+	current := ToDo{CreatedBy: t.CreatedBy}
+	undo := ToDoRevision{}
+
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return undo, locales.New("invalid_user")
+	}
+
+	if err := current.GetToDoById(id, db); err != nil {
+		return undo, err
+	}
+
+	row := db.QueryRow("SELECT id_revision, changed_by, changed_at, action, changes, snapshot, restored_from FROM todo_revisions WHERE id_revision = ? AND id_todo = ? LIMIT 1;", revisionId, id)
+	revision := ToDoRevision{ToDo: id}
+
+	if err := revision.scan(row); err != nil {
+		return undo, err
+	}
+
+	target := ToDo{
+		Title:       revision.Snapshot.Title,
+		Description: revision.Snapshot.Description,
+		Color:       revision.Snapshot.Color,
+		Deadline:    revision.Snapshot.Deadline,
+		Tags:        make([]int64, 0),
+		Recurrence:  revision.Snapshot.Recurrence,
+		Timezone:    revision.Snapshot.Timezone,
+		Priority:    revision.Snapshot.Priority,
+		CreatedBy:   t.CreatedBy,
+		Workspace:   current.Workspace,
+	}
+
+	for _, tag := range revision.Snapshot.Tags {
+		owned := ToDo{CreatedBy: t.CreatedBy, Workspace: current.Workspace, Tags: []int64{tag}}
+
+		if owned.CheckTagsAreOwned(db) == nil {
+			target.Tags = append(target.Tags, tag)
+		}
+	}
+
+	target.ValidateTags()
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return undo, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE todos SET title = ?, description = ?, color = ?, deadline = ?, tag = ?, recurrence = ?, timezone = ?, priority = ?, updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 1 LIMIT 1;",
+		target.Title, target.Description, target.Color, target.Deadline, target.Tag, target.Recurrence, target.Timezone, target.Priority, id, t.CreatedBy)
+
+	if err != nil {
+		return undo, locales.New("internal_error")
+	}
+
+	if err := target.setTags(id, tx); err != nil {
+		return undo, err
+	}
+
+	prev := current.snapshot()
+	undo = ToDoRevision{
+		ToDo:         id,
+		ChangedBy:    t.CreatedBy,
+		ChangedAt:    time.Now(),
+		Action:       REVISION_UNDONE,
+		Snapshot:     target.snapshot(),
+		RestoredFrom: revisionId,
+	}
+	undo.Changes = undo.Snapshot.diff(&prev)
+
+	if err := insertRevision(tx, &undo); err != nil {
+		return undo, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return undo, locales.New("internal_error")
+	}
+
+	return undo, nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRestoreRevisionKeepsTagsOfTheWorkspace(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "restorer")
+	home := insertTestTag(t, db, "home", user)
+	workspace := insertTestWorkspace(t, db, "team", user)
+
+	team := Tag{Title: "team", CreatedBy: user, Workspace: workspace}
+	teamTag, err := team.InsertTag(db)
+
+	if err != nil {
+		t.Fatalf("InsertTag(%q): %v", team.Title, err)
+	}
+
+	id := insertTestToDo(t, db, ToDo{Title: "groceries", CreatedBy: user, Workspace: workspace})
+
+	// A revision made before the to do was moved to the workspace
+	revision := ToDoRevision{
+		ToDo:      id,
+		ChangedBy: user,
+		Action:    REVISION_UPDATED,
+		Changes:   map[string]FieldChange{"tags": {From: []int64{}, To: []int64{home, teamTag}}},
+		Snapshot: ToDoSnapshot{
+			Title:    "groceries",
+			Deadline: time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second),
+			Tags:     []int64{home, teamTag},
+			Timezone: "UTC",
+		},
+	}
+
+	if err := insertRevision(db, &revision); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := ToDo{CreatedBy: user}
+
+	if _, err := owner.RestoreRevision(id, revision.Id, db); err != nil {
+		t.Fatalf("RestoreRevision: %v", err)
+	}
+
+	stored := ToDo{CreatedBy: user}
+
+	if err := stored.GetToDoById(id, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(stored.Tags, []int64{teamTag}) {
+		t.Errorf("tags = %v, want [%d]", stored.Tags, teamTag)
+	}
+}
+
+func TestDeleteToDoRecordsRevision(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "deleter")
+	id := insertTestToDo(t, db, ToDo{Title: "groceries", CreatedBy: user})
+
+	owner := ToDo{CreatedBy: user}
+
+	if err := owner.UpdateToDoById(id, true, db); err != nil {
+		t.Fatalf("UpdateToDoById: %v", err)
+	}
+
+	var action string
+
+	if err := db.QueryRow("SELECT action FROM todo_revisions WHERE id_todo = ? ORDER BY id_revision DESC LIMIT 1;", id).Scan(&action); err != nil {
+		t.Fatal(err)
+	}
+
+	if action != REVISION_DELETED {
+		t.Errorf("last revision = %q, want %q", action, REVISION_DELETED)
+	}
+}
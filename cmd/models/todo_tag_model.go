@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// The tags of a to do live in todo_tags (id_todo, id_tag). todos.tag keeps the
+// first one for the clients that only know a single tag, 0 when there is none
+const MAX_TAGS_PER_TODO = 10
+
+const (
+	TAG_MATCH_ANY = "any"
+	TAG_MATCH_ALL = "all"
+)
+
+// Removes the repeated tags and fills Tag with the first one. A single tag sent
+// by an older client becomes the list
+func (t *ToDo) ValidateTags() bool {
+	if t.Tags == nil && t.Tag != 0 {
+		t.Tags = []int64{t.Tag}
+	}
+
+	tags := make([]int64, 0, len(t.Tags))
+
+	for _, tag := range t.Tags {
+		if tag <= 0 {
+			return false
+		}
+
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	t.Tags = tags
+	t.Tag = 0
+
+	if len(tags) != 0 {
+		t.Tag = tags[0]
+	}
+
+	return len(tags) <= MAX_TAGS_PER_TODO
+}
+
+// Without tags in the request the stored ones stay. An older client only knows the
+// first tag, so a single tag it doesn't have yet takes the place of that one
+func (t *ToDo) keepStoredTags(current *ToDo) {
+	tags := slices.Clone(current.Tags)
+
+	if t.Tag != 0 && !slices.Contains(tags, t.Tag) {
+		if len(tags) == 0 {
+			tags = append(tags, t.Tag)
+		} else {
+			tags[0] = t.Tag
+		}
+	}
+
+	t.Tags = tags
+	t.Tag = 0
+
+	if len(tags) != 0 {
+		t.Tag = tags[0]
+	}
+}
+
+// Every tag of the to do must be an active tag of its owner, or of its workspace
+// when it is in one
+func (t *ToDo) CheckTagsAreOwned(db *sql.DB) error {
+	if len(t.Tags) == 0 {
+		return nil
+	}
+
+	query := "SELECT COUNT(*) FROM tags WHERE created_by = ? AND status = 1"
+	args := []any{t.CreatedBy}
+
+	if t.Workspace != 0 {
+		query = "SELECT COUNT(*) FROM tags WHERE status = 1"
+		args = nil
+	}
+
+	condition, workspaceArgs := workspaceScope(t.Workspace)
+	args = append(args, workspaceArgs...)
+
+	for _, tag := range t.Tags {
+		args = append(args, tag)
+	}
+
+	stm, err := db.Prepare(query + condition + " AND id_tag IN (" + placeholders(len(t.Tags)) + ");")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	count := -1
+	row := stm.QueryRow(args...)
+
+	if err := row.Scan(&count); err != nil {
+		return locales.New("internal_error")
+	}
+
+	if count != len(t.Tags) {
+		return locales.New("tag_not_found")
+	}
+
+	return nil
+}
+
+// Replaces the tags of the to do id with t.Tags
+func (t *ToDo) setTags(id int64, tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM todo_tags WHERE id_todo = ?;", id); err != nil {
+		return locales.New("internal_error")
+	}
+
+	if len(t.Tags) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(t.Tags))
+	args := make([]any, 0, len(t.Tags)*2)
+
+	for _, tag := range t.Tags {
+		values = append(values, "(?, ?)")
+		args = append(args, id, tag)
+	}
+
+	if _, err := tx.Exec("INSERT INTO todo_tags (id_todo, id_tag) VALUES "+strings.Join(values, ", ")+";", args...); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+// Takes the tags matching the condition on the tags table off every to do, the to dos stay
+func detachTagsWhere(tx *sql.Tx, condition string, args ...any) error {
+	tags := "(SELECT id_tag FROM tags WHERE " + condition + ")"
+
+	if _, err := tx.Exec("DELETE FROM todo_tags WHERE id_tag IN "+tags+";", args...); err != nil {
+		return locales.New("delete_failed")
+	}
+
+	_, err := tx.Exec("UPDATE todos SET tag = COALESCE((SELECT MIN(tt.id_tag) FROM todo_tags tt WHERE tt.id_todo = todos.id_todo), 0) WHERE tag IN "+tags+";", args...)
+
+	if err != nil {
+		return locales.New("delete_failed")
+	}
+
+	return nil
+}
+
+// Reads the ids of a GROUP_CONCAT
+func parseIdList(list sql.NullString) []int64 {
+	ids := make([]int64, 0)
+
+	if !list.Valid || list.String == "" {
+		return ids
+	}
+
+	for _, part := range strings.Split(list.String, ",") {
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
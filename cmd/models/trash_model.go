@@ -0,0 +1,271 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Deleted to dos and tags stay with status = 0 and their deleted_at until they are
+// restored or purged. Rows deleted before deleted_at existed fall back to updated_at
+const trashedAt = "COALESCE(deleted_at, updated_at, created_at)"
+
+// The to dos the user deleted, the personal ones or the ones of t.Workspace
+func (t *ToDo) GetTrashFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	if t.Workspace != 0 {
+		if err := CheckWorkspaceRole(t.Workspace, t.CreatedBy, ROLE_VIEWER, db); err != nil {
+			return nil, err
+		}
+	}
+
+	condition, args := workspaceScope(t.Workspace)
+	stm, err := db.Prepare("SELECT id_todo, title, description, color, deadline, tag, completed, " + trashedAt + " FROM todos WHERE created_by = ? AND status = 0" + condition + " ORDER BY 8 DESC, id_todo DESC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(append([]any{t.CreatedBy}, args...)...)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	todos := make([]map[string]any, 0)
+
+	for rows.Next() {
+		todo := ToDo{CreatedBy: t.CreatedBy}
+		var id int64 = 0
+		var deletedAt time.Time
+
+		err = rows.Scan(&id, &todo.Title, &todo.Description, &todo.Color, &todo.Deadline, &todo.Tag, &todo.Completed, &deletedAt)
+
+		if err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		todos = append(todos, map[string]any{
+			"id":          id,
+			"title":       todo.Title,
+			"description": todo.Description,
+			"color":       todo.Color,
+			"deadline":    todo.Deadline,
+			"tag":         todo.Tag,
+			"completed":   todo.Completed,
+			"created_by":  todo.CreatedBy,
+			"workspace":   t.Workspace,
+			"deleted_at":  deletedAt,
+		})
+	}
+
+	return todos, nil
+}
+
+// Brings back a deleted to do with the checklist items it had when it was deleted.
+// An open to do counts against the quota again
+func (t *ToDo) RestoreToDoById(id int64, db *sql.DB) error {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	stm, err := db.Prepare("SELECT " + trashedAt + " FROM todos WHERE id_todo = ? AND created_by = ? AND status = 0 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	var deletedAt time.Time
+	row := stm.QueryRow(id, t.CreatedBy)
+	current := ToDo{CreatedBy: t.CreatedBy}
+
+	if err := row.Scan(&deletedAt); err != nil || current.loadToDo(id, 0, db) != nil {
+		return locales.New("todo_not_found")
+	}
+
+	t.Workspace = current.Workspace
+
+	if !current.Completed {
+		if err := t.CheckQuota(db); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("restore_failed")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE todos SET status = 1, deleted_at = NULL, updated_at = now() WHERE id_todo = ? AND created_by = ? AND status = 0 LIMIT 1;", id, t.CreatedBy)
+
+	if err != nil {
+		return locales.New("restore_failed")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("todo_not_found")
+	}
+
+	// Items deleted one by one before the to do keep their older updated_at
+	_, err = tx.Exec("UPDATE todo_items SET status = 1 WHERE id_todo = ? AND status = 0 AND updated_at >= ?;", id, deletedAt)
+
+	if err != nil {
+		return locales.New("restore_failed")
+	}
+
+	if err := t.recordState(tx, id, REVISION_RESTORED, "deleted", true, false, &current); err != nil {
+		return locales.New("restore_failed")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("restore_failed")
+	}
+
+	return nil
+}
+
+// The tags the user deleted, the personal ones or the ones of t.Workspace
+func (t *Tag) GetTrashFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := t.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	if t.Workspace != 0 {
+		if err := CheckWorkspaceRole(t.Workspace, t.CreatedBy, ROLE_VIEWER, db); err != nil {
+			return nil, err
+		}
+	}
+
+	condition, args := workspaceScope(t.Workspace)
+	stm, err := db.Prepare("SELECT id_tag, title, color, " + trashedAt + " FROM tags WHERE created_by = ? AND status = 0" + condition + " ORDER BY 4 DESC, id_tag DESC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(append([]any{t.CreatedBy}, args...)...)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	tags := make([]map[string]any, 0)
+
+	for rows.Next() {
+		tag := Tag{CreatedBy: t.CreatedBy}
+		var id int64 = 0
+		var deletedAt time.Time
+
+		if err := rows.Scan(&id, &tag.Title, &tag.Color, &deletedAt); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		tags = append(tags, map[string]any{
+			"id":         id,
+			"title":      tag.Title,
+			"color":      tag.Color,
+			"created_by": tag.CreatedBy,
+			"workspace":  t.Workspace,
+			"deleted_at": deletedAt,
+		})
+	}
+
+	return tags, nil
+}
+
+// Brings back a deleted tag, the to dos it was taken off don't get it again
+func (t *Tag) RestoreTagById(id int64, db *sql.DB) error {
+	row := db.QueryRow("SELECT COALESCE(id_workspace, 0) FROM tags WHERE id_tag = ? AND created_by = ? AND status = 0 LIMIT 1;", id, t.CreatedBy)
+
+	if err := row.Scan(&t.Workspace); err != nil {
+		return locales.New("tag_not_found")
+	}
+
+	if err := t.CheckQuota(db); err != nil {
+		return err
+	}
+
+	stm, err := db.Prepare("UPDATE tags SET status = 1, deleted_at = NULL, updated_at = now() WHERE id_tag = ? AND created_by = ? AND status = 0 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("restore_failed")
+	}
+	defer stm.Close()
+
+	res, err := stm.Exec(id, t.CreatedBy)
+
+	if err != nil {
+		return locales.New("restore_failed")
+	}
+
+	if affected, err := res.RowsAffected(); affected != 1 || err != nil {
+		return locales.New("tag_not_found")
+	}
+
+	return nil
+}
+
+// Deletes for good the to dos and tags that were deleted before the given time, with
+// everything that hangs from them. Returns how many to dos and tags were purged
+func PurgeTrash(before time.Time, db *sql.DB) (int64, int64, error) {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	todos := "(SELECT id_todo FROM todos WHERE status = 0 AND " + trashedAt + " < ?)"
+
+	if _, err := tx.Exec("DELETE FROM comment_mentions WHERE id_comment IN (SELECT id_comment FROM todo_comments WHERE id_todo IN "+todos+");", before); err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM todo_dependencies WHERE id_blocker IN "+todos+" OR id_blocked IN "+todos+";", before, before); err != nil {
+		return 0, 0, err
+	}
+
+	for _, table := range []string{"todo_items", "todo_tags", "reminders", "todo_revisions", "todo_comments", "time_entries", "pomodoro_sessions"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE id_todo IN "+todos+";", before); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	res, err := tx.Exec("DELETE FROM todos WHERE status = 0 AND "+trashedAt+" < ?;", before)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	purgedToDos, _ := res.RowsAffected()
+
+	// Checklist items deleted on their own
+	if _, err := tx.Exec("DELETE FROM todo_items WHERE status = 0 AND updated_at < ?;", before); err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM todo_tags WHERE id_tag IN (SELECT id_tag FROM tags WHERE status = 0 AND "+trashedAt+" < ?);", before); err != nil {
+		return 0, 0, err
+	}
+
+	res, err = tx.Exec("DELETE FROM tags WHERE status = 0 AND "+trashedAt+" < ?;", before)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	purgedTags, _ := res.RowsAffected()
+
+	return purgedToDos, purgedTags, tx.Commit()
+}
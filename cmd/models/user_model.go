@@ -2,11 +2,11 @@ package models
 
 import (
 	"database/sql"
-	"errors"
 	"regexp"
 	"time"
 	"unicode"
 
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,21 +17,27 @@ type UserDTO struct {
 	Password string `json:"password"`
 	UserType int 	`json:"user_type"`
 	ProfilePic string `json:"image_url"`
+	Locale   string `json:"locale"`
 }
 
 const TABLE_NAME = "users"
 
+const mailRegex = "^(?:[a-z0-9!#$%&'*+/=?^_`{|}~-]+(?:\\.[a-z0-9!#$%&'*+/=?^_`{|}~-]+)*|\"(?:[\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x21\\x23-\\x5b\\x5d-\\x7f]|\\\\[\\x01-\\x09\\x0b\\x0c\\x0e-\\x7f])*\")@(?:(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\\.)+[a-z0-9](?:[a-z0-9-]*[a-z0-9])?|\\[(?:(?:(2(5[0-5]|[0-4][0-9])|1[0-9][0-9]|[1-9]?[0-9]))\\.){3}(?:(2(5[0-5]|[0-4][0-9])|1[0-9][0-9]|[1-9]?[0-9])|[a-z0-9-]*[a-z0-9]:(?:[\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x21-\\x5a\\x53-\\x7f]|\\\\[\\x01-\\x09\x0b\\x0c\\x0e-\\x7f])+)\\])$"
+
 func (u *UserDTO) ValidateUser() (bool, error) {
-	userRegex := "^[a-zA-Z]{2,18}$"
-	mailRegex := "^(?:[a-z0-9!#$%&'*+/=?^_`{|}~-]+(?:\\.[a-z0-9!#$%&'*+/=?^_`{|}~-]+)*|\"(?:[\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x21\\x23-\\x5b\\x5d-\\x7f]|\\\\[\\x01-\\x09\\x0b\\x0c\\x0e-\\x7f])*\")@(?:(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\\.)+[a-z0-9](?:[a-z0-9-]*[a-z0-9])?|\\[(?:(?:(2(5[0-5]|[0-4][0-9])|1[0-9][0-9]|[1-9]?[0-9]))\\.){3}(?:(2(5[0-5]|[0-4][0-9])|1[0-9][0-9]|[1-9]?[0-9])|[a-z0-9-]*[a-z0-9]:(?:[\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x21-\\x5a\\x53-\\x7f]|\\\\[\\x01-\\x09\x0b\\x0c\\x0e-\\x7f])+)\\])$"
 	phoneRegex:= "^(\\+\\d{1,2}\\s?)?1?\\-?\\.?\\s?\\(?\\d{3}\\)?[\\s.-]?\\d{3}[\\s.-]?\\d{4}$"
 
-	userValid, userErr := validate(userRegex, u.Name)
+	u.Name = NormalizeText(u.Name)
+	userValid := ValidateField(FIELD_USER_NAME, u.Name)
 	mailValid, mailErr := validate(mailRegex, u.Mail)
 	phoneValid, phoneErr:= validate(phoneRegex, u.Phone)
 	passValid := u.validatePassword(8)
 
-	if userErr != nil { return false, userErr }
+	// A missing locale is picked on insert and left as stored on edit
+	if !locales.IsSupported(u.Locale) {
+		u.Locale = ""
+	}
+
 	if mailErr != nil { return false, mailErr }
 	if phoneErr != nil { return false, phoneErr }
 
@@ -78,7 +84,7 @@ func (u UserDTO)VerifyUserIdIsActive(id int, db *sql.DB) (bool, error) {
 	stm, err := db.Prepare("SELECT COUNT(*) AS count FROM users WHERE id_user = ? AND status = 1 LIMIT 1;")
 
 	if err != nil {
-		return false, errors.New("invalid user")
+		return false, locales.New("invalid_user")
 	}
 
 	defer stm.Close()
@@ -134,7 +140,7 @@ func (u UserDTO) CountUsersByMailAvoidingId(mail string, id int, db *sql.DB) (in
 }
 
 func (u *UserDTO) GetUserById(id int64, db *sql.DB) error {
-	stm, err := db.Prepare("SELECT name, mail, phone, user_type, image_url FROM users WHERE id_user = ? AND status = 1 LIMIT 1;")
+	stm, err := db.Prepare("SELECT name, mail, phone, user_type, image_url, COALESCE(locale, '') FROM users WHERE id_user = ? AND status = 1 LIMIT 1;")
 
 	if err != nil {
 		return err
@@ -146,10 +152,11 @@ func (u *UserDTO) GetUserById(id int64, db *sql.DB) error {
 	holderPhone:= ""
 	holderUserT:= -1
 	holderImage:= ""
+	holderLocale:= ""
 
 	row := stm.QueryRow(id)
 
-	err = row.Scan(&holderName, &holderMail, &holderPhone, &holderUserT, &holderImage)
+	err = row.Scan(&holderName, &holderMail, &holderPhone, &holderUserT, &holderImage, &holderLocale)
 
 	if err != nil {
 		return err
@@ -160,6 +167,7 @@ func (u *UserDTO) GetUserById(id int64, db *sql.DB) error {
 	u.ProfilePic = holderImage
 	u.UserType = holderUserT
 	u.Phone = holderPhone
+	u.Locale = holderLocale
 
 	err = u.VerifyUserIsPremium(int(id), db)
 
@@ -171,7 +179,7 @@ func (u *UserDTO) GetUserById(id int64, db *sql.DB) error {
 }
 
 func (u *UserDTO) GetUserByMail(db *sql.DB) (int, string,error) {
-	stm, err := db.Prepare("SELECT id_user, password, name, phone, user_type, image_url FROM users WHERE mail = ? AND status = 1 LIMIT 1;")
+	stm, err := db.Prepare("SELECT id_user, password, name, phone, user_type, image_url, COALESCE(locale, '') FROM users WHERE mail = ? AND status = 1 LIMIT 1;")
 
 	if err != nil {
 		return -1, "",err
@@ -184,10 +192,11 @@ func (u *UserDTO) GetUserByMail(db *sql.DB) (int, string,error) {
 	holderPhone:= ""
 	holderUserT:= -1
 	holderImage:= ""
+	holderLocale:= ""
 
 	row := stm.QueryRow(u.Mail)
 
-	err = row.Scan(&id, &passwordHash, &holderName, &holderPhone, &holderUserT, &holderImage)
+	err = row.Scan(&id, &passwordHash, &holderName, &holderPhone, &holderUserT, &holderImage, &holderLocale)
 
 	if err != nil {
 		return -1, "",err
@@ -197,6 +206,7 @@ func (u *UserDTO) GetUserByMail(db *sql.DB) (int, string,error) {
 	u.ProfilePic = holderImage
 	u.UserType = holderUserT
 	u.Phone = holderPhone
+	u.Locale = holderLocale
 
 	err = u.VerifyUserIsPremium(int(id), db)
 
@@ -209,14 +219,14 @@ func (u *UserDTO) GetUserByMail(db *sql.DB) (int, string,error) {
 
 // Inserts a user to db and returns the last inserted id or an error
 func (u *UserDTO) InsertUser(hash string, db *sql.DB) (int64, error) {
-	stm, err := db.Prepare("INSERT INTO users (name, mail, password, phone) VALUES ( ? , ? , ? , ? ) LIMIT 1;")
+	stm, err := db.Prepare("INSERT INTO users (name, mail, password, phone, locale) VALUES ( ? , ? , ? , ? , ? ) LIMIT 1;")
 
 	if err != nil {
 		return 0, err
 	}
 	defer stm.Close()
 
-	res, err := stm.Exec(u.Name, u.Mail, hash, u.Phone)
+	res, err := stm.Exec(u.Name, u.Mail, hash, u.Phone, u.Locale)
 
 	if err != nil {
 		return 0, err
@@ -232,14 +242,14 @@ func (u *UserDTO) UpdateUser(id int, hash string,db *sql.DB) error {
 		return err
 	}
 
-	stm, err := db.Prepare("UPDATE users SET name = ?, mail = ?, password = ?, phone = ?, updated_at = now() WHERE id_user = ? AND status = 1 LIMIT 1;")
+	stm, err := db.Prepare("UPDATE users SET name = ?, mail = ?, password = ?, phone = ?, locale = COALESCE(NULLIF(?, ''), locale), updated_at = now() WHERE id_user = ? AND status = 1 LIMIT 1;")
 
 	if err != nil {
 		return err
 	}
 	defer stm.Close()	
 
-	_, err = stm.Exec(u.Name, u.Mail, hash, u.Phone, id)
+	_, err = stm.Exec(u.Name, u.Mail, hash, u.Phone, u.Locale, id)
 
 	return err
 }
@@ -372,4 +382,43 @@ func UpdateUserProfileImage(secureUrl, publicId string, id int, db *sql.DB) erro
 	_, err = stm.Exec(secureUrl, publicId, id)
 
 	return err
-}
\ No newline at end of file
+}
+
+// Gets the preferred locale stored for the user
+func GetUserLocale(id int, db *sql.DB) (string, error) {
+	stm, err := db.Prepare("SELECT COALESCE(locale, '') FROM users WHERE id_user = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return "", err
+	}
+	defer stm.Close()
+
+	var locale string = ""
+	row := stm.QueryRow(id)
+
+	err = row.Scan(&locale)
+
+	return locale, err
+}
+
+// Checks the user has a premium plan that has not expired yet
+func IsUserPremium(id int, db *sql.DB) (bool, error) {
+	stm, err := db.Prepare("SELECT user_type, premium_expiracy FROM users WHERE id_user = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return false, err
+	}
+	defer stm.Close()
+
+	userType := 0
+	expiracy := time.Time{}
+	row := stm.QueryRow(id)
+
+	err = row.Scan(&userType, &expiracy)
+
+	if err != nil {
+		return false, err
+	}
+
+	return userType == 1 && time.Now().Before(expiracy), nil
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type FieldLimit struct {
+	Min int
+	Max int
+	// Allows digits, punctuation and symbols (emoji included) besides letters
+	Symbols bool
+	// Allows line breaks
+	Multiline bool
+}
+
+const (
+	FIELD_USER_NAME        = "user.name"
+	FIELD_TAG_TITLE        = "tag.title"
+	FIELD_TODO_TITLE       = "todo.title"
+	FIELD_TODO_DESCRIPTION = "todo.description"
+	FIELD_TODO_ITEM_TEXT   = "todo_item.text"
+	FIELD_LIST_TITLE       = "list.title"
+	FIELD_WORKSPACE_NAME   = "workspace.name"
+	FIELD_COMMENT_BODY     = "comment.body"
+	FIELD_COLUMN_TITLE     = "column.title"
+	FIELD_TEMPLATE_TITLE   = "template.title"
+	FIELD_SMART_LIST_NAME  = "smart_list.name"
+)
+
+var FieldLimits = map[string]FieldLimit{
+	FIELD_USER_NAME:        {Min: 2, Max: 18},
+	FIELD_TAG_TITLE:        {Min: 2, Max: 18, Symbols: true},
+	FIELD_TODO_TITLE:       {Min: 3, Max: 15, Symbols: true},
+	FIELD_TODO_DESCRIPTION: {Min: 0, Max: 100, Symbols: true},
+	FIELD_TODO_ITEM_TEXT:   {Min: 1, Max: 100, Symbols: true},
+	FIELD_LIST_TITLE:       {Min: 2, Max: 30, Symbols: true},
+	FIELD_WORKSPACE_NAME:   {Min: 2, Max: 30, Symbols: true},
+	FIELD_COMMENT_BODY:     {Min: 1, Max: 1000, Symbols: true, Multiline: true},
+	FIELD_COLUMN_TITLE:     {Min: 1, Max: 20, Symbols: true},
+	FIELD_TEMPLATE_TITLE:   {Min: 2, Max: 30, Symbols: true},
+	FIELD_SMART_LIST_NAME:  {Min: 2, Max: 30, Symbols: true},
+}
+
+// Overrides the default limits with env vars like LIMIT_TODO_TITLE="3,30"
+func LoadFieldLimits() {
+	for field, limit := range FieldLimits {
+		key := "LIMIT_" + strings.ToUpper(strings.ReplaceAll(field, ".", "_"))
+		value := os.Getenv(key)
+
+		if value == "" {
+			continue
+		}
+
+		bounds := strings.Split(value, ",")
+		if len(bounds) != 2 {
+			continue
+		}
+
+		min, minErr := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		max, maxErr := strconv.Atoi(strings.TrimSpace(bounds[1]))
+
+		if minErr != nil || maxErr != nil || min < 0 || max < min {
+			continue
+		}
+
+		limit.Min = min
+		limit.Max = max
+		FieldLimits[field] = limit
+	}
+}
+
+// Composes the text to NFC so "José" counts the same however it was typed, and trims it
+func NormalizeText(text string) string {
+	return strings.TrimSpace(norm.NFC.String(text))
+}
+
+// Checks the rune length and the characters of an already normalized text
+func ValidateField(field, text string) bool {
+	limit, ok := FieldLimits[field]
+
+	if !ok || !utf8.ValidString(text) {
+		return false
+	}
+
+	length := utf8.RuneCountInString(text)
+	if length < limit.Min || length > limit.Max {
+		return false
+	}
+
+	for _, r := range text {
+		if r == '\n' && limit.Multiline {
+			continue
+		}
+
+		if !isAllowedRune(r, limit.Symbols) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAllowedRune(r rune, symbols bool) bool {
+	switch {
+	case unicode.IsLetter(r), unicode.Is(unicode.Mn, r):
+		return true
+	case r == ' ':
+		return true
+	case r == '\'' || r == '-' || r == '.':
+		return true
+	case !symbols:
+		return false
+	case unicode.IsNumber(r), unicode.IsPunct(r), unicode.IsSymbol(r):
+		return true
+	case r == '\u200d', unicode.Is(unicode.Me, r):
+		// zero width joiner and enclosing marks, used by composed and keycap emoji
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"composes a combining accent", "José", "José"},
+		{"keeps composed text", "José", "José"},
+		{"composes several marks", "ñaña", "ñaña"},
+		{"angstrom sign becomes the letter", "Å", "Å"},
+		{"trims spaces and line breaks", "  hola \n", "hola"},
+		{"keeps fullwidth letters", "Ａ", "Ａ"},
+		{"keeps emoji sequences", "\U0001f468‍\U0001f469‍\U0001f467", "\U0001f468‍\U0001f469‍\U0001f467"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := NormalizeText(test.text); got != test.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateField(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		text  string
+		want  bool
+	}{
+		{"accented name", FIELD_USER_NAME, "José Núñez", true},
+		{"name with a combining accent", FIELD_USER_NAME, "José", true},
+		{"name with apostrophe and hyphen", FIELD_USER_NAME, "O'Brien-Smith", true},
+		{"non latin name", FIELD_USER_NAME, "Дмитрий", true},
+		{"name too short", FIELD_USER_NAME, "J", false},
+		{"name of max runes in more bytes", FIELD_USER_NAME, strings.Repeat("é", 18), true},
+		{"name over max runes", FIELD_USER_NAME, strings.Repeat("é", 19), false},
+		{"name with digits", FIELD_USER_NAME, "José2", false},
+		{"name with emoji", FIELD_USER_NAME, "José 😀", false},
+
+		{"title with emoji", FIELD_TODO_TITLE, "Pay rent 💸", true},
+		{"title with skin tone", FIELD_TODO_TITLE, "👍🏽 done", true},
+		{"title with joined emoji", FIELD_TODO_TITLE, "👨‍👩‍👧 trip", true},
+		{"title with variation selector", FIELD_TODO_TITLE, "❤️ love", true},
+		{"title with keycap", FIELD_TODO_TITLE, "1️⃣ first", true},
+		{"title with flag", FIELD_TODO_TITLE, "Trip 🇲🇽", true},
+		{"title in japanese", FIELD_TODO_TITLE, "日本語のメモ", true},
+		{"title of max runes in emoji", FIELD_TODO_TITLE, strings.Repeat("😀", 15), true},
+		{"title over max runes in emoji", FIELD_TODO_TITLE, strings.Repeat("😀", 16), false},
+		{"title too short", FIELD_TODO_TITLE, "ab", false},
+		{"title with a line break", FIELD_TODO_TITLE, "two\nlines", false},
+		{"title with a tab", FIELD_TODO_TITLE, "tab\there", false},
+		{"title with a control character", FIELD_TODO_TITLE, "bell\u0007", false},
+		{"invalid utf-8", FIELD_TODO_TITLE, "\xff\xfe\xfd", false},
+
+		{"empty description", FIELD_TODO_DESCRIPTION, "", true},
+		{"comment with line breaks", FIELD_COMMENT_BODY, "first\nsecond", true},
+		{"unknown field", "todo.unknown", "anything", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ValidateField(test.field, test.text); got != test.want {
+				t.Errorf("ValidateField(%q, %q) = %v, want %v", test.field, test.text, got, test.want)
+			}
+		})
+	}
+}
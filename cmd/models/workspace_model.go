@@ -0,0 +1,581 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Workspaces let a team share to dos, tags and images. A row with id_workspace set
+// belongs to the workspace: it is only listed in it, to its members, and counts
+// against the quota of the workspace plan instead of the one of its creator.
+// Rows with id_workspace NULL are the personal ones of their creator
+const (
+	WORKSPACE_PLAN_FREE    = "free"
+	WORKSPACE_PLAN_PREMIUM = "premium"
+
+	WORKSPACE_TODOS   = "todos"
+	WORKSPACE_TAGS    = "tags"
+	WORKSPACE_IMAGES  = "images"
+	WORKSPACE_MEMBERS = "members"
+
+	MAX_WORKSPACES_PER_USER = 5
+)
+
+// Limits of each plan, the open to dos are the ones counted as in the personal quota
+var workspaceLimits = map[string]map[string]int{
+	WORKSPACE_PLAN_FREE:    {WORKSPACE_TODOS: 100, WORKSPACE_TAGS: 30, WORKSPACE_IMAGES: 30, WORKSPACE_MEMBERS: 5},
+	WORKSPACE_PLAN_PREMIUM: {WORKSPACE_TODOS: 1000, WORKSPACE_TAGS: 200, WORKSPACE_IMAGES: 300, WORKSPACE_MEMBERS: 50},
+}
+
+var workspaceUsage = map[string]string{
+	WORKSPACE_TODOS:   "SELECT COUNT(*) FROM todos WHERE id_workspace = ? AND status = 1 AND completed = 0 AND archived_at IS NULL;",
+	WORKSPACE_TAGS:    "SELECT COUNT(*) FROM tags WHERE id_workspace = ? AND status = 1;",
+	WORKSPACE_IMAGES:  "SELECT COUNT(*) FROM images WHERE id_workspace = ?;",
+	WORKSPACE_MEMBERS: "SELECT COUNT(*) FROM workspace_members WHERE id_workspace = ? AND status = 1;",
+}
+
+var workspaceLimitErrors = map[string]string{
+	WORKSPACE_TODOS:   "todos_limit",
+	WORKSPACE_TAGS:    "tags_limit",
+	WORKSPACE_IMAGES:  "images_limit",
+	WORKSPACE_MEMBERS: "members_limit",
+}
+
+type Workspace struct {
+	Name      string `json:"name"`
+	CreatedBy int64  `json:"created_by"`
+}
+
+func (w *Workspace) ValidateWorkspace() bool {
+	w.Name = NormalizeText(w.Name)
+	return ValidateField(FIELD_WORKSPACE_NAME, w.Name)
+}
+
+func (w *Workspace) CheckUserIsActive(db *sql.DB) (bool, error) {
+	userDto := UserDTO{}
+
+	return userDto.VerifyUserIdIsActive(int(w.CreatedBy), db)
+}
+
+// Condition keeping the rows of a table in the workspace, or the personal ones with 0
+func workspaceScope(workspace int64) (string, []any) {
+	if workspace == 0 {
+		return " AND id_workspace IS NULL", nil
+	}
+
+	return " AND id_workspace = ?", []any{workspace}
+}
+
+// Role of the user in the workspace, empty when they are not a member
+func WorkspaceRole(id int64, userId int64, db *sql.DB) (string, error) {
+	stm, err := db.Prepare("SELECT m.role FROM workspace_members m JOIN workspaces w ON w.id_workspace = m.id_workspace WHERE m.id_workspace = ? AND m.id_user = ? AND m.status = 1 AND w.status = 1 LIMIT 1;")
+
+	if err != nil {
+		return "", locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	role := ""
+	err = stm.QueryRow(id, userId).Scan(&role)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", locales.New("internal_error")
+	}
+
+	return role, nil
+}
+
+// Fails unless the user has at least the needed role in the workspace
+func CheckWorkspaceRole(id int64, userId int64, needed string, db *sql.DB) error {
+	role, err := WorkspaceRole(id, userId, db)
+
+	if err != nil {
+		return err
+	}
+
+	if role == "" {
+		return locales.New("workspace_not_found")
+	}
+
+	if roleRanks[role] < roleRanks[needed] {
+		return locales.New("not_allowed")
+	}
+
+	return nil
+}
+
+// Plan of the workspace, premium until its premium_expiracy
+func workspacePlan(id int64, db *sql.DB) (string, *time.Time, error) {
+	var expiracy sql.NullTime
+	err := db.QueryRow("SELECT premium_expiracy FROM workspaces WHERE id_workspace = ? AND status = 1 LIMIT 1;", id).Scan(&expiracy)
+
+	if err != nil {
+		return "", nil, locales.New("workspace_not_found")
+	}
+
+	if expiracy.Valid && expiracy.Time.After(time.Now()) {
+		return WORKSPACE_PLAN_PREMIUM, &expiracy.Time, nil
+	}
+
+	return WORKSPACE_PLAN_FREE, nil, nil
+}
+
+func workspaceCount(id int64, resource string, db *sql.DB) (int, error) {
+	count := -1
+	err := db.QueryRow(workspaceUsage[resource], id).Scan(&count)
+
+	return count, err
+}
+
+// Checks the user can add one more of the resource to the workspace, editors add
+// to dos, tags and images and only owners add members
+func CheckWorkspaceQuota(id int64, userId int64, resource string, db *sql.DB) error {
+	userDto := UserDTO{}
+
+	if active, err := userDto.VerifyUserIdIsActive(int(userId), db); !active || err != nil {
+		return locales.New("invalid_user")
+	}
+
+	needed := ROLE_EDITOR
+	if resource == WORKSPACE_MEMBERS {
+		needed = ROLE_OWNER
+	}
+
+	if err := CheckWorkspaceRole(id, userId, needed, db); err != nil {
+		return err
+	}
+
+	plan, _, err := workspacePlan(id, db)
+
+	if err != nil {
+		return err
+	}
+
+	count, err := workspaceCount(id, resource, db)
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+
+	if count >= workspaceLimits[plan][resource] {
+		return locales.New(workspaceLimitErrors[resource])
+	}
+
+	return nil
+}
+
+func (w *Workspace) CountWorkspacesPerUserId(db *sql.DB) (int, error) {
+	stm, err := db.Prepare("SELECT COUNT(*) FROM workspaces WHERE created_by = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return -1, err
+	}
+	defer stm.Close()
+
+	count := -1
+	err = stm.QueryRow(w.CreatedBy).Scan(&count)
+
+	return count, err
+}
+
+// Creates the workspace on the free plan with its creator as owner
+func (w *Workspace) InsertWorkspace(db *sql.DB) (int64, error) {
+	if active, err := w.CheckUserIsActive(db); !active || err != nil {
+		return -1, locales.New("invalid_user")
+	}
+
+	count, err := w.CountWorkspacesPerUserId(db)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if count >= MAX_WORKSPACES_PER_USER {
+		return -1, locales.New("workspaces_limit")
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO workspaces (name, created_by) VALUES ( ?, ? );", w.Name, w.CreatedBy)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if _, err := tx.Exec("INSERT INTO workspace_members (id_workspace, id_user, role) VALUES ( ?, ?, ? );", id, w.CreatedBy, ROLE_OWNER); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return id, nil
+}
+
+// Only owners rename or delete a workspace, and it must be empty to be deleted so
+// nothing of the team ends up in the personal space of its creator
+func (w *Workspace) UpdateWorkspaceById(id int64, delete bool, db *sql.DB) error {
+	if err := CheckWorkspaceRole(id, w.CreatedBy, ROLE_OWNER, db); err != nil {
+		return err
+	}
+
+	if !delete {
+		stm, err := db.Prepare("UPDATE workspaces SET name = ?, updated_at = now() WHERE id_workspace = ? AND status = 1 LIMIT 1;")
+
+		if err != nil {
+			return locales.New("workspace_not_updated")
+		}
+		defer stm.Close()
+
+		if _, err := stm.Exec(w.Name, id); err != nil {
+			return locales.New("workspace_not_updated")
+		}
+
+		return nil
+	}
+
+	for _, resource := range []string{WORKSPACE_TODOS, WORKSPACE_TAGS, WORKSPACE_IMAGES} {
+		count, err := workspaceCount(id, resource, db)
+
+		if err != nil {
+			return locales.New("workspace_not_deleted")
+		}
+
+		if count > 0 {
+			return locales.New("workspace_not_empty")
+		}
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return locales.New("workspace_not_deleted")
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		"UPDATE workspaces SET status = 0, updated_at = now() WHERE id_workspace = ? LIMIT 1;",
+		"UPDATE workspace_members SET status = 0 WHERE id_workspace = ?;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return locales.New("workspace_not_deleted")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return locales.New("workspace_not_deleted")
+	}
+
+	return nil
+}
+
+// Lists the workspaces the user is a member of, with their role and plan
+func (w *Workspace) GetAllWorkspacesFromUserId(db *sql.DB) ([]map[string]any, error) {
+	if active, err := w.CheckUserIsActive(db); !active || err != nil {
+		return nil, locales.New("get_failed")
+	}
+
+	stm, err := db.Prepare("SELECT ws.id_workspace, ws.name, ws.created_by, m.role, ws.premium_expiracy, " +
+		"(SELECT COUNT(*) FROM workspace_members wm WHERE wm.id_workspace = ws.id_workspace AND wm.status = 1) " +
+		"FROM workspaces ws JOIN workspace_members m ON m.id_workspace = ws.id_workspace WHERE m.id_user = ? AND m.status = 1 AND ws.status = 1 ORDER BY ws.name ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(w.CreatedBy)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	workspaces := make([]map[string]any, 0)
+
+	for rows.Next() {
+		workspace := Workspace{}
+		var id int64
+		var role string
+		var expiracy sql.NullTime
+		members := 0
+
+		if err := rows.Scan(&id, &workspace.Name, &workspace.CreatedBy, &role, &expiracy, &members); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		plan := WORKSPACE_PLAN_FREE
+		if expiracy.Valid && expiracy.Time.After(time.Now()) {
+			plan = WORKSPACE_PLAN_PREMIUM
+		}
+
+		workspaces = append(workspaces, map[string]any{
+			"id":         id,
+			"name":       workspace.Name,
+			"created_by": workspace.CreatedBy,
+			"role":       role,
+			"plan":       plan,
+			"members":    members,
+		})
+	}
+
+	return workspaces, nil
+}
+
+// The workspace with its plan, and the usage and limits of each resource
+func (w *Workspace) GetWorkspaceById(id int64, db *sql.DB) (map[string]any, error) {
+	role, err := WorkspaceRole(id, w.CreatedBy, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if role == "" {
+		return nil, locales.New("workspace_not_found")
+	}
+
+	if err := db.QueryRow("SELECT name, created_by FROM workspaces WHERE id_workspace = ? AND status = 1 LIMIT 1;", id).Scan(&w.Name, &w.CreatedBy); err != nil {
+		return nil, locales.New("workspace_not_found")
+	}
+
+	plan, expiracy, err := workspacePlan(id, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]int{}
+
+	for resource := range workspaceUsage {
+		count, err := workspaceCount(id, resource, db)
+
+		if err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		usage[resource] = count
+	}
+
+	return map[string]any{
+		"id":               id,
+		"name":             w.Name,
+		"created_by":       w.CreatedBy,
+		"role":             role,
+		"plan":             plan,
+		"premium_expiracy": expiracy,
+		"usage":            usage,
+		"limits":           workspaceLimits[plan],
+	}, nil
+}
+
+// Adds 30 days of premium to the workspace, from now or from the current expiracy
+func (w *Workspace) UpgradeToPremium(id int64, db *sql.DB) (time.Time, error) {
+	if err := CheckWorkspaceRole(id, w.CreatedBy, ROLE_OWNER, db); err != nil {
+		return time.Time{}, err
+	}
+
+	_, expiracy, err := workspacePlan(id, db)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	newExpiracy := time.Now().Add(time.Hour * 24 * 30)
+
+	if expiracy != nil {
+		newExpiracy = expiracy.Add(time.Hour * 24 * 30)
+	}
+
+	stm, err := db.Prepare("UPDATE workspaces SET premium_expiracy = ?, updated_at = now() WHERE id_workspace = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return time.Time{}, locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	if _, err := stm.Exec(newExpiracy, id); err != nil {
+		return time.Time{}, locales.New("internal_error")
+	}
+
+	return newExpiracy, nil
+}
+
+func (w *Workspace) GetMembers(id int64, db *sql.DB) ([]map[string]any, error) {
+	if err := CheckWorkspaceRole(id, w.CreatedBy, ROLE_VIEWER, db); err != nil {
+		return nil, err
+	}
+
+	stm, err := db.Prepare("SELECT u.id_user, u.name, u.mail, u.image_url, m.role FROM workspace_members m JOIN users u ON u.id_user = m.id_user WHERE m.id_workspace = ? AND m.status = 1 AND u.status = 1 ORDER BY u.name ASC;")
+
+	if err != nil {
+		return nil, locales.New("get_failed")
+	}
+	defer stm.Close()
+
+	rows, err := stm.Query(id)
+
+	if err != nil {
+		return nil, locales.New("internal_error")
+	}
+	defer rows.Close()
+
+	members := make([]map[string]any, 0)
+
+	for rows.Next() {
+		var userId int64
+		var name, mail, role string
+		var image sql.NullString
+
+		if err := rows.Scan(&userId, &name, &mail, &image, &role); err != nil {
+			return nil, locales.New("internal_error")
+		}
+
+		members = append(members, map[string]any{
+			"id":        userId,
+			"name":      name,
+			"mail":      mail,
+			"image_url": image.String,
+			"role":      role,
+		})
+	}
+
+	return members, nil
+}
+
+// Adds the user with the mail to the workspace, the members count against the plan
+func (w *Workspace) AddMember(id int64, mail string, role string, db *sql.DB) (int64, error) {
+	if err := CheckWorkspaceQuota(id, w.CreatedBy, WORKSPACE_MEMBERS, db); err != nil {
+		return -1, err
+	}
+
+	var userId int64
+	err := db.QueryRow("SELECT id_user FROM users WHERE mail = ? AND status = 1 LIMIT 1;", strings.ToLower(strings.TrimSpace(mail))).Scan(&userId)
+
+	if err != nil {
+		return -1, locales.New("user_not_found")
+	}
+
+	current, err := WorkspaceRole(id, userId, db)
+
+	if err != nil {
+		return -1, err
+	}
+
+	if current != "" {
+		return -1, locales.New("already_member")
+	}
+
+	// A member that left or was removed comes back with the new role
+	_, err = db.Exec("INSERT INTO workspace_members (id_workspace, id_user, role) VALUES ( ?, ?, ? ) ON DUPLICATE KEY UPDATE role = VALUES(role), status = 1;", id, userId, role)
+
+	if err != nil {
+		return -1, locales.New("internal_error")
+	}
+
+	return userId, nil
+}
+
+func countWorkspaceOwners(id int64, db *sql.DB) (int, error) {
+	count := -1
+	err := db.QueryRow("SELECT COUNT(*) FROM workspace_members WHERE id_workspace = ? AND role = ? AND status = 1;", id, ROLE_OWNER).Scan(&count)
+
+	return count, err
+}
+
+// A workspace always keeps an owner, the last one can't leave nor be demoted
+func (w *Workspace) SetMemberRole(id int64, userId int64, role string, db *sql.DB) error {
+	if err := CheckWorkspaceRole(id, w.CreatedBy, ROLE_OWNER, db); err != nil {
+		return err
+	}
+
+	current, err := WorkspaceRole(id, userId, db)
+
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		return locales.New("member_not_found")
+	}
+
+	if current == ROLE_OWNER && role != ROLE_OWNER {
+		if owners, err := countWorkspaceOwners(id, db); err != nil || owners <= 1 {
+			return locales.New("workspace_needs_owner")
+		}
+	}
+
+	stm, err := db.Prepare("UPDATE workspace_members SET role = ? WHERE id_workspace = ? AND id_user = ? AND status = 1 LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	if _, err := stm.Exec(role, id, userId); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
+
+// Owners remove members and anyone can leave. What they created stays in the workspace
+func (w *Workspace) RemoveMember(id int64, userId int64, db *sql.DB) error {
+	needed := ROLE_OWNER
+	if userId == w.CreatedBy {
+		needed = ROLE_VIEWER
+	}
+
+	if err := CheckWorkspaceRole(id, w.CreatedBy, needed, db); err != nil {
+		return err
+	}
+
+	current, err := WorkspaceRole(id, userId, db)
+
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		return locales.New("member_not_found")
+	}
+
+	if current == ROLE_OWNER {
+		if owners, err := countWorkspaceOwners(id, db); err != nil || owners <= 1 {
+			return locales.New("workspace_needs_owner")
+		}
+	}
+
+	stm, err := db.Prepare("UPDATE workspace_members SET status = 0 WHERE id_workspace = ? AND id_user = ? LIMIT 1;")
+
+	if err != nil {
+		return locales.New("internal_error")
+	}
+	defer stm.Close()
+
+	if _, err := stm.Exec(id, userId); err != nil {
+		return locales.New("internal_error")
+	}
+
+	return nil
+}
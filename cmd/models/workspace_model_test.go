@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/locales"
+)
+
+// Inserts n open to dos of the user straight into the table, in the workspace or
+// personal ones with 0, without going through the quota
+func fillToDos(t *testing.T, db *sql.DB, user int64, workspace int64, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	for i := 0; i < n; i++ {
+		_, err := db.Exec("INSERT INTO todos (title, deadline, created_by, id_workspace) VALUES ( 'filler', ?, ?, NULLIF(?, 0) );", deadline, user, workspace)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Inserts a to do that doesn't count against any quota, reopening or unarchiving it needs a free slot
+func insertInactiveToDo(t *testing.T, db *sql.DB, user int64, workspace int64, state string) int64 {
+	t.Helper()
+
+	deadline := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	res, err := db.Exec("INSERT INTO todos (title, deadline, created_by, id_workspace) VALUES ( 'inactive', ?, ?, NULLIF(?, 0) );", deadline, user, workspace)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := res.LastInsertId()
+
+	if _, err := db.Exec("UPDATE todos SET "+state+" WHERE id_todo = ?;", id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func TestQuotaIsolationBetweenWorkspaces(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "member")
+	full := insertTestWorkspace(t, db, "full", user)
+	other := insertTestWorkspace(t, db, "other", user)
+
+	fillToDos(t, db, user, 0, 20)
+	fillToDos(t, db, user, full, workspaceLimits[WORKSPACE_PLAN_FREE][WORKSPACE_TODOS])
+
+	tests := []struct {
+		name      string
+		workspace int64
+		err       string
+	}{
+		{"personal quota is full", 0, "todos_limit"},
+		{"workspace quota is full", full, "todos_limit"},
+		{"another workspace only counts its own", other, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			owner := ToDo{CreatedBy: user}
+
+			completed := insertInactiveToDo(t, db, user, test.workspace, "completed = 1, completed_at = now()")
+
+			if _, err := owner.SetCompleted(completed, false, db); locales.CodeOf(err) != test.err {
+				t.Errorf("SetCompleted(false) = %v, want %q", err, test.err)
+			}
+
+			archived := insertInactiveToDo(t, db, user, test.workspace, "archived_at = now()")
+
+			if err := owner.SetArchived(archived, false, db); locales.CodeOf(err) != test.err {
+				t.Errorf("SetArchived(false) = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestListingIsolationBetweenWorkspaces(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "member")
+	outsider := insertTestUser(t, db, "outsider")
+	first := insertTestWorkspace(t, db, "first", user)
+	second := insertTestWorkspace(t, db, "second", user)
+
+	fillToDos(t, db, user, 0, 1)
+	fillToDos(t, db, user, first, 2)
+	fillToDos(t, db, user, second, 3)
+
+	tests := []struct {
+		name      string
+		user      int64
+		workspace int64
+		total     int
+		err       string
+	}{
+		{"personal leaves the workspaces out", user, 0, 1, ""},
+		{"first workspace", user, first, 2, ""},
+		{"second workspace", user, second, 3, ""},
+		{"outsider sees nothing of the workspace", outsider, first, 0, "workspace_not_found"},
+		{"outsider has no personal to dos", outsider, 0, 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			owner := ToDo{CreatedBy: test.user}
+			filter := ToDoFilter{Workspace: test.workspace}
+
+			if !filter.Validate() {
+				t.Fatal("Validate failed")
+			}
+
+			page, err := owner.GetAllToDosFromUserId(filter, db)
+
+			if locales.CodeOf(err) != test.err {
+				t.Fatalf("GetAllToDosFromUserId = %v, want %q", err, test.err)
+			}
+
+			if page.Total != test.total || len(page.ToDos) != test.total {
+				t.Fatalf("total = %d with %d to dos, want %d", page.Total, len(page.ToDos), test.total)
+			}
+
+			for _, todo := range page.ToDos {
+				if todo["workspace"] != test.workspace {
+					t.Errorf("to do %v of workspace %v listed in %d", todo["id"], todo["workspace"], test.workspace)
+				}
+			}
+		})
+	}
+}
+
+// Every resource of the plans answers with its own catalog code when it is over the limit
+func TestWorkspaceLimitErrors(t *testing.T) {
+	for resource := range workspaceLimits[WORKSPACE_PLAN_FREE] {
+		code, ok := workspaceLimitErrors[resource]
+
+		if !ok {
+			t.Errorf("%s has no limit error", resource)
+			continue
+		}
+
+		if _, ok := locales.Message(locales.DEFAULT_LOCALE, code); !ok {
+			t.Errorf("%s answers %q, which is not in the catalog", resource, code)
+		}
+	}
+}
+
+func TestWorkspaceImagesLimit(t *testing.T) {
+	db := testDB(t)
+	user := insertTestUser(t, db, "uploader")
+	workspace := insertTestWorkspace(t, db, "gallery", user)
+
+	for i := 0; i < workspaceLimits[WORKSPACE_PLAN_FREE][WORKSPACE_IMAGES]; i++ {
+		_, err := db.Exec("INSERT INTO images (image_url, public_id, id_user, id_workspace) VALUES ( 'url', 'public', ?, ? );", user, workspace)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := CheckWorkspaceQuota(workspace, user, WORKSPACE_IMAGES, db)
+
+	if code := locales.CodeOf(err); code != "images_limit" {
+		t.Errorf("CheckWorkspaceQuota = %q, want images_limit", code)
+	}
+}
@@ -0,0 +1,692 @@
+// Quick add of to dos from a line of text in English or Spanish, e.g.
+//
+//	Pay rent tomorrow 9am #home !red
+//	Pagar la renta mañana a las 9 #casa !rojo
+//	Call mom friday at 18:30
+//	Dentist in 2 weeks
+//	Reunión el 3 de mayo por la tarde
+//	Send report 5/3 5pm !#00FF00
+//
+// Dates, times, #tags and !colors are taken out of the text and the rest is the title.
+// Weekdays are the next one after today, today too with this or este, and a time alone
+// is the next one to come
+package quickadd
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LOCALE_EN = "en"
+	LOCALE_ES = "es"
+
+	// Wall clock time of the deadlines that only give a day, and of the text without any
+	DEFAULT_HOUR   = 23
+	DEFAULT_MINUTE = 59
+
+	// Largest amount of "in 3 days"
+	MAX_AMOUNT = 999
+)
+
+const punctuation = ",.;:!?"
+
+var ErrEmptyText = errors.New("empty text")
+
+type Result struct {
+	Title    string
+	Deadline time.Time
+	// False when the text had no date nor time and the deadline is the end of today
+	Dated bool
+	// Names after the #, as written
+	Tags  []string
+	Color *uint
+}
+
+var colors = map[string]uint{
+	"red":      0xFF0000,
+	"rojo":     0xFF0000,
+	"orange":   0xFFA500,
+	"naranja":  0xFFA500,
+	"yellow":   0xFFFF00,
+	"amarillo": 0xFFFF00,
+	"green":    0x008000,
+	"verde":    0x008000,
+	"blue":     0x0000FF,
+	"azul":     0x0000FF,
+	"purple":   0x800080,
+	"morado":   0x800080,
+	"pink":     0xFFC0CB,
+	"rosa":     0xFFC0CB,
+	"brown":    0xA52A2A,
+	"cafe":     0xA52A2A,
+	"marron":   0xA52A2A,
+	"gray":     0x808080,
+	"grey":     0x808080,
+	"gris":     0x808080,
+	"black":    0x000000,
+	"negro":    0x000000,
+	"white":    0xFFFFFF,
+	"blanco":   0xFFFFFF,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"domingo":   time.Sunday,
+	"lunes":     time.Monday,
+	"martes":    time.Tuesday,
+	"miercoles": time.Wednesday,
+	"jueves":    time.Thursday,
+	"viernes":   time.Friday,
+	"sabado":    time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january":    time.January,
+	"jan":        time.January,
+	"february":   time.February,
+	"feb":        time.February,
+	"march":      time.March,
+	"mar":        time.March,
+	"april":      time.April,
+	"apr":        time.April,
+	"may":        time.May,
+	"june":       time.June,
+	"jun":        time.June,
+	"july":       time.July,
+	"jul":        time.July,
+	"august":     time.August,
+	"aug":        time.August,
+	"september":  time.September,
+	"sep":        time.September,
+	"sept":       time.September,
+	"october":    time.October,
+	"oct":        time.October,
+	"november":   time.November,
+	"nov":        time.November,
+	"december":   time.December,
+	"dec":        time.December,
+	"enero":      time.January,
+	"febrero":    time.February,
+	"marzo":      time.March,
+	"abril":      time.April,
+	"mayo":       time.May,
+	"junio":      time.June,
+	"julio":      time.July,
+	"agosto":     time.August,
+	"septiembre": time.September,
+	"setiembre":  time.September,
+	"octubre":    time.October,
+	"noviembre":  time.November,
+	"diciembre":  time.December,
+}
+
+// Hour each part of the day stands for
+var periods = map[string]int{
+	"morning":   9,
+	"afternoon": 15,
+	"evening":   19,
+	"night":     20,
+	"manana":    9,
+	"tarde":     15,
+	"noche":     20,
+}
+
+var amounts = map[string]int{
+	"a":   1,
+	"an":  1,
+	"one": 1,
+	"un":  1,
+	"una": 1,
+	"uno": 1,
+}
+
+var units = map[string]string{
+	"minute":  "minute",
+	"minutes": "minute",
+	"min":     "minute",
+	"mins":    "minute",
+	"minuto":  "minute",
+	"minutos": "minute",
+	"hour":    "hour",
+	"hours":   "hour",
+	"hora":    "hour",
+	"horas":   "hour",
+	"day":     "day",
+	"days":    "day",
+	"dia":     "day",
+	"dias":    "day",
+	"week":    "week",
+	"weeks":   "week",
+	"semana":  "week",
+	"semanas": "week",
+	"month":   "month",
+	"months":  "month",
+	"mes":     "month",
+	"meses":   "month",
+}
+
+// Words before a date that are part of it, "on friday", "el próximo lunes"
+var datePrefixes = []string{"on", "this", "next", "by", "due", "el", "este", "proximo", "la", "proxima", "para"}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a\.m|p\.m|h)?$`)
+
+var accents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+type parser struct {
+	// As written and folded for matching
+	words  []string
+	folded []string
+	locale string
+	now    time.Time
+
+	// Day of the deadline as a civil day, see today, zero when the text has none
+	date     time.Time
+	hasTime  bool
+	hour     int
+	minute   int
+	meridiem bool
+	// Hour of the part of the day, zero when the text has none
+	period int
+
+	result *Result
+}
+
+// Each matcher returns how many words it took at i, zero when they are not its own
+type matcher func(p *parser, i int) int
+
+var matchers = []matcher{matchTag, matchColor, matchRelative, matchPeriod, matchDate, matchClock}
+
+// Parses text with now in the timezone of the user. The locale only decides whether
+// 5/3 is the 3rd of May (en) or the 5th of March (es), the words of both are understood
+func Parse(text, locale string, now time.Time) (*Result, error) {
+	words := strings.Fields(text)
+
+	if len(words) == 0 {
+		return nil, ErrEmptyText
+	}
+
+	p := &parser{
+		words:  words,
+		folded: make([]string, len(words)),
+		locale: locale,
+		now:    now,
+		result: &Result{Tags: make([]string, 0)},
+	}
+
+	for i, word := range words {
+		p.folded[i] = accents.Replace(strings.TrimRight(strings.ToLower(word), punctuation))
+	}
+
+	title := make([]string, 0, len(words))
+
+	for i := 0; i < len(words); {
+		n := 0
+
+		for _, match := range matchers {
+			if n = match(p, i); n > 0 {
+				break
+			}
+		}
+
+		if n == 0 {
+			title = append(title, words[i])
+			n = 1
+		}
+
+		i += n
+	}
+
+	p.result.Title = strings.Join(title, " ")
+	p.result.Deadline, p.result.Dated = p.deadline()
+
+	return p.result, nil
+}
+
+// The folded word at i, empty past the end
+func (p *parser) at(i int) string {
+	if i < 0 || i >= len(p.folded) {
+		return ""
+	}
+
+	return p.folded[i]
+}
+
+// Today as a civil day, at midnight in UTC, so adding days doesn't suffer from DST
+// nor from the zones where a change skips midnight
+func (p *parser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// The time of the civil day in the timezone of the user. A time skipped by a DST change
+// is read with the offset from before it, so 2:30 on a spring forward day is 3:30
+func (p *parser) wallClock(day time.Time, hour, minute int) time.Time {
+	loc := p.now.Location()
+	t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+
+	if t.Hour() == hour && t.Minute() == minute {
+		return t
+	}
+
+	_, before := t.Add(-3 * time.Hour).Zone()
+	_, after := t.Add(3 * time.Hour).Zone()
+
+	wall := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.UTC)
+	return wall.Add(-time.Duration(min(before, after)) * time.Second).In(loc)
+}
+
+func (p *parser) deadline() (time.Time, bool) {
+	date, hour, minute := p.date, DEFAULT_HOUR, DEFAULT_MINUTE
+
+	if date.IsZero() {
+		date = p.today()
+	}
+
+	switch {
+	case p.hasTime:
+		hour, minute = p.hour, p.minute
+
+		// "9 de la noche"
+		if !p.meridiem && p.period >= 12 && hour < 12 {
+			hour += 12
+		}
+	case p.period != 0:
+		hour, minute = p.period, 0
+	}
+
+	deadline := p.wallClock(date, hour, minute)
+	timed := p.hasTime || p.period != 0
+
+	if p.date.IsZero() && timed && deadline.Before(p.now) {
+		deadline = p.wallClock(date.AddDate(0, 0, 1), hour, minute)
+	}
+
+	return deadline, !p.date.IsZero() || timed
+}
+
+func matchTag(p *parser, i int) int {
+	name, ok := strings.CutPrefix(p.words[i], "#")
+	name = strings.TrimRight(name, punctuation)
+
+	if !ok || name == "" {
+		return 0
+	}
+
+	p.result.Tags = append(p.result.Tags, name)
+	return 1
+}
+
+// !red, !rojo or !#FF0000
+func matchColor(p *parser, i int) int {
+	name, ok := strings.CutPrefix(p.folded[i], "!")
+
+	if !ok || p.result.Color != nil {
+		return 0
+	}
+
+	color, ok := colors[name]
+
+	if !ok {
+		hex := strings.TrimPrefix(name, "#")
+		value, err := strconv.ParseUint(hex, 16, 32)
+
+		if len(hex) != 6 || err != nil {
+			return 0
+		}
+
+		color = uint(value)
+	}
+
+	p.result.Color = &color
+	return 1
+}
+
+// "in 3 days", "en 2 horas"
+func matchRelative(p *parser, i int) int {
+	if !p.date.IsZero() || p.hasTime || (p.at(i) != "in" && p.at(i) != "en") {
+		return 0
+	}
+
+	n, ok := amounts[p.at(i+1)]
+
+	if !ok {
+		var err error
+		if n, err = strconv.Atoi(p.at(i + 1)); err != nil || n < 1 || n > MAX_AMOUNT {
+			return 0
+		}
+	}
+
+	today := p.today()
+
+	switch units[p.at(i+2)] {
+	case "minute", "hour":
+		unit := time.Minute
+		if units[p.at(i+2)] == "hour" {
+			unit = time.Hour
+		}
+
+		exact := p.now.Add(time.Duration(n) * unit)
+		p.date = time.Date(exact.Year(), exact.Month(), exact.Day(), 0, 0, 0, 0, time.UTC)
+		p.hasTime, p.hour, p.minute, p.meridiem = true, exact.Hour(), exact.Minute(), true
+	case "day":
+		p.date = today.AddDate(0, 0, n)
+	case "week":
+		p.date = today.AddDate(0, 0, 7*n)
+	case "month":
+		p.date = today.AddDate(0, n, 0)
+	default:
+		return 0
+	}
+
+	return 3
+}
+
+// "in the morning", "por la tarde", "de la noche"
+func matchPeriod(p *parser, i int) int {
+	if p.period != 0 {
+		return 0
+	}
+
+	n := 0
+
+	switch {
+	case p.at(i) == "in" && p.at(i+1) == "the":
+		n = 3
+	case slices.Contains([]string{"de", "por", "en"}, p.at(i)) && p.at(i+1) == "la":
+		n = 3
+	case p.at(i) == "at" && p.at(i+1) == "night":
+		n = 2
+	default:
+		return 0
+	}
+
+	hour, ok := periods[p.at(i+n-1)]
+
+	if !ok {
+		return 0
+	}
+
+	p.period = hour
+	return n
+}
+
+func matchDate(p *parser, i int) int {
+	if !p.date.IsZero() {
+		return 0
+	}
+
+	// The longest run of prefixes that is followed by a date
+	for k := 3; k >= 0; k-- {
+		prefixed := true
+
+		for j := i; j < i+k; j++ {
+			prefixed = prefixed && slices.Contains(datePrefixes, p.at(j))
+		}
+
+		if !prefixed {
+			continue
+		}
+
+		// "this friday" said on a friday is today, a weekday alone is the next one
+		this := k > 0 && (p.at(i+k-1) == "this" || p.at(i+k-1) == "este")
+
+		if n := p.matchDay(i+k, this); n > 0 {
+			return k + n
+		}
+	}
+
+	return 0
+}
+
+func (p *parser) matchDay(j int, this bool) int {
+	today := p.today()
+	word := p.at(j)
+
+	switch {
+	case word == "today" || word == "hoy":
+		p.date = today
+		return 1
+	case word == "tonight" || (word == "esta" && p.at(j+1) == "noche"):
+		p.date = today
+		if p.period == 0 {
+			p.period = periods["night"]
+		}
+
+		if word == "esta" {
+			return 2
+		}
+		return 1
+	case word == "pasado" && p.at(j+1) == "manana":
+		p.date = today.AddDate(0, 0, 2)
+		return 2
+	case word == "day" && p.at(j+1) == "after" && p.at(j+2) == "tomorrow":
+		p.date = today.AddDate(0, 0, 2)
+		return 3
+	case word == "tomorrow" || word == "manana":
+		p.date = today.AddDate(0, 0, 1)
+		return 1
+	case (word == "next" && p.at(j+1) == "week") || (word == "proxima" && p.at(j+1) == "semana"):
+		p.date = today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+		return 2
+	case word == "semana" && p.at(j+1) == "que" && p.at(j+2) == "viene":
+		p.date = today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+		return 3
+	}
+
+	if weekday, ok := weekdays[word]; ok {
+		days := (int(weekday)-int(today.Weekday())+6)%7 + 1
+
+		if this {
+			days %= 7
+		}
+
+		p.date = today.AddDate(0, 0, days)
+		return 1
+	}
+
+	date, n := p.matchCalendar(j)
+
+	if n > 0 {
+		p.date = date
+	}
+
+	return n
+}
+
+// 2025-05-03, 5/3, 5/3/2025, may 3, 3 may, 3 de mayo de 2025
+func (p *parser) matchCalendar(j int) (time.Time, int) {
+	word := p.at(j)
+
+	if date, err := time.Parse("2006-01-02", word); err == nil {
+		return date, 1
+	}
+
+	if parts := strings.Split(word, "/"); len(parts) == 2 || len(parts) == 3 {
+		first, err1 := strconv.Atoi(parts[0])
+		second, err2 := strconv.Atoi(parts[1])
+		year := 0
+
+		if len(parts) == 3 {
+			var err error
+			if year, err = strconv.Atoi(parts[2]); err != nil || year < 1 {
+				return time.Time{}, 0
+			}
+
+			if year < 100 {
+				year += 2000
+			}
+		}
+
+		if err1 != nil || err2 != nil {
+			return time.Time{}, 0
+		}
+
+		month, day := first, second
+		if p.locale == LOCALE_ES {
+			month, day = second, first
+		}
+
+		return p.calendarDay(year, month, day, 1)
+	}
+
+	if month, ok := months[word]; ok {
+		day, ok := dayNumber(p.at(j + 1))
+
+		if !ok {
+			return time.Time{}, 0
+		}
+
+		if year, ok := yearNumber(p.at(j + 2)); ok {
+			return p.calendarDay(year, int(month), day, 3)
+		}
+
+		return p.calendarDay(0, int(month), day, 2)
+	}
+
+	day, ok := dayNumber(word)
+
+	if !ok {
+		return time.Time{}, 0
+	}
+
+	n := 1
+	if p.at(j+n) == "de" {
+		n++
+	}
+
+	month, ok := months[p.at(j+n)]
+
+	if !ok {
+		return time.Time{}, 0
+	}
+
+	n++
+
+	if p.at(j+n) == "de" {
+		if year, ok := yearNumber(p.at(j + n + 1)); ok {
+			return p.calendarDay(year, int(month), day, n+2)
+		}
+	} else if year, ok := yearNumber(p.at(j + n)); ok {
+		return p.calendarDay(year, int(month), day, n+1)
+	}
+
+	return p.calendarDay(0, int(month), day, n)
+}
+
+// The day, or zero words when it does not exist. Without a year it is the next one to come
+func (p *parser) calendarDay(year, month, day, n int) (time.Time, int) {
+	today := p.today()
+	explicit := year != 0
+
+	if !explicit {
+		year = today.Year()
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+
+	if !explicit && date.Before(today) {
+		year++
+		date = time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	}
+
+	if month < 1 || month > 12 || date.Day() != day {
+		return time.Time{}, 0
+	}
+
+	return date, n
+}
+
+// "3", "3rd"
+func dayNumber(word string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		word = strings.TrimSuffix(word, suffix)
+	}
+
+	day, err := strconv.Atoi(word)
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+func yearNumber(word string) (int, bool) {
+	year, err := strconv.Atoi(word)
+	return year, err == nil && len(word) == 4
+}
+
+// "9am", "9 pm", "at 18:30", "a las 9", "noon"
+func matchClock(p *parser, i int) int {
+	if p.hasTime {
+		return 0
+	}
+
+	n := 0
+
+	switch {
+	case p.at(i) == "at" || p.at(i) == "@":
+		n = 1
+	case p.at(i) == "a" && (p.at(i+1) == "las" || p.at(i+1) == "la"):
+		n = 2
+	}
+
+	prefixed := n > 0
+
+	if word := p.at(i + n); word == "noon" || word == "mediodia" {
+		p.hasTime, p.hour, p.minute, p.meridiem = true, 12, 0, true
+		return n + 1
+	}
+
+	match := clockPattern.FindStringSubmatch(p.at(i + n))
+
+	if match == nil {
+		return 0
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	suffix := match[3]
+	n++
+
+	if next := p.at(i + n); suffix == "" && slices.Contains([]string{"am", "pm", "a.m", "p.m"}, next) {
+		suffix = next
+		n++
+	}
+
+	// A number alone is not a time, "buy 3 apples"
+	if !prefixed && suffix == "" && match[2] == "" {
+		return 0
+	}
+
+	switch suffix {
+	case "am", "a.m", "pm", "p.m":
+		if hour < 1 || hour > 12 {
+			return 0
+		}
+
+		hour %= 12
+		if suffix == "pm" || suffix == "p.m" {
+			hour += 12
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0
+	}
+
+	p.hasTime, p.hour, p.minute = true, hour, minute
+	p.meridiem = suffix != "" && suffix != "h"
+
+	return n
+}
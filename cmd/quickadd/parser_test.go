@@ -0,0 +1,354 @@
+package quickadd
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loc
+}
+
+func color(c uint) *uint {
+	return &c
+}
+
+func TestParse(t *testing.T) {
+	mexico := mustLoad(t, "America/Mexico_City")
+	newYork := mustLoad(t, "America/New_York")
+	santiago := mustLoad(t, "America/Santiago")
+
+	// A monday morning, and a friday one
+	monday := time.Date(2026, 10, 19, 10, 0, 0, 0, mexico)
+	friday := time.Date(2026, 10, 23, 10, 0, 0, 0, mexico)
+
+	tests := []struct {
+		name   string
+		text   string
+		locale string
+		now    time.Time
+		title  string
+		want   time.Time
+		dated  bool
+		tags   []string
+		color  *uint
+	}{
+		// The examples of the package doc
+		{
+			name:  "english with time, tag and color",
+			text:  "Pay rent tomorrow 9am #home !red",
+			now:   monday,
+			title: "Pay rent",
+			want:  time.Date(2026, 10, 20, 9, 0, 0, 0, mexico),
+			dated: true,
+			tags:  []string{"home"},
+			color: color(0xFF0000),
+		},
+		{
+			name:   "spanish with time, tag and color",
+			text:   "Pagar la renta mañana a las 9 #casa !rojo",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Pagar la renta",
+			want:   time.Date(2026, 10, 20, 9, 0, 0, 0, mexico),
+			dated:  true,
+			tags:   []string{"casa"},
+			color:  color(0xFF0000),
+		},
+		{
+			name:  "weekday at a 24 hour time",
+			text:  "Call mom friday at 18:30",
+			now:   monday,
+			title: "Call mom",
+			want:  time.Date(2026, 10, 23, 18, 30, 0, 0, mexico),
+			dated: true,
+		},
+		{
+			name:  "in weeks",
+			text:  "Dentist in 2 weeks",
+			now:   monday,
+			title: "Dentist",
+			want:  time.Date(2026, 11, 2, 23, 59, 0, 0, mexico),
+			dated: true,
+		},
+		{
+			name:   "spanish day of a month that already passed",
+			text:   "Reunión el 3 de mayo por la tarde",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Reunión",
+			want:   time.Date(2027, 5, 3, 15, 0, 0, 0, mexico),
+			dated:  true,
+		},
+		{
+			name:  "slashed date is month first in english",
+			text:  "Send report 5/3 5pm !#00FF00",
+			now:   monday,
+			title: "Send report",
+			want:  time.Date(2027, 5, 3, 17, 0, 0, 0, mexico),
+			dated: true,
+			color: color(0x00FF00),
+		},
+		{
+			name:   "slashed date is day first in spanish",
+			text:   "Send report 5/3 5pm !#00FF00",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Send report",
+			want:   time.Date(2027, 3, 5, 17, 0, 0, 0, mexico),
+			dated:  true,
+			color:  color(0x00FF00),
+		},
+		{
+			name:   "slashed date with a year",
+			text:   "Renew passport 5/3/2028",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Renew passport",
+			want:   time.Date(2028, 3, 5, 23, 59, 0, 0, mexico),
+			dated:  true,
+		},
+
+		// Mañana is tomorrow, la mañana is the morning
+		{
+			name:   "mañana alone is tomorrow",
+			text:   "Correr mañana",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Correr",
+			want:   time.Date(2026, 10, 20, 23, 59, 0, 0, mexico),
+			dated:  true,
+		},
+		{
+			name:   "por la mañana is the morning",
+			text:   "Correr por la mañana",
+			locale: LOCALE_ES,
+			now:    time.Date(2026, 10, 19, 7, 0, 0, 0, mexico),
+			title:  "Correr",
+			want:   time.Date(2026, 10, 19, 9, 0, 0, 0, mexico),
+			dated:  true,
+		},
+		{
+			name:   "mañana por la mañana",
+			text:   "Correr mañana por la mañana",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Correr",
+			want:   time.Date(2026, 10, 20, 9, 0, 0, 0, mexico),
+			dated:  true,
+		},
+		{
+			name:   "a time of the morning already gone is tomorrow",
+			text:   "Correr a las 8 de la mañana",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Correr",
+			want:   time.Date(2026, 10, 20, 8, 0, 0, 0, mexico),
+			dated:  true,
+		},
+		{
+			name:   "9 de la noche",
+			text:   "Cenar a las 9 de la noche",
+			locale: LOCALE_ES,
+			now:    monday,
+			title:  "Cenar",
+			want:   time.Date(2026, 10, 19, 21, 0, 0, 0, mexico),
+			dated:  true,
+		},
+
+		// Numbers that are not dates nor times stay in the title
+		{
+			name:  "a number alone",
+			text:  "buy 3 apples",
+			now:   monday,
+			title: "buy 3 apples",
+			want:  time.Date(2026, 10, 19, 23, 59, 0, 0, mexico),
+		},
+		{
+			name:  "a number alone with a date",
+			text:  "buy 3 apples tomorrow",
+			now:   monday,
+			title: "buy 3 apples",
+			want:  time.Date(2026, 10, 20, 23, 59, 0, 0, mexico),
+			dated: true,
+		},
+		{
+			name:  "no date",
+			text:  "Water the plants",
+			now:   monday,
+			title: "Water the plants",
+			want:  time.Date(2026, 10, 19, 23, 59, 0, 0, mexico),
+		},
+
+		// Weekdays
+		{
+			name:  "weekday on that same weekday is the next one",
+			text:  "Pay friday",
+			now:   friday,
+			title: "Pay",
+			want:  time.Date(2026, 10, 30, 23, 59, 0, 0, mexico),
+			dated: true,
+		},
+		{
+			name:  "this weekday on that same weekday is today",
+			text:  "Pay this friday",
+			now:   friday,
+			title: "Pay",
+			want:  time.Date(2026, 10, 23, 23, 59, 0, 0, mexico),
+			dated: true,
+		},
+		{
+			name:   "este weekday on that same weekday is today",
+			text:   "Pagar este viernes",
+			locale: LOCALE_ES,
+			now:    friday,
+			title:  "Pagar",
+			want:   time.Date(2026, 10, 23, 23, 59, 0, 0, mexico),
+			dated:  true,
+		},
+		{
+			name:  "this weekday later in the week",
+			text:  "Pay this friday",
+			now:   monday,
+			title: "Pay",
+			want:  time.Date(2026, 10, 23, 23, 59, 0, 0, mexico),
+			dated: true,
+		},
+		{
+			name:  "next weekday on that same weekday is the next one",
+			text:  "Pay next friday",
+			now:   friday,
+			title: "Pay",
+			want:  time.Date(2026, 10, 30, 23, 59, 0, 0, mexico),
+			dated: true,
+		},
+
+		// DST changes: New York springs forward on March 8 2026 and falls back on
+		// November 1, Santiago springs forward at midnight on September 6
+		{
+			name:  "tomorrow across spring forward",
+			text:  "Standup tomorrow 9am",
+			now:   time.Date(2026, 3, 7, 10, 0, 0, 0, newYork),
+			title: "Standup",
+			want:  time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			dated: true,
+		},
+		{
+			name:  "a time skipped by spring forward moves forward",
+			text:  "Backup tomorrow at 2:30",
+			now:   time.Date(2026, 3, 7, 10, 0, 0, 0, newYork),
+			title: "Backup",
+			want:  time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC),
+			dated: true,
+		},
+		{
+			name:  "in hours across spring forward",
+			text:  "Check oven in 3 hours",
+			now:   time.Date(2026, 3, 8, 0, 30, 0, 0, newYork),
+			title: "Check oven",
+			want:  time.Date(2026, 3, 8, 8, 30, 0, 0, time.UTC),
+			dated: true,
+		},
+		{
+			name:  "a time repeated by fall back takes the first one",
+			text:  "Backup tomorrow at 1:30am",
+			now:   time.Date(2026, 10, 31, 10, 0, 0, 0, newYork),
+			title: "Backup",
+			want:  time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+			dated: true,
+		},
+		{
+			name:  "end of the fall back day",
+			text:  "Read tomorrow",
+			now:   time.Date(2026, 10, 31, 10, 0, 0, 0, newYork),
+			title: "Read",
+			want:  time.Date(2026, 11, 2, 4, 59, 0, 0, time.UTC),
+			dated: true,
+		},
+		{
+			name:   "mañana when midnight is skipped",
+			text:   "Leer mañana",
+			locale: LOCALE_ES,
+			now:    time.Date(2026, 9, 5, 10, 0, 0, 0, santiago),
+			title:  "Leer",
+			want:   time.Date(2026, 9, 6, 23, 59, 0, 0, santiago),
+			dated:  true,
+		},
+		{
+			name:   "hoy on the day midnight was skipped",
+			text:   "Leer hoy",
+			locale: LOCALE_ES,
+			now:    time.Date(2026, 9, 6, 10, 0, 0, 0, santiago),
+			title:  "Leer",
+			want:   time.Date(2026, 9, 6, 23, 59, 0, 0, santiago),
+			dated:  true,
+		},
+		{
+			name:   "en días from the day midnight was skipped",
+			text:   "Leer en 2 días",
+			locale: LOCALE_ES,
+			now:    time.Date(2026, 9, 6, 10, 0, 0, 0, santiago),
+			title:  "Leer",
+			want:   time.Date(2026, 9, 8, 23, 59, 0, 0, santiago),
+			dated:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			locale := test.locale
+			if locale == "" {
+				locale = LOCALE_EN
+			}
+
+			got, err := Parse(test.text, locale, test.now)
+
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.text, err)
+			}
+
+			if got.Title != test.title {
+				t.Errorf("title = %q, want %q", got.Title, test.title)
+			}
+
+			if !got.Deadline.Equal(test.want) {
+				t.Errorf("deadline = %v, want %v", got.Deadline, test.want.In(test.now.Location()))
+			}
+
+			if got.Dated != test.dated {
+				t.Errorf("dated = %v, want %v", got.Dated, test.dated)
+			}
+
+			tags := test.tags
+			if tags == nil {
+				tags = []string{}
+			}
+
+			if !slices.Equal(got.Tags, tags) {
+				t.Errorf("tags = %v, want %v", got.Tags, tags)
+			}
+
+			if (got.Color == nil) != (test.color == nil) || (got.Color != nil && *got.Color != *test.color) {
+				t.Errorf("color = %v, want %v", got.Color, test.color)
+			}
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	for _, text := range []string{"", "   "} {
+		if _, err := Parse(text, LOCALE_EN, time.Now()); err != ErrEmptyText {
+			t.Errorf("Parse(%q) = %v, want ErrEmptyText", text, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Subset of the RFC 5545 RRULE used by recurring to dos, e.g.
+//
+//	FREQ=DAILY
+//	FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR
+//	FREQ=WEEKLY;INTERVAL=2
+//	FREQ=MONTHLY;BYMONTHDAY=15
+//	FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=12
+//	FREQ=YEARLY;UNTIL=20301231T235959Z
+package recurrence
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DAILY   = "DAILY"
+	WEEKLY  = "WEEKLY"
+	MONTHLY = "MONTHLY"
+	YEARLY  = "YEARLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var ErrInvalidRule = errors.New("invalid recurrence")
+
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	// 1 to 31, or -1 to -31 counting from the end of the month
+	ByMonthDay []int
+	// Occurrences left including the current one, zero means no limit
+	Count int
+	Until time.Time
+}
+
+func Parse(rule string) (*Rule, error) {
+	r := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		key, value, found := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		if !found || value == "" || seen[key] {
+			return nil, ErrInvalidRule
+		}
+		seen[key] = true
+
+		var err error
+
+		switch key {
+		case "FREQ":
+			r.Freq = value
+			if r.Freq != DAILY && r.Freq != WEEKLY && r.Freq != MONTHLY && r.Freq != YEARLY {
+				return nil, ErrInvalidRule
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 || r.Interval > 365 {
+				return nil, ErrInvalidRule
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, ErrInvalidRule
+				}
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, ErrInvalidRule
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err != nil || r.Count < 1 {
+				return nil, ErrInvalidRule
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+			if err != nil {
+				return nil, ErrInvalidRule
+			}
+		default:
+			return nil, ErrInvalidRule
+		}
+	}
+
+	if r.Freq == "" || (r.Count != 0 && !r.Until.IsZero()) {
+		return nil, ErrInvalidRule
+	}
+
+	if len(r.ByDay) != 0 && r.Freq != DAILY && r.Freq != WEEKLY {
+		return nil, ErrInvalidRule
+	}
+
+	if len(r.ByMonthDay) != 0 && r.Freq != MONTHLY {
+		return nil, ErrInvalidRule
+	}
+
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+
+	// A date alone includes the whole day
+	t, err := time.Parse("20060102", value)
+	return t.Add(24*time.Hour - time.Second), err
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) != 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for name, weekday := range weekdays {
+			if slices.Contains(r.ByDay, weekday) {
+				days = append(days, name)
+			}
+		}
+		slices.SortFunc(days, func(a, b string) int {
+			return int(weekdays[a]+6)%7 - int(weekdays[b]+6)%7
+		})
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(r.ByMonthDay) != 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Returns the occurrence that follows the one at from, keeping the wall clock time of
+// from in loc so a 9:00 deadline stays at 9:00 across DST changes. The rule of the
+// next occurrence is returned too, with its COUNT decreased
+func (r *Rule) Next(from time.Time, loc *time.Location) (time.Time, *Rule, bool) {
+	if r.Count == 1 {
+		return time.Time{}, nil, false
+	}
+
+	from = from.In(loc)
+	fromDay := civilDay(from)
+	limit := 366 * 8 * r.Interval
+
+	for i := 1; i <= limit; i++ {
+		candidate := wallClock(from.Year(), from.Month(), from.Day()+i, from.Hour(), from.Minute(), from.Second(), loc)
+
+		if !r.matches(fromDay, civilDay(candidate), from) {
+			continue
+		}
+
+		if !r.Until.IsZero() && candidate.After(r.Until) {
+			return time.Time{}, nil, false
+		}
+
+		next := *r
+		if next.Count > 1 {
+			next.Count--
+		}
+
+		return candidate, &next, true
+	}
+
+	return time.Time{}, nil, false
+}
+
+// Like time.Date, but a time skipped by a DST change is read with the offset from
+// before the change as RFC 5545 does, so 2:30 on a spring forward day is 3:30
+func wallClock(year int, month time.Month, day, hour, minute, sec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, minute, sec, 0, loc)
+
+	if t.Hour() == hour && t.Minute() == minute {
+		return t
+	}
+
+	_, before := t.Add(-3 * time.Hour).Zone()
+	_, after := t.Add(3 * time.Hour).Zone()
+
+	wall := time.Date(year, month, day, hour, minute, sec, 0, time.UTC)
+	return wall.Add(-time.Duration(min(before, after)) * time.Second).In(loc)
+}
+
+// Date without time nor zone, so day arithmetic doesn't suffer from DST
+func civilDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (r *Rule) matches(start, day time.Time, from time.Time) bool {
+	switch r.Freq {
+	case DAILY:
+		days := int(day.Sub(start).Hours() / 24)
+		return days%r.Interval == 0 && r.matchesWeekday(day, from)
+	case WEEKLY:
+		weeks := int(weekStart(day).Sub(weekStart(start)).Hours() / 24 / 7)
+		return weeks%r.Interval == 0 && r.matchesWeekday(day, from)
+	case MONTHLY:
+		months := (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+		return months%r.Interval == 0 && r.matchesMonthDay(day, from)
+	case YEARLY:
+		years := day.Year() - start.Year()
+		return years%r.Interval == 0 && day.Month() == from.Month() && day.Day() == from.Day()
+	}
+
+	return false
+}
+
+func (r *Rule) matchesWeekday(day time.Time, from time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return r.Freq == DAILY || day.Weekday() == from.Weekday()
+	}
+
+	return slices.Contains(r.ByDay, day.Weekday())
+}
+
+func (r *Rule) matchesMonthDay(day time.Time, from time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		// Months without that day are skipped, as RFC 5545 does
+		return day.Day() == from.Day()
+	}
+
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay < 0 {
+			monthDay = daysInMonth + monthDay + 1
+		}
+
+		if monthDay == day.Day() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Monday of the week of day, weeks start on monday as the RRULE default WKST
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,275 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	madrid := mustLoad(t, "Europe/Madrid")
+
+	// 2026 DST changes: New York springs forward on March 8 and falls back on
+	// November 1, Madrid on March 29 and October 25
+	tests := []struct {
+		name  string
+		rule  string
+		loc   *time.Location
+		from  time.Time
+		want  time.Time
+		ok    bool
+		count int
+	}{
+		{
+			name: "daily across spring forward",
+			rule: "FREQ=DAILY",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "daily across fall back",
+			rule: "FREQ=DAILY",
+			loc:  newYork,
+			from: time.Date(2026, 10, 31, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "daily into the skipped hour moves forward",
+			rule: "FREQ=DAILY",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 2, 30, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "daily into the repeated hour takes the first one",
+			rule: "FREQ=DAILY",
+			loc:  newYork,
+			from: time.Date(2026, 10, 31, 1, 30, 0, 0, newYork),
+			want: time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "daily every 2 days across spring forward in Madrid",
+			rule: "FREQ=DAILY;INTERVAL=2",
+			loc:  madrid,
+			from: time.Date(2026, 3, 28, 9, 0, 0, 0, madrid),
+			want: time.Date(2026, 3, 30, 7, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "weekly by day on the spring forward sunday",
+			rule: "FREQ=WEEKLY;BYDAY=SU",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "weekly across fall back",
+			rule: "FREQ=WEEKLY",
+			loc:  newYork,
+			from: time.Date(2026, 10, 25, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "weekly on work days skips the weekend of the fall back",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+			loc:  madrid,
+			from: time.Date(2026, 10, 23, 18, 0, 0, 0, madrid),
+			want: time.Date(2026, 10, 26, 17, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "monthly across spring forward",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=8",
+			loc:  newYork,
+			from: time.Date(2026, 2, 8, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "monthly last day from january",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1",
+			loc:  newYork,
+			from: time.Date(2026, 1, 31, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 2, 28, 14, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "monthly last day across spring forward",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1",
+			loc:  newYork,
+			from: time.Date(2026, 2, 28, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "monthly on the 31st skips the months without it",
+			rule: "FREQ=MONTHLY",
+			loc:  newYork,
+			from: time.Date(2026, 1, 31, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "monthly on the 30th skips february",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=30",
+			loc:  madrid,
+			from: time.Date(2026, 1, 30, 9, 0, 0, 0, madrid),
+			want: time.Date(2026, 3, 30, 7, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "yearly on february 29th waits for the leap year",
+			rule: "FREQ=YEARLY",
+			loc:  madrid,
+			from: time.Date(2024, 2, 29, 9, 0, 0, 0, madrid),
+			want: time.Date(2028, 2, 29, 8, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name:  "count decreases",
+			rule:  "FREQ=DAILY;COUNT=3",
+			loc:   newYork,
+			from:  time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+			want:  time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			ok:    true,
+			count: 2,
+		},
+		{
+			name: "count of one is the last occurrence",
+			rule: "FREQ=DAILY;COUNT=1",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "until right at the next occurrence includes it",
+			rule: "FREQ=DAILY;UNTIL=20260308T130000Z",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "until before the next occurrence ends the series",
+			rule: "FREQ=DAILY;UNTIL=20260308T125959Z",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "until as a date includes the whole day",
+			rule: "FREQ=WEEKLY;UNTIL=20261101",
+			loc:  madrid,
+			from: time.Date(2026, 10, 25, 9, 0, 0, 0, madrid),
+			want: time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := Parse(test.rule)
+
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.rule, err)
+			}
+
+			got, next, ok := rule.Next(test.from, test.loc)
+
+			if ok != test.ok {
+				t.Fatalf("Next(%v) ok = %v, want %v", test.from, ok, test.ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if !got.Equal(test.want) {
+				t.Errorf("Next(%v) = %v, want %v", test.from, got.UTC(), test.want)
+			}
+
+			if next.Count != test.count {
+				t.Errorf("Next(%v) count = %d, want %d", test.from, next.Count, test.count)
+			}
+		})
+	}
+}
+
+func TestNextKeepsWallClock(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	rule, err := Parse("FREQ=DAILY")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2026, 10, 28, 9, 0, 0, 0, newYork)
+
+	// Ten occurrences through the fall back, all at 9:00 in New York
+	for i := 0; i < 10; i++ {
+		next, _, ok := rule.Next(from, newYork)
+
+		if !ok {
+			t.Fatalf("no occurrence after %v", from)
+		}
+
+		local := next.In(newYork)
+
+		if local.Hour() != 9 || local.Minute() != 0 || civilDay(local).Sub(civilDay(from)) != 24*time.Hour {
+			t.Fatalf("after %v got %v", from, local)
+		}
+
+		from = next
+	}
+}
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"FREQ=DAILY",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"FREQ=WEEKLY;INTERVAL=2",
+		"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=12",
+		"FREQ=YEARLY;UNTIL=20301231T235959Z",
+	}
+
+	for _, rule := range valid {
+		if _, err := Parse(rule); err != nil {
+			t.Errorf("Parse(%q): %v", rule, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20301231",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+	}
+
+	for _, rule := range invalid {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("Parse(%q) should fail", rule)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package reminders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+)
+
+const (
+	CHANNEL_LOG     = "log"
+	CHANNEL_EMAIL   = "email"
+	CHANNEL_WEBHOOK = "webhook"
+)
+
+// A reminder can reach Notify more than once, when the process dies before it is marked
+// as sent. The notifiers pass IdempotencyKey along so the receivers can tell
+type Notifier interface {
+	Notify(r models.DueReminder) error
+}
+
+// The same for every delivery of the reminder
+func IdempotencyKey(r models.DueReminder) string {
+	return "reminder-" + strconv.FormatInt(r.Id, 10)
+}
+
+// Builds the notifiers that have their settings in the env, log is always available
+func NotifiersFromEnv() map[string]Notifier {
+	notifiers := map[string]Notifier{
+		CHANNEL_LOG: LogNotifier{},
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		notifiers[CHANNEL_EMAIL] = &EmailNotifier{
+			Host:     host,
+			Port:     os.Getenv("SMTP_PORT"),
+			User:     os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	}
+
+	if url := os.Getenv("REMINDER_WEBHOOK_URL"); url != "" {
+		notifiers[CHANNEL_WEBHOOK] = &WebhookNotifier{
+			Url:    url,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	return notifiers
+}
+
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(r models.DueReminder) error {
+	log.Printf("reminder %d: %q of %s is due on %s", r.Id, r.Title, r.UserMail, r.Deadline.Format(time.RFC3339))
+	return nil
+}
+
+type EmailNotifier struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+func (e *EmailNotifier) Notify(r models.DueReminder) error {
+	var auth smtp.Auth
+	if e.User != "" {
+		auth = smtp.PlainAuth("", e.User, e.Password, e.Host)
+	}
+
+	msg := strings.Builder{}
+	msg.WriteString("From: " + e.From + "\r\n")
+	msg.WriteString("To: " + r.UserMail + "\r\n")
+	msg.WriteString("Subject: Reminder: " + r.Title + "\r\n")
+	msg.WriteString("Message-ID: <" + IdempotencyKey(r) + "@nailit>\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(fmt.Sprintf("Hi %s,\r\n\r\n%q is due on %s.\r\n", r.UserName, r.Title, r.Deadline.Format(time.RFC1123)))
+
+	return smtp.SendMail(e.Host+":"+e.Port, auth, e.From, []string{r.UserMail}, []byte(msg.String()))
+}
+
+type WebhookNotifier struct {
+	Url    string
+	Client *http.Client
+}
+
+func (w *WebhookNotifier) Notify(r models.DueReminder) error {
+	body, err := json.Marshal(map[string]any{
+		"reminder": r.Id,
+		"todo":     r.ToDo,
+		"title":    r.Title,
+		"deadline": r.Deadline.UnixMilli(),
+		"due_at":   r.DueAt.UnixMilli(),
+		"mail":     r.UserMail,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.Url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", IdempotencyKey(r))
+
+	res, err := w.Client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package reminders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+)
+
+// Every delivery of a reminder carries the same key, so the receiver can drop the repeated ones
+func TestWebhookIdempotencyKey(t *testing.T) {
+	keys := make([]string, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+	}))
+	defer server.Close()
+
+	notifier := &WebhookNotifier{Url: server.URL, Client: server.Client()}
+	r := models.DueReminder{Id: 42, Title: "standup", Deadline: time.Now()}
+
+	for i := 0; i < 2; i++ {
+		if err := notifier.Notify(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(keys) != 2 || keys[0] != "reminder-42" || keys[1] != keys[0] {
+		t.Errorf("keys = %v, want reminder-42 twice", keys)
+	}
+}
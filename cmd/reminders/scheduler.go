@@ -0,0 +1,114 @@
+package reminders
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+)
+
+// Polls the due reminders and hands them to the notifier of their channel.
+// The state lives in the reminders table, so the ones that came due while the
+// api was down are sent on the next poll and several instances can run at once
+type Scheduler struct {
+	db        *sql.DB
+	notifiers map[string]Notifier
+	interval  time.Duration
+	batch     int
+}
+
+func NewScheduler(db *sql.DB, notifiers map[string]Notifier, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		db:        db,
+		notifiers: notifiers,
+		interval:  interval,
+		batch:     100,
+	}
+}
+
+func (s *Scheduler) Start() {
+	go func() {
+		for {
+			s.Poll()
+			time.Sleep(s.interval)
+		}
+	}()
+}
+
+func (s *Scheduler) Poll() {
+	due, err := models.GetDueReminders(time.Now().UTC(), s.batch, s.db)
+
+	if err != nil {
+		log.Println("reminders:", err)
+		return
+	}
+
+	for _, r := range due {
+		s.deliver(r)
+	}
+}
+
+// A reminder is claimed before being sent and marked after, if the process dies in
+// between the claim times out and it is sent again, it is never lost. Delivery is at
+// least once, so the notifiers give the receivers a key to drop the repeated ones.
+// The claim is renewed while the notifier runs, a slow one doesn't cause a resend
+func (s *Scheduler) deliver(r models.DueReminder) {
+	notifier, ok := s.notifiers[r.Channel]
+
+	if !ok {
+		notifier = s.notifiers[CHANNEL_LOG]
+	}
+
+	// claimed_at has no fractions of a second, the renewals compare against it
+	claimedAt := time.Now().UTC().Truncate(time.Second)
+	claimed, err := models.ClaimReminder(r.Id, claimedAt, s.db)
+
+	if err != nil || !claimed {
+		return
+	}
+
+	done := make(chan struct{})
+	go s.keepClaim(r.Id, claimedAt, done)
+
+	err = notifier.Notify(r)
+	close(done)
+
+	if err != nil {
+		log.Printf("reminders: couldnt notify %d: %v", r.Id, err)
+		models.ReleaseReminder(r.Id, s.db)
+		return
+	}
+
+	if err := models.MarkReminderSent(r.Id, time.Now().UTC(), s.db); err != nil {
+		log.Printf("reminders: couldnt mark %d as sent: %v", r.Id, err)
+	}
+}
+
+// Renews the claim every half of its timeout until done is closed
+func (s *Scheduler) keepClaim(id int64, claimedAt time.Time, done <-chan struct{}) {
+	ticker := time.NewTicker(models.REMINDER_CLAIM_TIMEOUT * time.Minute / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			now := time.Now().UTC().Truncate(time.Second)
+			renewed, err := models.RenewReminderClaim(id, claimedAt, now, s.db)
+
+			if err != nil || !renewed {
+				log.Printf("reminders: couldnt renew the claim of %d: %v", id, err)
+				return
+			}
+
+			claimedAt = now
+		}
+	}
+}
+
+func IsChannelAvailable(channel string, notifiers map[string]Notifier) bool {
+	_, ok := notifiers[channel]
+	return ok
+}
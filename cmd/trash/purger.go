@@ -0,0 +1,46 @@
+package trash
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/ArnulfoVargas/nailit_api.git/cmd/models"
+)
+
+// Deletes for good the to dos and tags that have been in the trash longer than the retention
+type Purger struct {
+	db        *sql.DB
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(db *sql.DB, retention time.Duration, interval time.Duration) *Purger {
+	return &Purger{
+		db:        db,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+func (p *Purger) Start() {
+	go func() {
+		for {
+			p.Purge()
+			time.Sleep(p.interval)
+		}
+	}()
+}
+
+func (p *Purger) Purge() {
+	todos, tags, err := models.PurgeTrash(time.Now().Add(-p.retention), p.db)
+
+	if err != nil {
+		log.Println("trash:", err)
+		return
+	}
+
+	if todos != 0 || tags != 0 {
+		log.Printf("trash: purged %d to dos and %d tags", todos, tags)
+	}
+}
@@ -0,0 +1,48 @@
+-- Tables of the api before the versioned migrations, they are only created when
+-- missing so the migrations can run on the databases that already have them
+
+CREATE TABLE IF NOT EXISTS users (
+  id_user BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(50) NOT NULL,
+  mail VARCHAR(100) NOT NULL,
+  password VARCHAR(100) NOT NULL DEFAULT '',
+  phone VARCHAR(20) NOT NULL DEFAULT '',
+  user_type TINYINT NOT NULL DEFAULT 0,
+  premium_expiracy DATETIME NULL,
+  image_url VARCHAR(255) NOT NULL DEFAULT '',
+  image_public_id VARCHAR(255) NOT NULL DEFAULT '',
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE IF NOT EXISTS todos (
+  id_todo BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  deadline DATETIME(6) NOT NULL,
+  tag BIGINT NOT NULL DEFAULT 0,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id_tag BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE IF NOT EXISTS images (
+  id_image BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  image_url VARCHAR(255) NOT NULL,
+  public_id VARCHAR(255) NOT NULL,
+  id_user BIGINT NOT NULL
+);
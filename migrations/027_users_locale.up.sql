@@ -0,0 +1,3 @@
+-- Locale the errors are answered in when the request doesn't ask for one
+
+ALTER TABLE users ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT 'en' AFTER image_public_id;
@@ -0,0 +1,10 @@
+-- Token buckets of the rate limiter, shared between the instances of the api. The
+-- buckets that are full again are purged by full_at
+
+CREATE TABLE rate_limits (
+  bucket_key VARCHAR(191) NOT NULL PRIMARY KEY,
+  tokens DOUBLE NOT NULL,
+  updated_at DATETIME(6) NOT NULL,
+  full_at DATETIME(6) NOT NULL,
+  INDEX (full_at)
+);
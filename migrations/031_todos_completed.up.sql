@@ -0,0 +1,5 @@
+-- Completion of the to dos, the existing ones start as not completed
+
+ALTER TABLE todos
+  ADD COLUMN completed BOOL NOT NULL DEFAULT 0 AFTER created_by,
+  ADD COLUMN completed_at DATETIME NULL AFTER completed;
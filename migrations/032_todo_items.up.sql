@@ -0,0 +1,13 @@
+-- Checklist items of the to dos
+
+CREATE TABLE todo_items (
+  id_item BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  text VARCHAR(255) NOT NULL,
+  done BOOL NOT NULL DEFAULT 0,
+  position INT NOT NULL DEFAULT 0,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (id_todo, status)
+);
@@ -0,0 +1,7 @@
+-- Schedule of the recurring to dos and the occurrence created when one is completed,
+-- the existing to dos don't repeat
+
+ALTER TABLE todos
+  ADD COLUMN recurrence VARCHAR(255) NOT NULL DEFAULT '' AFTER completed_at,
+  ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC' AFTER recurrence,
+  ADD COLUMN next_occurrence BIGINT NULL AFTER timezone;
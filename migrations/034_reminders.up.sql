@@ -0,0 +1,15 @@
+-- Reminders of the to dos, claimed by the scheduler while they are delivered
+
+CREATE TABLE reminders (
+  id_reminder BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  remind_at DATETIME NULL,
+  offset_minutes INT NOT NULL DEFAULT 0,
+  channel VARCHAR(20) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  sent_at DATETIME NULL,
+  claimed_at DATETIME NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  INDEX (id_todo, status),
+  INDEX (status, sent_at)
+);
@@ -0,0 +1,13 @@
+-- Priority and manual order of the to dos. The existing ones are ordered as they were
+-- listed, by deadline, so the first manual move doesn't find them all tied at 0
+
+ALTER TABLE todos
+  ADD COLUMN priority TINYINT NOT NULL DEFAULT 0 AFTER next_occurrence,
+  ADD COLUMN position DOUBLE NOT NULL DEFAULT 0 AFTER priority;
+
+UPDATE todos t
+  JOIN (
+    SELECT id_todo, ROW_NUMBER() OVER (PARTITION BY created_by ORDER BY deadline ASC, id_todo ASC) AS n
+    FROM todos
+  ) o ON o.id_todo = t.id_todo
+  SET t.position = o.n;
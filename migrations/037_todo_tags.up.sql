@@ -0,0 +1,12 @@
+-- Tags of the to dos, more than one each. todos.tag is kept for the legacy routes and
+-- the tag it holds is copied here
+
+CREATE TABLE todo_tags (
+  id_todo BIGINT NOT NULL,
+  id_tag BIGINT NOT NULL,
+  PRIMARY KEY (id_todo, id_tag),
+  INDEX (id_tag)
+);
+
+INSERT INTO todo_tags (id_todo, id_tag)
+  SELECT id_todo, tag FROM todos WHERE tag != 0;
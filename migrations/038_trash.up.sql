@@ -0,0 +1,10 @@
+-- When the to dos and tags went to the trash. The ones deleted before take the date
+-- of their last change, the same one the trash fell back to
+
+ALTER TABLE todos ADD COLUMN deleted_at DATETIME NULL AFTER updated_at;
+
+ALTER TABLE tags ADD COLUMN deleted_at DATETIME NULL AFTER updated_at;
+
+UPDATE todos SET deleted_at = COALESCE(updated_at, created_at) WHERE status = 0 AND deleted_at IS NULL;
+
+UPDATE tags SET deleted_at = COALESCE(updated_at, created_at) WHERE status = 0 AND deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- Archived to dos, out of the listings and quotas
+
+ALTER TABLE todos ADD COLUMN archived_at DATETIME NULL AFTER position;
@@ -0,0 +1,13 @@
+-- History of the changes of every to do, with the snapshot undo goes back to
+
+CREATE TABLE todo_revisions (
+  id_revision BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  changed_by BIGINT NOT NULL,
+  changed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  action VARCHAR(20) NOT NULL,
+  changes JSON NOT NULL,
+  snapshot JSON NOT NULL,
+  restored_from BIGINT NULL,
+  INDEX (id_todo)
+);
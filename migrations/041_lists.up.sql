@@ -0,0 +1,39 @@
+-- Shared lists, their members and the invites sent by mail. The to dos of a list
+-- can be assigned to one of its members
+
+CREATE TABLE lists (
+  id_list BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE list_members (
+  id_list BIGINT NOT NULL,
+  id_user BIGINT NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  PRIMARY KEY (id_list, id_user),
+  INDEX (id_user)
+);
+
+CREATE TABLE list_invites (
+  id_invite BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_list BIGINT NOT NULL,
+  mail VARCHAR(100) NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  invited_by BIGINT NOT NULL,
+  accepted BOOL NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  answered_at DATETIME NULL,
+  INDEX (id_list, mail)
+);
+
+ALTER TABLE todos
+  ADD COLUMN id_list BIGINT NULL AFTER archived_at,
+  ADD COLUMN assigned_to BIGINT NULL AFTER id_list,
+  ADD INDEX (id_list);
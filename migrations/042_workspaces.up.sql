@@ -0,0 +1,29 @@
+-- Workspaces with their members and plan. The to dos, tags and images without one
+-- stay personal
+
+CREATE TABLE workspaces (
+  id_workspace BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(50) NOT NULL,
+  created_by BIGINT NOT NULL,
+  premium_expiracy DATETIME NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL
+);
+
+CREATE TABLE workspace_members (
+  id_workspace BIGINT NOT NULL,
+  id_user BIGINT NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  PRIMARY KEY (id_workspace, id_user),
+  INDEX (id_user)
+);
+
+ALTER TABLE todos
+  ADD COLUMN id_workspace BIGINT NULL AFTER assigned_to,
+  ADD INDEX (id_workspace);
+
+ALTER TABLE tags ADD COLUMN id_workspace BIGINT NULL AFTER created_by;
+
+ALTER TABLE images ADD COLUMN id_workspace BIGINT NULL AFTER id_user;
@@ -0,0 +1,22 @@
+-- Threaded comments of the to dos and the users mentioned in them
+
+CREATE TABLE todo_comments (
+  id_comment BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  id_parent BIGINT NULL,
+  created_by BIGINT NOT NULL,
+  body VARCHAR(1000) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  deleted_at DATETIME NULL,
+  INDEX (id_todo),
+  INDEX (id_parent)
+);
+
+CREATE TABLE comment_mentions (
+  id_comment BIGINT NOT NULL,
+  id_user BIGINT NOT NULL,
+  PRIMARY KEY (id_comment, id_user),
+  INDEX (id_user)
+);
@@ -0,0 +1,18 @@
+-- Kanban columns of the users, the to dos out of every column are in the backlog
+
+CREATE TABLE board_columns (
+  id_column BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  color INT UNSIGNED NOT NULL DEFAULT 0,
+  position DOUBLE NOT NULL,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);
+
+ALTER TABLE todos
+  ADD COLUMN id_column BIGINT NULL AFTER id_workspace,
+  ADD COLUMN column_position DOUBLE NULL AFTER id_column,
+  ADD INDEX (id_column);
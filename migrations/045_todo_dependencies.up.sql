@@ -0,0 +1,9 @@
+-- To dos that block others until they are completed
+
+CREATE TABLE todo_dependencies (
+  id_blocker BIGINT NOT NULL,
+  id_blocked BIGINT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id_blocker, id_blocked),
+  INDEX (id_blocked)
+);
@@ -0,0 +1,13 @@
+-- Time tracked on the to dos, the running entry has no ended_at
+
+CREATE TABLE time_entries (
+  id_entry BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  created_by BIGINT NOT NULL,
+  started_at DATETIME(6) NOT NULL,
+  ended_at DATETIME(6) NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX (created_by, started_at),
+  INDEX (id_todo)
+);
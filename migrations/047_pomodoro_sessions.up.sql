@@ -0,0 +1,17 @@
+-- Pomodoro sessions of the users on their to dos
+
+CREATE TABLE pomodoro_sessions (
+  id_session BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  id_todo BIGINT NOT NULL,
+  created_by BIGINT NOT NULL,
+  work_minutes INT NOT NULL,
+  break_minutes INT NOT NULL,
+  state VARCHAR(20) NOT NULL,
+  elapsed_seconds INT NOT NULL DEFAULT 0,
+  resumed_at DATETIME(6) NULL,
+  started_at DATETIME(6) NOT NULL,
+  finished_at DATETIME(6) NULL,
+  completed BOOL NOT NULL DEFAULT 0,
+  INDEX (created_by, state),
+  INDEX (created_by, started_at)
+);
@@ -0,0 +1,12 @@
+-- Templates of the users, their to dos are kept as JSON
+
+CREATE TABLE templates (
+  id_template BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(50) NOT NULL,
+  todos JSON NOT NULL,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);
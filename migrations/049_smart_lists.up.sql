@@ -0,0 +1,12 @@
+-- Filters the users saved as smart lists
+
+CREATE TABLE smart_lists (
+  id_smart_list BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(30) NOT NULL,
+  filter JSON NOT NULL,
+  created_by BIGINT NOT NULL,
+  status TINYINT NOT NULL DEFAULT 1,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NULL,
+  INDEX (created_by, status)
+);